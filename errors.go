@@ -0,0 +1,64 @@
+package funplugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/httprunner/funplugin/fungo"
+)
+
+// PluginError is the structured error of plugin function call, use errors.As to extract it
+type PluginError = fungo.PluginError
+
+// ErrorKind is the kind of PluginError
+type ErrorKind = fungo.ErrorKind
+
+const (
+	ErrKindFuncNotFound     = fungo.ErrKindFuncNotFound
+	ErrKindArgMismatch      = fungo.ErrKindArgMismatch
+	ErrKindUser             = fungo.ErrKindUser
+	ErrKindPanic            = fungo.ErrKindPanic
+	ErrKindTransportFailure = fungo.ErrKindTransportFailure
+	ErrKindStepLimit        = fungo.ErrKindStepLimit
+)
+
+// FuncConflictError is returned by Manager when a plain function name is found in multiple plugins
+type FuncConflictError struct {
+	FuncName   string   // plain function name
+	Namespaces []string // namespaces of plugins which have the function
+}
+
+func (e *FuncConflictError) Error() string {
+	return fmt.Sprintf("function %s found in multiple plugins %v, call it with namespace, e.g. %s.%s",
+		e.FuncName, e.Namespaces, e.Namespaces[0], e.FuncName)
+}
+
+// CallTimeoutError is returned when a function call exceeds its timeout
+type CallTimeoutError struct {
+	FuncName string        // function name
+	Timeout  time.Duration // exceeded timeout
+}
+
+func (e *CallTimeoutError) Error() string {
+	return fmt.Sprintf("call function %s timeout after %v", e.FuncName, e.Timeout)
+}
+
+// Unwrap makes errors.Is(err, context.DeadlineExceeded) work
+func (e *CallTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// isDeadlineExceeded checks if err is caused by exceeding the deadline of ctx,
+// either reported by the plugin or detected on the host side.
+func isDeadlineExceeded(ctx context.Context, err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && !time.Now().Before(deadline)
+}
@@ -0,0 +1,186 @@
+package fungo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/httprunner/funplugin/fungo/protoGen"
+)
+
+// DefaultMaxMessageSize is the default max size of gRPC messages,
+// CallRequest and CallResponse beyond max size are transferred in chunks.
+const DefaultMaxMessageSize = 4 << 20
+
+const (
+	maxChunkSize  = 1 << 20
+	chunkOverhead = 1 << 10 // reserved for fields of Chunk message
+)
+
+// chunkTimeout is the duration to keep oversized response until it is fetched
+var chunkTimeout = time.Minute
+
+// chunkSize returns size of chunk data within max message size
+func chunkSize(maxMessageSize int) int {
+	if maxMessageSize <= 0 {
+		maxMessageSize = DefaultMaxMessageSize
+	}
+	if size := maxMessageSize - chunkOverhead; size < maxChunkSize {
+		return size
+	}
+	return maxChunkSize
+}
+
+// oversized checks if message exceeds max message size
+func oversized(msg proto.Message, maxMessageSize int) bool {
+	if maxMessageSize <= 0 {
+		maxMessageSize = DefaultMaxMessageSize
+	}
+	return proto.Size(msg) > maxMessageSize
+}
+
+// splitChunks splits data into chunks with max size
+func splitChunks(data []byte, size int) [][]byte {
+	chunks := make([][]byte, 0, len(data)/size+1)
+	for len(data) > size {
+		chunks = append(chunks, data[:size])
+		data = data[size:]
+	}
+	return append(chunks, data)
+}
+
+// chunkStore keeps oversized responses until they are fetched by FetchChunks
+type chunkStore struct {
+	lastID uint64
+	data   sync.Map // chunk id -> marshaled CallResponse
+}
+
+func (s *chunkStore) put(data []byte) string {
+	id := strconv.FormatUint(atomic.AddUint64(&s.lastID, 1), 10)
+	s.data.Store(id, data)
+	time.AfterFunc(chunkTimeout, func() {
+		s.data.Delete(id) // not fetched in time, e.g. host call cancelled
+	})
+	return id
+}
+
+func (s *chunkStore) take(id string) ([]byte, bool) {
+	data, ok := s.data.LoadAndDelete(id)
+	if !ok {
+		return nil, false
+	}
+	return data.([]byte), true
+}
+
+// callChunked sends oversized request in chunks
+func (m *functionGRPCClient) callChunked(ctx context.Context, req *protoGen.CallRequest) (*protoGen.CallResponse, error) {
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal CallRequest failed")
+	}
+	logger.Debug("gRPC_client CallChunked() start", "funcName", req.Name, "size", len(data))
+
+	stream, err := m.client.CallChunked(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, chunk := range splitChunks(data, chunkSize(m.maxSendSize)) {
+		if err := stream.Send(&protoGen.Chunk{Data: chunk}); err != nil {
+			if err == io.EOF {
+				break // plugin returned early, error is received by CloseAndRecv
+			}
+			return nil, err
+		}
+	}
+	return stream.CloseAndRecv()
+}
+
+// fetchChunks receives oversized response in chunks
+func (m *functionGRPCClient) fetchChunks(ctx context.Context, chunkID string) (*protoGen.CallResponse, error) {
+	logger.Debug("gRPC_client FetchChunks() start", "chunkID", chunkID)
+	stream, err := m.client.FetchChunks(ctx, &protoGen.FetchChunksRequest{ChunkId: chunkID})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(chunk.Data)
+	}
+
+	resp := &protoGen.CallResponse{}
+	if err := proto.Unmarshal(buf.Bytes(), resp); err != nil {
+		return nil, errors.Wrap(err, "unmarshal CallResponse failed")
+	}
+	return resp, nil
+}
+
+// CallChunked receives oversized request in chunks and calls function
+func (m *functionGRPCServer) CallChunked(srv protoGen.DebugTalk_CallChunkedServer) error {
+	var buf bytes.Buffer
+	for {
+		chunk, err := srv.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		buf.Write(chunk.Data)
+	}
+	logger.Debug("gRPC_server CallChunked() start", "size", buf.Len())
+
+	req := &protoGen.CallRequest{}
+	if err := proto.Unmarshal(buf.Bytes(), req); err != nil {
+		return status.Errorf(codes.InvalidArgument, "unmarshal CallRequest failed: %v", err)
+	}
+	resp, err := m.Call(srv.Context(), req)
+	if err != nil {
+		return err
+	}
+	return srv.SendAndClose(resp)
+}
+
+// FetchChunks sends oversized response kept by Call in chunks
+func (m *functionGRPCServer) FetchChunks(req *protoGen.FetchChunksRequest, srv protoGen.DebugTalk_FetchChunksServer) error {
+	logger.Debug("gRPC_server FetchChunks() start", "chunkID", req.ChunkId)
+	data, ok := m.chunks.take(req.ChunkId)
+	if !ok {
+		return status.Errorf(codes.NotFound, "chunks %s not found or expired", req.ChunkId)
+	}
+	for _, chunk := range splitChunks(data, chunkSize(m.maxSendSize)) {
+		if err := srv.Send(&protoGen.Chunk{Data: chunk}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deferOversized keeps oversized response to be fetched by FetchChunks if host accepts chunks
+func (m *functionGRPCServer) deferOversized(req *protoGen.CallRequest, resp *protoGen.CallResponse) (*protoGen.CallResponse, error) {
+	if !req.AcceptChunks || !oversized(resp, m.maxSendSize) {
+		return resp, nil
+	}
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal CallResponse failed")
+	}
+	chunkID := m.chunks.put(data)
+	logger.Debug("gRPC_server Call() response oversized", "chunkID", chunkID, "size", len(data))
+	return &protoGen.CallResponse{ChunkId: chunkID}, nil
+}
@@ -0,0 +1,37 @@
+package fungo
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunks(t *testing.T) {
+	if !assert.Equal(t, maxChunkSize, chunkSize(0)) {
+		t.Fail()
+	}
+	if !assert.Equal(t, 64<<10-chunkOverhead, chunkSize(64<<10)) {
+		t.Fail()
+	}
+
+	data := bytes.Repeat([]byte("x"), 2500)
+	chunks := splitChunks(data, 1000)
+	if !assert.Len(t, chunks, 3) || !assert.Len(t, chunks[2], 500) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, data, bytes.Join(chunks, nil)) {
+		t.Fail()
+	}
+
+	// oversized response is taken only once
+	var store chunkStore
+	id := store.put(data)
+	v, ok := store.take(id)
+	if !assert.True(t, ok) || !assert.Equal(t, data, v) {
+		t.Fail()
+	}
+	if _, ok := store.take(id); !assert.False(t, ok) {
+		t.Fail()
+	}
+}
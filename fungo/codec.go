@@ -0,0 +1,210 @@
+package fungo
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes arguments and return values of gRPC calls,
+// it is negotiated between host and plugin when plugin starts.
+type Codec interface {
+	Name() string // codec name negotiated with plugin, e.g. json, msgpack, cbor
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// built-in codecs, registered by default
+var (
+	JSONCodec    Codec = jsonCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+	CBORCodec    Codec = newCBORCodec()
+)
+
+var codecs sync.Map // registered codecs, key is codec name, value is Codec
+
+func init() {
+	RegisterCodec(JSONCodec)
+	RegisterCodec(MsgpackCodec)
+	RegisterCodec(CBORCodec)
+}
+
+// RegisterCodec registers codec which can be negotiated with host,
+// it should be called before Serve() in plugin.
+func RegisterCodec(codec Codec) {
+	codecs.Store(codec.Name(), codec)
+}
+
+func getCodec(name string) (Codec, bool) {
+	codec, ok := codecs.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return codec.(Codec), true
+}
+
+// jsonCodec is understood by all plugins, numbers are decoded as float64
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// msgpackCodec keeps integers, bytes and time,
+// integers are decoded as int64, or uint64 if beyond int64, and maps as map[string]interface{}
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string {
+	return "msgpack"
+}
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := msgpack.NewEncoder(&buf)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	decoder := msgpack.NewDecoder(bytes.NewReader(data))
+	decoder.SetCustomStructTag("json")
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	normalizeValues(v)
+	return nil
+}
+
+// normalizeValues converts integers decoded into interface{} to int64,
+// or uint64 if beyond int64, e.g. int8 decoded by msgpack and uint64 decoded by cbor,
+// and decimal fractions tagged in cbor, e.g. python Decimal, to *big.Float.
+func normalizeValues(v interface{}) {
+	switch val := v.(type) {
+	case *interface{}:
+		*val = normalizeValue(*val)
+	case *[]interface{}:
+		for i := range *val {
+			(*val)[i] = normalizeValue((*val)[i])
+		}
+	case *map[string]interface{}:
+		normalizeValue(*val)
+	}
+}
+
+func normalizeValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case int8:
+		return int64(val)
+	case int16:
+		return int64(val)
+	case int32:
+		return int64(val)
+	case uint8:
+		return int64(val)
+	case uint16:
+		return int64(val)
+	case uint32:
+		return int64(val)
+	case uint64:
+		if val <= math.MaxInt64 {
+			return int64(val)
+		}
+	case float32:
+		return float64(val)
+	case cbor.Tag:
+		if f, ok := decodeDecimalFraction(val); ok {
+			return f
+		}
+	case []interface{}:
+		for i := range val {
+			val[i] = normalizeValue(val[i])
+		}
+	case map[string]interface{}:
+		for key, item := range val {
+			val[key] = normalizeValue(item)
+		}
+	}
+	return v
+}
+
+// decodeDecimalFraction decodes cbor tag 4 [exponent, mantissa] to *big.Float
+func decodeDecimalFraction(tag cbor.Tag) (*big.Float, bool) {
+	content, ok := tag.Content.([]interface{})
+	if tag.Number != 4 || !ok || len(content) != 2 {
+		return nil, false
+	}
+	exponent, ok := normalizeValue(content[0]).(int64)
+	if !ok {
+		return nil, false
+	}
+	var mantissa string
+	switch m := normalizeValue(content[1]).(type) {
+	case int64, uint64:
+		mantissa = fmt.Sprint(m)
+	case big.Int:
+		mantissa = m.String()
+	case *big.Int:
+		mantissa = m.String()
+	default:
+		return nil, false
+	}
+	f, ok := new(big.Float).SetPrec(256).SetString(fmt.Sprintf("%se%d", mantissa, exponent))
+	return f, ok
+}
+
+// cborCodec keeps integers, bytes and time,
+// integers are decoded as int64, or uint64 if beyond int64, and maps as map[string]interface{}
+type cborCodec struct {
+	encMode cbor.EncMode
+	decMode cbor.DecMode
+}
+
+func newCBORCodec() *cborCodec {
+	encMode, err := cbor.EncOptions{
+		Time:    cbor.TimeRFC3339Nano,
+		TimeTag: cbor.EncTagRequired,
+	}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	decMode, err := cbor.DecOptions{
+		DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+	}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return &cborCodec{encMode: encMode, decMode: decMode}
+}
+
+func (c *cborCodec) Name() string {
+	return "cbor"
+}
+
+func (c *cborCodec) Marshal(v interface{}) ([]byte, error) {
+	return c.encMode.Marshal(v)
+}
+
+func (c *cborCodec) Unmarshal(data []byte, v interface{}) error {
+	if err := c.decMode.Unmarshal(data, v); err != nil {
+		return err
+	}
+	normalizeValues(v)
+	return nil
+}
@@ -0,0 +1,60 @@
+package fungo
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodec(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	args := []interface{}{
+		int64(9007199254740993), uint64(18446744073709551615), 3, 1.5, "a", true, nil,
+		[]byte("bytes"), now, []interface{}{1, "b"}, map[string]interface{}{"c": 2},
+	}
+	expArgs := []interface{}{
+		int64(9007199254740993), uint64(18446744073709551615), int64(3), 1.5, "a", true, nil,
+		[]byte("bytes"), now, []interface{}{int64(1), "b"}, map[string]interface{}{"c": int64(2)},
+	}
+
+	for _, codec := range []Codec{MsgpackCodec, CBORCodec} {
+		data, err := codec.Marshal(args)
+		if !assert.Nil(t, err, codec.Name()) {
+			t.Fatal()
+		}
+		var v []interface{}
+		if !assert.Nil(t, codec.Unmarshal(data, &v), codec.Name()) {
+			t.Fatal()
+		}
+		for i := range expArgs {
+			if tm, ok := v[i].(time.Time); ok {
+				v[i] = tm.UTC()
+			}
+		}
+		if !assert.Equal(t, expArgs, v, codec.Name()) {
+			t.Fail()
+		}
+	}
+
+	// decimal fraction 123.45 encoded by python cbor2, i.e. tag 4 [-2, 12345]
+	var decimal interface{}
+	if !assert.Nil(t, CBORCodec.Unmarshal([]byte{0xc4, 0x82, 0x21, 0x19, 0x30, 0x39}, &decimal)) {
+		t.Fatal()
+	}
+	if f, ok := decimal.(*big.Float); !assert.True(t, ok, decimal) || !assert.Equal(t, "123.45", f.Text('f', 2)) {
+		t.Fail()
+	}
+
+	// registered codecs can be negotiated
+	for _, name := range []string{"json", "msgpack", "cbor"} {
+		codec, ok := getCodec(name)
+		if !assert.True(t, ok) || !assert.Equal(t, name, codec.Name()) {
+			t.Fail()
+		}
+	}
+	if _, ok := getCodec("xml"); !assert.False(t, ok) {
+		t.Fail()
+	}
+}
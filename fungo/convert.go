@@ -0,0 +1,235 @@
+package fungo
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// convertValue converts argument to expect type, path locates the argument in error messages, e.g. argument 0.items[1].
+// Besides exact match and Go conversion, it binds map to struct by JSON tags, converts slice and map
+// element-wise, allocates pointer parameters, and converts integral float64 decoded from JSON to integers.
+func convertValue(value reflect.Value, expectType reflect.Type, path string) (reflect.Value, error) {
+	for value.IsValid() && value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return reflect.Zero(expectType), nil // nil
+	}
+	actualType := value.Type()
+	if actualType == expectType {
+		return value, nil
+	}
+	if expectType.Kind() == reflect.Interface {
+		if !actualType.Implements(expectType) {
+			return reflect.Value{}, newConvertError(path, expectType, actualType)
+		}
+		return value.Convert(expectType), nil
+	}
+
+	switch expectType.Kind() {
+	case reflect.Ptr:
+		if value.Kind() == reflect.Ptr {
+			if value.IsNil() {
+				return reflect.Zero(expectType), nil
+			}
+			if actualType.ConvertibleTo(expectType) {
+				return value.Convert(expectType), nil
+			}
+			value = value.Elem()
+		}
+		elem, err := convertValue(value, expectType.Elem(), path)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(expectType.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if isNumberKind(value.Kind()) {
+			return convertInteger(value, expectType, path)
+		}
+	case reflect.Struct:
+		if expectType == timeType && value.Kind() == reflect.String {
+			t, err := time.Parse(time.RFC3339Nano, value.String())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("function %s is not RFC3339 time: %v", path, err)
+			}
+			return reflect.ValueOf(t), nil
+		}
+		if value.Kind() == reflect.Map && value.Type().Key().Kind() == reflect.String {
+			return convertStruct(value, expectType, path)
+		}
+	case reflect.Slice:
+		if (value.Kind() == reflect.Slice || value.Kind() == reflect.Array) && !actualType.ConvertibleTo(expectType) {
+			slice := reflect.MakeSlice(expectType, value.Len(), value.Len())
+			for i := 0; i < value.Len(); i++ {
+				elem, err := convertValue(value.Index(i), expectType.Elem(), fmt.Sprintf("%s[%d]", path, i))
+				if err != nil {
+					return reflect.Value{}, err
+				}
+				slice.Index(i).Set(elem)
+			}
+			return slice, nil
+		}
+	case reflect.Map:
+		if value.Kind() == reflect.Map && !actualType.ConvertibleTo(expectType) {
+			return convertMap(value, expectType, path)
+		}
+	}
+
+	// integers are not converted to string as runes
+	if !actualType.ConvertibleTo(expectType) ||
+		(expectType.Kind() == reflect.String && isNumberKind(value.Kind())) {
+		return reflect.Value{}, newConvertError(path, expectType, actualType)
+	}
+	return value.Convert(expectType), nil
+}
+
+func newConvertError(path string, expectType, actualType reflect.Type) error {
+	return fmt.Errorf("function %s's type is neither match nor convertible, expect %v, actual %v",
+		path, expectType, actualType)
+}
+
+func isNumberKind(kind reflect.Kind) bool {
+	return isIntegerKind(kind) || kind == reflect.Float32 || kind == reflect.Float64
+}
+
+// convertInteger converts number to integer type, fraction and overflow are rejected
+func convertInteger(value reflect.Value, expectType reflect.Type, path string) (reflect.Value, error) {
+	result := reflect.New(expectType).Elem()
+	var overflow bool
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		f := value.Float()
+		if f != math.Trunc(f) {
+			return reflect.Value{}, fmt.Errorf("function %s has fraction, cannot convert %v to %v",
+				path, f, expectType)
+		}
+		if expectType.Kind() >= reflect.Uint {
+			overflow = f < 0 || f >= math.MaxUint64 || result.OverflowUint(uint64(f))
+		} else {
+			overflow = f < math.MinInt64 || f >= math.MaxInt64 || result.OverflowInt(int64(f))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := value.Int()
+		if expectType.Kind() >= reflect.Uint {
+			overflow = i < 0 || result.OverflowUint(uint64(i))
+		} else {
+			overflow = result.OverflowInt(i)
+		}
+	default: // unsigned integers
+		u := value.Uint()
+		if expectType.Kind() >= reflect.Uint {
+			overflow = result.OverflowUint(u)
+		} else {
+			overflow = u > math.MaxInt64 || result.OverflowInt(int64(u))
+		}
+	}
+	if overflow {
+		return reflect.Value{}, fmt.Errorf("function %s overflows %v: %v", path, expectType, value)
+	}
+	return value.Convert(expectType), nil
+}
+
+// convertStruct binds map to struct fields by JSON tags, field names are matched case-insensitively
+// if no tag matches exactly, and unknown keys are ignored like encoding/json.
+func convertStruct(value reflect.Value, expectType reflect.Type, path string) (reflect.Value, error) {
+	fields := structFields(expectType)
+	result := reflect.New(expectType).Elem()
+	iter := value.MapRange()
+	for iter.Next() {
+		key := iter.Key().String()
+		field, ok := fields[key]
+		if !ok {
+			for name, f := range fields {
+				if strings.EqualFold(name, key) {
+					field, ok = f, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		fieldValue, err := convertValue(iter.Value(), field.Type, path+"."+key)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result.FieldByIndex(field.Index).Set(fieldValue)
+	}
+	return result, nil
+}
+
+// structFields returns exported fields of struct keyed by JSON name,
+// fields promoted from embedded structs are included unless embedded by pointer.
+func structFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || len(field.Index) > 1 && embeddedByPointer(t, field.Index) {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			continue // fields are promoted
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := fields[name]; ok && len(field.Index) > 1 {
+			continue // shadowed by outer field
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+func embeddedByPointer(t reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		t = t.Field(i).Type
+		if t.Kind() == reflect.Ptr {
+			return true
+		}
+	}
+	return false
+}
+
+// convertMap converts map keys and values element-wise,
+// string keys are parsed if expect integer keys, as map keys are encoded as strings in JSON.
+func convertMap(value reflect.Value, expectType reflect.Type, path string) (reflect.Value, error) {
+	keyType := expectType.Key()
+	result := reflect.MakeMapWithSize(expectType, value.Len())
+	iter := value.MapRange()
+	for iter.Next() {
+		keyPath := fmt.Sprintf("%s[%v]", path, iter.Key().Interface())
+		key := iter.Key()
+		for key.Kind() == reflect.Interface {
+			key = key.Elem()
+		}
+		if key.Kind() == reflect.String && isIntegerKind(keyType.Kind()) {
+			i, err := strconv.ParseInt(key.String(), 10, 64)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("function %s's key is not integer", keyPath)
+			}
+			key = reflect.ValueOf(i)
+		}
+		k, err := convertValue(key, keyType, keyPath)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v, err := convertValue(iter.Value(), expectType.Elem(), keyPath)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		result.SetMapIndex(k, v)
+	}
+	return result, nil
+}
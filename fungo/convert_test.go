@@ -0,0 +1,182 @@
+package fungo
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type address struct {
+	City string `json:"city"`
+	Zip  *int   `json:"zip,omitempty"`
+}
+
+type base struct {
+	ID int64 `json:"id"`
+}
+
+type user struct {
+	base
+	Name    string            `json:"name"`
+	Tags    []string          `json:"tags"`
+	Scores  map[string]int    `json:"scores"`
+	Address *address          `json:"address"`
+	Created time.Time         `json:"created"`
+	Ignored string            `json:"-"`
+	Extra   map[int]*address  `json:"extra"`
+	Attrs   map[string]string // matched case-insensitively
+}
+
+func TestCallFuncConvert(t *testing.T) {
+	zip := 100000
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	params := []data{
+		// map to struct via JSON tags
+		{
+			f: func(u user) user { return u },
+			args: []interface{}{map[string]interface{}{
+				"id":      float64(1),
+				"name":    "foo",
+				"tags":    []interface{}{"a", "b"},
+				"scores":  map[string]interface{}{"math": 90.0},
+				"address": map[string]interface{}{"city": "Shenzhen", "zip": 100000.0},
+				"created": "2024-01-02T03:04:05Z",
+				"Ignored": "x",
+				"extra":   map[string]interface{}{"1": map[string]interface{}{"city": "Beijing"}},
+				"attrs":   map[string]interface{}{"k": "v"},
+				"unknown": 1,
+			}},
+			expVal: user{
+				base:    base{ID: 1},
+				Name:    "foo",
+				Tags:    []string{"a", "b"},
+				Scores:  map[string]int{"math": 90},
+				Address: &address{City: "Shenzhen", Zip: &zip},
+				Created: created,
+				Extra:   map[int]*address{1: {City: "Beijing"}},
+				Attrs:   map[string]string{"k": "v"},
+			},
+		},
+		// pointer parameter
+		{
+			f:      func(a *address) string { return a.City },
+			args:   []interface{}{map[string]interface{}{"city": "Shenzhen"}},
+			expVal: "Shenzhen",
+		},
+		{
+			f:      func(n *int) bool { return n == nil },
+			args:   []interface{}{nil},
+			expVal: true,
+		},
+		{
+			f:      func(n *int) int { return *n },
+			args:   []interface{}{2.0},
+			expVal: 2,
+		},
+		// element-wise slice and map conversion
+		{
+			f:      func(n []int, s []string) int { return len(n) + len(s) },
+			args:   []interface{}{[]interface{}{1.0, int64(2)}, []interface{}{"a"}},
+			expVal: 3,
+		},
+		{
+			f:      func(m map[string][]int) int { return m["a"][1] },
+			args:   []interface{}{map[string]interface{}{"a": []interface{}{1.0, 2.0}}},
+			expVal: 2,
+		},
+		{
+			f:      func(n ...uint8) int { return len(n) },
+			args:   []interface{}{1.0, int64(255)},
+			expVal: 2,
+		},
+		// bytes
+		{
+			f:      func(b []byte) string { return string(b) },
+			args:   []interface{}{"bytes"},
+			expVal: "bytes",
+		},
+	}
+
+	for _, p := range params {
+		fn := reflect.ValueOf(p.f)
+		val, err := CallFunc(fn, p.args...)
+		if !assert.NoError(t, err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, p.expVal, val) {
+			t.Fatal()
+		}
+	}
+}
+
+func TestCallFuncConvertError(t *testing.T) {
+	params := []struct {
+		f       interface{}
+		args    []interface{}
+		message string
+	}{
+		{
+			func(n int) int { return n },
+			[]interface{}{1.5},
+			"function argument 0 has fraction, cannot convert 1.5 to int",
+		},
+		{
+			func(n int8) int8 { return n },
+			[]interface{}{300.0},
+			"function argument 0 overflows int8: 300",
+		},
+		{
+			func(n uint) uint { return n },
+			[]interface{}{-1},
+			"function argument 0 overflows uint: -1",
+		},
+		{
+			func(s string) string { return s },
+			[]interface{}{65},
+			"function argument 0's type is neither match nor convertible, expect string, actual int",
+		},
+		{
+			func(a string, n []int) int { return len(n) },
+			[]interface{}{"a", []interface{}{1.0, "2"}},
+			"function argument 1[1]'s type is neither match nor convertible, expect int, actual string",
+		},
+		{
+			func(u user) string { return u.Name },
+			[]interface{}{map[string]interface{}{"address": map[string]interface{}{"zip": 1.5}}},
+			"function argument 0.address.zip has fraction, cannot convert 1.5 to int",
+		},
+		{
+			func(u user) string { return u.Name },
+			[]interface{}{map[string]interface{}{"extra": map[string]interface{}{"a": nil}}},
+			"function argument 0.extra[a]'s key is not integer",
+		},
+		{
+			func(u user) string { return u.Name },
+			[]interface{}{map[string]interface{}{"created": "yesterday"}},
+			"function argument 0.created is not RFC3339 time: " +
+				`parsing time "yesterday" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "yesterday" as "2006"`,
+		},
+		{
+			func(a, b int, n ...int) int { return a + b },
+			[]interface{}{1},
+			"function expect at least 2 arguments, but got 1",
+		},
+	}
+
+	for _, p := range params {
+		_, err := CallFunc(reflect.ValueOf(p.f), p.args...)
+		var pluginErr *PluginError
+		if !assert.True(t, errors.As(err, &pluginErr), err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, ErrKindArgMismatch, pluginErr.Kind) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, p.message, pluginErr.Message) {
+			t.Fatal()
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package fungo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/httprunner/funplugin/fungo/protoGen"
+)
+
+// ErrorKind is the kind of PluginError
+type ErrorKind string
+
+const (
+	ErrKindFuncNotFound     ErrorKind = "function_not_found" // function is not registered
+	ErrKindArgMismatch      ErrorKind = "argument_mismatch"  // arguments do not match function parameters
+	ErrKindUser             ErrorKind = "user_error"         // function returned error or raised exception
+	ErrKindPanic            ErrorKind = "panic"              // function panicked
+	ErrKindTransportFailure ErrorKind = "transport_failure"  // failed to communicate with plugin
+	ErrKindStepLimit        ErrorKind = "step_limit"         // function exceeded execution steps limit
+)
+
+// PluginError is the structured error of plugin function call,
+// it is transferred between plugin and host on all backends.
+type PluginError struct {
+	Kind     ErrorKind `json:"kind"`
+	FuncName string    `json:"func_name"`
+	Message  string    `json:"message"` // original error message
+	Stack    string    `json:"stack"`   // remote stack trace if available
+	cause    error     // original error on host side, not transferred
+}
+
+func (e *PluginError) Error() string {
+	if e.FuncName == "" {
+		return fmt.Sprintf("%s: %s", e.Kind, e.Message)
+	}
+	return fmt.Sprintf("call function %s failed, %s: %s", e.FuncName, e.Kind, e.Message)
+}
+
+func (e *PluginError) Unwrap() error {
+	return e.cause
+}
+
+// AsPluginError converts err to *PluginError,
+// err is regarded as user error if it is not a *PluginError of funcName,
+// e.g. error returned by host function is a user error of the calling plugin function.
+func AsPluginError(funcName string, err error) *PluginError {
+	if err == nil {
+		return nil
+	}
+	var pluginErr *PluginError
+	if errors.As(err, &pluginErr) && (pluginErr.FuncName == "" || pluginErr.FuncName == funcName) {
+		if pluginErr.FuncName == "" {
+			pluginErr.FuncName = funcName
+		}
+		return pluginErr
+	}
+
+	pluginErr = &PluginError{
+		Kind:     ErrKindUser,
+		FuncName: funcName,
+		Message:  err.Error(),
+		cause:    err,
+	}
+	// keep stack trace of errors created by github.com/pkg/errors
+	if st, ok := err.(interface{ StackTrace() errors.StackTrace }); ok {
+		pluginErr.Stack = fmt.Sprintf("%+v", st.StackTrace())
+	}
+	return pluginErr
+}
+
+func newFuncNotFoundError(funcName string) *PluginError {
+	return &PluginError{
+		Kind:     ErrKindFuncNotFound,
+		FuncName: funcName,
+		Message:  fmt.Sprintf("function %s not found", funcName),
+	}
+}
+
+func newTransportError(funcName string, err error) *PluginError {
+	return &PluginError{
+		Kind:     ErrKindTransportFailure,
+		FuncName: funcName,
+		Message:  err.Error(),
+		cause:    err,
+	}
+}
+
+var errorKindCodes = map[ErrorKind]codes.Code{
+	ErrKindFuncNotFound:     codes.NotFound,
+	ErrKindArgMismatch:      codes.InvalidArgument,
+	ErrKindUser:             codes.Unknown,
+	ErrKindPanic:            codes.Internal,
+	ErrKindTransportFailure: codes.Unavailable,
+	ErrKindStepLimit:        codes.ResourceExhausted,
+}
+
+// toGRPCStatusError encodes err as gRPC status error with PluginError details
+func toGRPCStatusError(funcName string, err error) error {
+	pluginErr := AsPluginError(funcName, err)
+
+	code := errorKindCodes[pluginErr.Kind]
+	if errors.Is(err, context.DeadlineExceeded) {
+		code = codes.DeadlineExceeded
+	} else if errors.Is(err, context.Canceled) {
+		code = codes.Canceled
+	}
+
+	st, e := status.New(code, pluginErr.Message).WithDetails(&protoGen.PluginError{
+		Kind:     string(pluginErr.Kind),
+		FuncName: pluginErr.FuncName,
+		Message:  pluginErr.Message,
+		Stack:    pluginErr.Stack,
+	})
+	if e != nil {
+		return status.Error(code, pluginErr.Message)
+	}
+	return st.Err()
+}
+
+// fromGRPCStatusError decodes PluginError from gRPC status error
+func fromGRPCStatusError(funcName string, err error) *PluginError {
+	st, ok := status.FromError(err)
+	if !ok {
+		return newTransportError(funcName, err)
+	}
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*protoGen.PluginError); ok {
+			return &PluginError{
+				Kind:     ErrorKind(d.Kind),
+				FuncName: d.FuncName,
+				Message:  d.Message,
+				Stack:    d.Stack,
+				cause:    err,
+			}
+		}
+	}
+
+	// plugin without PluginError details support
+	switch st.Code() {
+	case codes.Unknown:
+		// exception raised by plugin function
+		return &PluginError{Kind: ErrKindUser, FuncName: funcName, Message: st.Message(), cause: err}
+	default:
+		return newTransportError(funcName, err)
+	}
+}
@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"time"
+
+	"github.com/httprunner/funplugin/fungo"
 )
 
 func init() {
@@ -27,6 +33,8 @@ func Sum(args ...interface{}) (interface{}, error) {
 		switch v := arg.(type) {
 		case int:
 			sum += float64(v)
+		case int64:
+			sum += float64(v)
 		case float64:
 			sum += v
 		default:
@@ -36,6 +44,18 @@ func Sum(args ...interface{}) (interface{}, error) {
 	return sum, nil
 }
 
+func Divide(a, b int) int {
+	return a / b // panic if b is zero
+}
+
+// DivMod returns multiple values, which are returned to host as slice
+func DivMod(a, b int) (int, int, error) {
+	if b == 0 {
+		return 0, 0, errors.New("division by zero")
+	}
+	return a / b, a % b, nil
+}
+
 func SumTwoString(a, b string) string {
 	return a + b
 }
@@ -56,6 +76,63 @@ func Concatenate(args ...interface{}) (interface{}, error) {
 	return result, nil
 }
 
+func Sleep(ctx context.Context, seconds float64) (string, error) {
+	select {
+	case <-time.After(time.Duration(seconds * float64(time.Second))):
+		return fmt.Sprintf("slept %vs", seconds), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// SleepUninterruptible ignores cancellation, used to simulate a hung function
+func SleepUninterruptible(seconds float64) string {
+	time.Sleep(time.Duration(seconds * float64(time.Second)))
+	return fmt.Sprintf("slept %vs", seconds)
+}
+
+// GenerateInts pushes integers from 0 to n-1 one by one
+func GenerateInts(ctx context.Context, n int) <-chan int {
+	ch := make(chan int)
+	go func() {
+		defer close(ch)
+		for i := 0; i < n; i++ {
+			select {
+			case ch <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// CallHostFunction calls back function registered by host
+func CallHostFunction(funcName string, args ...interface{}) (interface{}, error) {
+	return fungo.CallHost(funcName, args...)
+}
+
+func Echo(value interface{}) interface{} {
+	return value
+}
+
+type GreetOptions struct {
+	Name     string `json:"name"`
+	Greeting string `json:"greeting"`
+}
+
+// Greet accepts keyword arguments decoded into GreetOptions
+func Greet(opts GreetOptions) string {
+	if opts.Greeting == "" {
+		opts.Greeting = "Hello"
+	}
+	return fmt.Sprintf("%s, %s!", opts.Greeting, opts.Name)
+}
+
+func GetPid() int {
+	return os.Getpid()
+}
+
 func SetupHookExample(args string) string {
 	return fmt.Sprintf("step name: %v, setup...", args)
 }
@@ -7,11 +7,22 @@ import (
 // register functions and build to plugin binary
 func main() {
 	fungo.Register("sum_ints", SumInts)
-	fungo.Register("sum_two_int", SumTwoInt)
+	fungo.Register("sum_two_int", SumTwoInt,
+		fungo.WithDoc("return the sum of two integers"),
+		fungo.WithParamNames("a", "b"))
 	fungo.Register("sum", Sum)
+	fungo.Register("divide", Divide)
+	fungo.Register("div_mod", DivMod)
 	fungo.Register("sum_two_string", SumTwoString)
 	fungo.Register("sum_strings", SumStrings)
 	fungo.Register("concatenate", Concatenate)
+	fungo.Register("sleep", Sleep)
+	fungo.Register("sleep_uninterruptible", SleepUninterruptible)
+	fungo.Register("generate_ints", GenerateInts)
+	fungo.Register("call_host_function", CallHostFunction)
+	fungo.Register("echo", Echo)
+	fungo.Register("greet", Greet)
+	fungo.Register("get_pid", GetPid)
 	fungo.Register("setup_hook_example", SetupHookExample)
 	fungo.Register("teardown_hook_example", TeardownHookExample)
 
@@ -2,10 +2,15 @@ package fungo
 
 import (
 	"context"
+	"fmt"
+	"io"
 
 	"github.com/hashicorp/go-plugin"
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	_ "google.golang.org/grpc/encoding/gzip" // register gzip compressor
+	"google.golang.org/grpc/status"
 
 	"github.com/httprunner/funplugin/fungo/protoGen"
 	jsoniter "github.com/json-iterator/go"
@@ -16,7 +21,106 @@ var json = jsoniter.ConfigCompatibleWithStandardLibrary
 
 // functionGRPCClient runs on the host side, it implements FuncCaller interface
 type functionGRPCClient struct {
-	client protoGen.DebugTalkClient
+	client      protoGen.DebugTalkClient
+	typedValues bool  // encode arguments in typed values, plugin replies in typed value as well
+	codec       Codec // codec negotiated with plugin, preferred to typed values if set
+	maxSendSize int   // requests beyond max size are sent in chunks, DefaultMaxMessageSize if 0
+}
+
+// negotiate agrees on codec with plugin when plugin starts,
+// JSON is always agreed since plugins without codec negotiation understand JSON as well.
+func (m *functionGRPCClient) negotiate(codec Codec) error {
+	logger.Debug("gRPC_client Negotiate() start", "codec", codec.Name())
+	resp, err := m.client.Negotiate(context.Background(), &protoGen.NegotiateRequest{
+		Codecs: []string{codec.Name()},
+	})
+	if status.Code(err) == codes.Unimplemented {
+		resp = &protoGen.NegotiateResponse{} // plugin built with older fungo/funppy
+	} else if err != nil {
+		logger.Error("gRPC_client Negotiate() failed", "error", err)
+		return errors.Wrap(err, "negotiate codec failed")
+	}
+
+	if resp.Codec == codec.Name() || codec.Name() == JSONCodec.Name() {
+		m.codec = codec
+		logger.Debug("gRPC_client Negotiate() success", "codec", codec.Name())
+		return nil
+	}
+	logger.Warn("codec not supported by plugin, fallback to default encoding",
+		"codec", codec.Name(), "typedValues", m.typedValues)
+	return nil
+}
+
+// newCallRequest encodes function arguments in negotiated codec, typed values or JSON
+func (m *functionGRPCClient) newCallRequest(funcName string, funcArgs []interface{}, kwargs map[string]interface{}) (*protoGen.CallRequest, error) {
+	if len(kwargs) > 0 && !m.typedValues {
+		// plugins negotiating protocol version 1 ignore keyword arguments
+		return nil, &PluginError{
+			Kind:     ErrKindArgMismatch,
+			FuncName: funcName,
+			Message:  "keyword arguments not supported by plugin built with older fungo/funppy",
+		}
+	}
+
+	req := &protoGen.CallRequest{Name: funcName}
+	if m.codec != nil {
+		args, err := m.codec.Marshal(funcArgs)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal funcArgs in %s", m.codec.Name())
+		}
+		req.Args = args
+		req.Codec = m.codec.Name()
+		if len(kwargs) > 0 {
+			req.Kwargs, err = m.codec.Marshal(kwargs)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to marshal kwargs in %s", m.codec.Name())
+			}
+		}
+		return req, nil
+	}
+	if m.typedValues {
+		args, err := encodeValues(funcArgs)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encode funcArgs")
+		}
+		req.TypedArgs = args
+		if len(kwargs) > 0 {
+			value, err := encodeValue(kwargs)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to encode kwargs")
+			}
+			req.TypedKwargs = value.GetMapValue()
+		}
+		return req, nil
+	}
+
+	args, err := json.Marshal(funcArgs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal funcArgs")
+	}
+	req.Args = args
+	return req, nil
+}
+
+// decodeResponse decodes typed value of response, or value encoded in codec of request,
+// codec is nil if request is encoded in typed values or JSON.
+func decodeResponse(response *protoGen.CallResponse, codec Codec) (interface{}, error) {
+	if response.TypedValue != nil {
+		value, err := decodeValue(response.TypedValue)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode response")
+		}
+		return value, nil
+	}
+	if codec == nil {
+		codec = JSONCodec
+	}
+
+	var value interface{}
+	if err := codec.Unmarshal(response.Value, &value); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal response")
+	}
+	return value, nil
 }
 
 func (m *functionGRPCClient) GetNames() ([]string, error) {
@@ -31,40 +135,225 @@ func (m *functionGRPCClient) GetNames() ([]string, error) {
 }
 
 func (m *functionGRPCClient) Call(funcName string, funcArgs ...interface{}) (interface{}, error) {
-	logger.Info("gRPC_client Call() start", "funcName", funcName, "funcArgs", funcArgs)
+	return m.CallContext(context.Background(), funcName, funcArgs...)
+}
+
+// CallContext calls plugin function, ctx deadline and cancellation are propagated to plugin via gRPC
+func (m *functionGRPCClient) CallContext(ctx context.Context, funcName string, funcArgs ...interface{}) (interface{}, error) {
+	return m.CallKw(ctx, funcName, funcArgs, nil)
+}
 
-	funcArgBytes, err := json.Marshal(funcArgs)
+// CallKw calls plugin function with keyword arguments
+func (m *functionGRPCClient) CallKw(ctx context.Context, funcName string, funcArgs []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	logger.Info("gRPC_client Call() start", "funcName", funcName, "funcArgs", logValue(funcArgs), "kwargs", logValue(kwargs))
+
+	req, err := m.newCallRequest(funcName, funcArgs, kwargs)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to marshal Call() funcArgs")
-	}
-	req := &protoGen.CallRequest{
-		Name: funcName,
-		Args: funcArgBytes,
+		var pluginErr *PluginError
+		if errors.As(err, &pluginErr) {
+			return nil, err
+		}
+		return nil, errors.Wrap(err, "Call() failed")
 	}
 
-	response, err := m.client.Call(context.Background(), req)
+	// oversized request and response are transferred in chunks by plugins since v0.6.0
+	req.AcceptChunks = m.typedValues
+	var response *protoGen.CallResponse
+	if m.typedValues && oversized(req, m.maxSendSize) {
+		response, err = m.callChunked(ctx, req)
+	} else {
+		response, err = m.client.Call(ctx, req)
+	}
+	if err == nil && response.ChunkId != "" {
+		response, err = m.fetchChunks(ctx, response.ChunkId)
+	}
 	if err != nil {
 		logger.Error("gRPC_client Call() failed",
 			"funcName", funcName,
-			"funcArgs", funcArgs,
+			"funcArgs", logValue(funcArgs),
 			"error", err,
 		)
-		return nil, err
+		return nil, fromGRPCStatusError(funcName, err)
 	}
 
-	var resp interface{}
-	err = json.Unmarshal(response.Value, &resp)
+	resp, err := decodeResponse(response, m.codec)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal Call() response")
+		return nil, errors.Wrap(err, "Call() failed")
 	}
-	logger.Info("gRPC_client Call() success", "result", resp)
+	logger.Info("gRPC_client Call() success", "result", logValue(resp))
 	return resp, nil
 }
 
+// CallStream calls plugin function and receives values pushed by plugin one at a time,
+// errors of plugin function are returned by Recv of the stream.
+func (m *functionGRPCClient) CallStream(ctx context.Context, funcName string, funcArgs ...interface{}) (Stream, error) {
+	logger.Info("gRPC_client CallStream() start", "funcName", funcName, "funcArgs", logValue(funcArgs))
+
+	req, err := m.newCallRequest(funcName, funcArgs, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "CallStream() failed")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := m.client.CallStream(ctx, req)
+	if err != nil {
+		cancel()
+		logger.Error("gRPC_client CallStream() failed",
+			"funcName", funcName,
+			"funcArgs", logValue(funcArgs),
+			"error", err,
+		)
+		return nil, fromGRPCStatusError(funcName, err)
+	}
+	return &grpcStream{funcName: funcName, stream: stream, cancel: cancel, codec: m.codec}, nil
+}
+
+// grpcStream receives values of plugin function via gRPC server-streaming
+type grpcStream struct {
+	funcName string
+	stream   protoGen.DebugTalk_CallStreamClient
+	cancel   context.CancelFunc
+	codec    Codec
+}
+
+func (s *grpcStream) Recv() (interface{}, error) {
+	response, err := s.stream.Recv()
+	if err == io.EOF {
+		s.cancel()
+		return nil, io.EOF
+	} else if err != nil {
+		s.cancel()
+		logger.Error("gRPC_client CallStream() recv failed", "funcName", s.funcName, "error", err)
+		return nil, fromGRPCStatusError(s.funcName, err)
+	}
+
+	value, err := decodeResponse(response, s.codec)
+	if err != nil {
+		return nil, errors.Wrap(err, "CallStream() failed")
+	}
+	return value, nil
+}
+
+func (s *grpcStream) Close() error {
+	s.cancel()
+	return nil
+}
+
+// connectHost serves host functions via broker and lets plugin connect to them
+func (m *functionGRPCClient) connectHost(broker *plugin.GRPCBroker, host IFuncCaller) error {
+	brokerID := broker.NextId()
+	go broker.AcceptAndServe(brokerID, func(opts []grpc.ServerOption) *grpc.Server {
+		s := grpc.NewServer(opts...)
+		protoGen.RegisterDebugTalkServer(s, &functionGRPCServer{Impl: host, maxSendSize: m.maxSendSize})
+		return s
+	})
+
+	logger.Debug("gRPC_client ConnectHost() start", "brokerID", brokerID)
+	_, err := m.client.ConnectHost(context.Background(), &protoGen.ConnectHostRequest{BrokerId: brokerID})
+	if err != nil {
+		logger.Error("gRPC_client ConnectHost() failed", "error", err)
+		return errors.Wrap(err, "connect host functions failed")
+	}
+	logger.Debug("gRPC_client ConnectHost() success")
+	return nil
+}
+
+func (m *functionGRPCClient) Describe(funcName string) (*FuncSignature, error) {
+	logger.Debug("gRPC_client Describe() start", "funcName", funcName)
+	resp, err := m.client.Describe(context.Background(), &protoGen.DescribeRequest{Name: funcName})
+	if err != nil {
+		logger.Error("gRPC_client Describe() failed", "funcName", funcName, "error", err)
+		return nil, fromGRPCStatusError(funcName, err)
+	}
+	sig := &FuncSignature{
+		Name:     resp.Name,
+		Params:   make([]FuncParam, 0, len(resp.Params)),
+		Variadic: resp.Variadic,
+		Returns:  append([]string{}, resp.Returns...),
+		Doc:      resp.Doc,
+	}
+	for _, param := range resp.Params {
+		sig.Params = append(sig.Params, FuncParam{
+			Name:     param.Name,
+			Type:     param.Type,
+			Optional: param.Optional,
+		})
+	}
+	logger.Debug("gRPC_client Describe() success")
+	return sig, nil
+}
+
 // Here is the gRPC server that functionGRPCClient talks to.
 type functionGRPCServer struct {
 	protoGen.UnimplementedDebugTalkServer
-	Impl IFuncCaller
+	Impl        IFuncCaller
+	broker      *plugin.GRPCBroker // used to connect host functions, nil when serving host functions
+	maxSendSize int                // responses beyond max size are kept to be fetched in chunks
+	chunks      chunkStore
+}
+
+// requestCodec returns codec of request, JSON if not specified
+func requestCodec(req *protoGen.CallRequest) (Codec, error) {
+	if req.Codec == "" {
+		return JSONCodec, nil
+	}
+	codec, ok := getCodec(req.Codec)
+	if !ok {
+		return nil, fmt.Errorf("codec %s not registered", req.Codec)
+	}
+	return codec, nil
+}
+
+// decodeRequest decodes function arguments and keyword arguments in typed values or codec of request
+func decodeRequest(req *protoGen.CallRequest) ([]interface{}, map[string]interface{}, error) {
+	if req.TypedArgs != nil {
+		funcArgs, err := decodeValues(req.TypedArgs)
+		if err != nil || req.TypedKwargs == nil {
+			return funcArgs, nil, err
+		}
+		kwargs, err := decodeValue(&protoGen.Value{Kind: &protoGen.Value_MapValue{MapValue: req.TypedKwargs}})
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "decode kwargs failed")
+		}
+		return funcArgs, kwargs.(map[string]interface{}), nil
+	}
+
+	codec, err := requestCodec(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	var funcArgs []interface{}
+	if err := codec.Unmarshal(req.Args, &funcArgs); err != nil {
+		return nil, nil, err
+	}
+	var kwargs map[string]interface{}
+	if len(req.Kwargs) > 0 {
+		if err := codec.Unmarshal(req.Kwargs, &kwargs); err != nil {
+			return nil, nil, errors.Wrap(err, "decode kwargs failed")
+		}
+	}
+	return funcArgs, kwargs, nil
+}
+
+// encodeResponse encodes value in typed value if request has typed arguments, otherwise in codec of request
+func encodeResponse(req *protoGen.CallRequest, v interface{}) (*protoGen.CallResponse, error) {
+	if req.TypedArgs != nil {
+		value, err := encodeValue(v)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to encode response")
+		}
+		return &protoGen.CallResponse{TypedValue: value}, nil
+	}
+
+	codec, err := requestCodec(req)
+	if err != nil {
+		return nil, err
+	}
+	value, err := codec.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal response in %s", codec.Name())
+	}
+	return &protoGen.CallResponse{Value: value}, nil
 }
 
 func (m *functionGRPCServer) GetNames(ctx context.Context, req *protoGen.Empty) (*protoGen.GetNamesResponse, error) {
@@ -81,36 +370,159 @@ func (m *functionGRPCServer) GetNames(ctx context.Context, req *protoGen.Empty)
 func (m *functionGRPCServer) Call(ctx context.Context, req *protoGen.CallRequest) (*protoGen.CallResponse, error) {
 	logger.Debug("gRPC_server Call() start")
 
-	var funcArgs []interface{}
-	if err := json.Unmarshal(req.Args, &funcArgs); err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal Call() funcArgs")
+	funcArgs, kwargs, err := decodeRequest(req)
+	if err != nil {
+		return nil, toGRPCStatusError(req.Name, &PluginError{
+			Kind:    ErrKindArgMismatch,
+			Message: errors.Wrap(err, "failed to decode Call() funcArgs").Error(),
+		})
 	}
 
-	v, err := m.Impl.Call(req.Name, funcArgs...)
+	v, err := m.Impl.CallKw(ctx, req.Name, funcArgs, kwargs)
 	if err != nil {
 		logger.Error("gRPC_server Call() failed", "req", req, "error", err)
-		return nil, err
+		return nil, toGRPCStatusError(req.Name, err)
 	}
 
-	value, err := json.Marshal(v)
+	resp, err := encodeResponse(req, v)
+	if err == nil {
+		resp, err = m.deferOversized(req, resp)
+	}
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to marshal Call() response")
+		return nil, toGRPCStatusError(req.Name, &PluginError{Kind: ErrKindUser, Message: err.Error()})
 	}
 	logger.Debug("gRPC_server Call() success")
-	return &protoGen.CallResponse{Value: value}, nil
+	return resp, nil
+}
+
+func (m *functionGRPCServer) CallStream(req *protoGen.CallRequest, srv protoGen.DebugTalk_CallStreamServer) error {
+	logger.Debug("gRPC_server CallStream() start")
+
+	funcArgs, kwargs, err := decodeRequest(req)
+	if err == nil && len(kwargs) > 0 {
+		err = errors.New("keyword arguments not supported by stream")
+	}
+	if err != nil {
+		return toGRPCStatusError(req.Name, &PluginError{
+			Kind:    ErrKindArgMismatch,
+			Message: errors.Wrap(err, "failed to decode CallStream() funcArgs").Error(),
+		})
+	}
+
+	stream, err := m.Impl.CallStream(srv.Context(), req.Name, funcArgs...)
+	if err != nil {
+		logger.Error("gRPC_server CallStream() failed", "req", req, "error", err)
+		return toGRPCStatusError(req.Name, err)
+	}
+	defer stream.Close()
+
+	for {
+		v, err := stream.Recv()
+		if err == io.EOF {
+			logger.Debug("gRPC_server CallStream() success")
+			return nil
+		} else if err != nil {
+			logger.Error("gRPC_server CallStream() recv failed", "req", req, "error", err)
+			return toGRPCStatusError(req.Name, err)
+		}
+
+		resp, err := encodeResponse(req, v)
+		if err != nil {
+			return toGRPCStatusError(req.Name, &PluginError{Kind: ErrKindUser, Message: err.Error()})
+		}
+		if err := srv.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (m *functionGRPCServer) Describe(ctx context.Context, req *protoGen.DescribeRequest) (*protoGen.DescribeResponse, error) {
+	logger.Debug("gRPC_server Describe() start")
+	sig, err := m.Impl.Describe(req.Name)
+	if err != nil {
+		logger.Error("gRPC_server Describe() failed", "req", req, "error", err)
+		return nil, toGRPCStatusError(req.Name, err)
+	}
+	resp := &protoGen.DescribeResponse{
+		Name:     sig.Name,
+		Variadic: sig.Variadic,
+		Returns:  sig.Returns,
+		Doc:      sig.Doc,
+	}
+	for _, param := range sig.Params {
+		resp.Params = append(resp.Params, &protoGen.Parameter{
+			Name:     param.Name,
+			Type:     param.Type,
+			Optional: param.Optional,
+		})
+	}
+	logger.Debug("gRPC_server Describe() success")
+	return resp, nil
+}
+
+func (m *functionGRPCServer) ConnectHost(ctx context.Context, req *protoGen.ConnectHostRequest) (*protoGen.Empty, error) {
+	logger.Debug("gRPC_server ConnectHost() start", "brokerID", req.BrokerId)
+	if m.broker == nil {
+		return nil, errors.New("broker not available")
+	}
+	conn, err := m.broker.Dial(req.BrokerId)
+	if err != nil {
+		logger.Error("gRPC_server ConnectHost() failed", "error", err)
+		return nil, err
+	}
+	// host functions are served by host since v0.6.0, which always supports typed values
+	setHost(&functionGRPCClient{
+		client:      protoGen.NewDebugTalkClient(conn),
+		typedValues: true,
+		maxSendSize: m.maxSendSize,
+	})
+	logger.Debug("gRPC_server ConnectHost() success")
+	return &protoGen.Empty{}, nil
+}
+
+// Negotiate chooses the first codec offered by host which is registered in plugin
+func (m *functionGRPCServer) Negotiate(ctx context.Context, req *protoGen.NegotiateRequest) (*protoGen.NegotiateResponse, error) {
+	logger.Debug("gRPC_server Negotiate() start", "codecs", req.Codecs)
+	for _, name := range req.Codecs {
+		if _, ok := getCodec(name); ok {
+			logger.Debug("gRPC_server Negotiate() success", "codec", name)
+			return &protoGen.NegotiateResponse{Codec: name}, nil
+		}
+	}
+	logger.Warn("gRPC_server Negotiate() no codec supported", "codecs", req.Codecs)
+	return &protoGen.NegotiateResponse{}, nil
 }
 
 // GRPCPlugin implements hashicorp's plugin.GRPCPlugin.
 type GRPCPlugin struct {
 	plugin.Plugin
-	Impl IFuncCaller
+	Impl          IFuncCaller            // plugin functions, used on plugin side
+	HostFunctions map[string]interface{} // host functions called back by plugin, used on host side
+	TypedValues   bool                   // encode values in typed values instead of JSON, used on host side
+	Codec         Codec                  // codec negotiated with plugin, preferred to typed values, used on host side
+	MaxSendSize   int                    // max size of messages sent to the other side, DefaultMaxMessageSize if 0
 }
 
 func (p *GRPCPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
-	protoGen.RegisterDebugTalkServer(s, &functionGRPCServer{Impl: p.Impl})
+	protoGen.RegisterDebugTalkServer(s, &functionGRPCServer{Impl: p.Impl, broker: broker, maxSendSize: p.MaxSendSize})
 	return nil
 }
 
 func (p *GRPCPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
-	return &functionGRPCClient{client: protoGen.NewDebugTalkClient(c)}, nil
+	client := &functionGRPCClient{
+		client:      protoGen.NewDebugTalkClient(c),
+		typedValues: p.TypedValues,
+		maxSendSize: p.MaxSendSize,
+	}
+	if p.Codec != nil {
+		if err := client.negotiate(p.Codec); err != nil {
+			return nil, err
+		}
+	}
+	if len(p.HostFunctions) > 0 {
+		if err := client.connectHost(broker, newHostFuncCaller(p.HostFunctions)); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
 }
@@ -0,0 +1,94 @@
+package fungo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// ErrHostNotConnected is returned by CallHost if host did not register host functions
+var ErrHostNotConnected = errors.New("host functions not connected")
+
+var (
+	hostMu        sync.RWMutex
+	host          IFuncCaller            // caller of host functions, set when host connects
+	hostFunctions map[string]interface{} // host functions registered by RegisterHostFunctions
+)
+
+func setHost(caller IFuncCaller) {
+	hostMu.Lock()
+	defer hostMu.Unlock()
+	host = caller
+}
+
+// CallHost calls host function registered by funplugin.WithHostFunctions,
+// it can be called in plugin functions to reach the host process.
+func CallHost(funcName string, args ...interface{}) (interface{}, error) {
+	return CallHostContext(context.Background(), funcName, args...)
+}
+
+// CallHostContext calls host function with context
+func CallHostContext(ctx context.Context, funcName string, args ...interface{}) (interface{}, error) {
+	hostMu.RLock()
+	caller := host
+	hostMu.RUnlock()
+	if caller == nil {
+		return nil, ErrHostNotConnected
+	}
+	return caller.CallContext(ctx, funcName, args...)
+}
+
+// RegisterHostFunctions makes host functions callable by CallHost in the same process,
+// it is used by go plugin which is loaded into host process. The registry is shared by all
+// go plugins in process, thus registering functions different from registered ones fails.
+func RegisterHostFunctions(funcs map[string]interface{}) error {
+	hostMu.Lock()
+	defer hostMu.Unlock()
+	if hostFunctions != nil {
+		if !sameFunctions(hostFunctions, funcs) {
+			return errors.New("host functions conflict with those registered by other go plugin")
+		}
+		return nil
+	}
+	hostFunctions = funcs
+	host = newHostFuncCaller(funcs)
+	return nil
+}
+
+// sameFunctions checks if a and b have the same functions with the same names
+func sameFunctions(a, b map[string]interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for funcName, fn := range a {
+		other, ok := b[funcName]
+		if !ok || reflect.ValueOf(fn).Pointer() != reflect.ValueOf(other).Pointer() {
+			return false
+		}
+	}
+	return true
+}
+
+// NewHostCaller creates caller of host functions owned by one plugin running in host process,
+// e.g. interpreted go or starlark plugin, which is not shared with other plugins like RegisterHostFunctions.
+func NewHostCaller(funcs map[string]interface{}) IFuncCaller {
+	return newHostFuncCaller(funcs)
+}
+
+// newHostFuncCaller creates caller of host functions
+func newHostFuncCaller(funcs map[string]interface{}) *functionPlugin {
+	hostFunctions := make(functionsMap)
+	for funcName, fn := range funcs {
+		f := &function{fn: reflect.ValueOf(fn)}
+		hostFunctions[funcName] = f
+		// automatic registration with common name
+		if _, ok := hostFunctions[ConvertCommonName(funcName)]; !ok {
+			hostFunctions[ConvertCommonName(funcName)] = f
+		}
+	}
+	return &functionPlugin{
+		logger:    logger.Named("host_func_exec"),
+		functions: hostFunctions,
+	}
+}
@@ -0,0 +1,43 @@
+package fungo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterHostFunctions(t *testing.T) {
+	defer func() {
+		hostFunctions = nil
+		setHost(nil)
+	}()
+
+	sum := func(a, b int) int { return a + b }
+	concat := func(a, b string) string { return a + b }
+	if err := RegisterHostFunctions(map[string]interface{}{"sum": sum}); err != nil {
+		t.Fatal(err)
+	}
+	v, err := CallHost("sum", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+
+	// go plugins with the same host functions share the registry
+	if !assert.NoError(t, RegisterHostFunctions(map[string]interface{}{"sum": sum})) {
+		t.Fail()
+	}
+
+	// different host functions can not be registered in process
+	for _, funcs := range []map[string]interface{}{
+		{"sum": concat},
+		{"concat": concat},
+		{"sum": sum, "concat": concat},
+	} {
+		if !assert.Error(t, RegisterHostFunctions(funcs)) {
+			t.Fail()
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package fungo
 
 import (
+	"context"
 	"io"
 	"os"
 	"path/filepath"
@@ -9,7 +10,7 @@ import (
 	"github.com/hashicorp/go-plugin"
 )
 
-const Version = "v0.5.4"
+const Version = "v0.6.0"
 
 var (
 	logger = Logger
@@ -58,7 +59,9 @@ func InitLogger(logLevel hclog.Level, logFile string, disableTime bool) hclog.Lo
 func CloseLogFile() error {
 	if file != nil {
 		logger.Info("close log file")
-		return file.Close()
+		err := file.Close()
+		file = nil
+		return err
 	}
 	return nil
 }
@@ -66,18 +69,51 @@ func CloseLogFile() error {
 // PluginTypeEnvName is used to specify hashicorp go plugin type, rpc/grpc
 const PluginTypeEnvName = "HRP_PLUGIN_TYPE"
 
+// environment variables specified by host to configure gRPC server of plugin
+const (
+	MaxSendSizeEnvName = "HRP_PLUGIN_MAX_SEND_SIZE" // max size of messages sent by plugin, i.e. max receive size of host
+	MaxRecvSizeEnvName = "HRP_PLUGIN_MAX_RECV_SIZE" // max size of messages received by plugin, i.e. max send size of host
+	CompressionEnvName = "HRP_PLUGIN_COMPRESSION"   // compressor of responses, fungo replies in the same compressor as requests
+)
+
 // HandshakeConfig is used to just do a basic handshake between
 // a plugin and host. If the handshake fails, a user friendly error is shown.
 // This prevents users from executing bad plugins or executing a plugin
 // directory. It is a UX feature, not a security feature.
 var HandshakeConfig = plugin.HandshakeConfig{
-	ProtocolVersion:  1,
+	ProtocolVersion:  ProtocolVersionJSON,
 	MagicCookieKey:   "HttpRunnerPlus",
 	MagicCookieValue: "debugtalk",
 }
 
+// plugin protocol versions negotiated between host and plugin when plugin starts
+const (
+	ProtocolVersionJSON  = 1 // gRPC values are encoded in JSON, numbers are decoded as float64
+	ProtocolVersionTyped = 2 // gRPC values are encoded in typed values, keeps int64, bytes, time and big numbers, RPC errors are structured
+)
+
 // IFuncCaller is the interface that we're exposing as a plugin.
 type IFuncCaller interface {
-	GetNames() ([]string, error)                                    // get all plugin function names list
-	Call(funcName string, args ...interface{}) (interface{}, error) // call plugin function
+	GetNames() ([]string, error)                                                                                         // get all plugin function names list
+	Call(funcName string, args ...interface{}) (interface{}, error)                                                      // call plugin function
+	CallContext(ctx context.Context, funcName string, args ...interface{}) (interface{}, error)                          // call plugin function with context
+	CallStream(ctx context.Context, funcName string, args ...interface{}) (Stream, error)                                // call plugin function and receive values as stream
+	CallKw(ctx context.Context, funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) // call plugin function with keyword arguments
+	Describe(funcName string) (*FuncSignature, error)                                                                    // get plugin function signature
+}
+
+// FuncSignature describes the signature of a plugin function
+type FuncSignature struct {
+	Name     string      `json:"name"`
+	Params   []FuncParam `json:"params"`
+	Variadic bool        `json:"variadic"` // last parameter accepts variable arguments
+	Returns  []string    `json:"returns"`  // return types
+	Doc      string      `json:"doc"`
+}
+
+// FuncParam describes a plugin function parameter
+type FuncParam struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`     // element type for variadic parameter
+	Optional bool   `json:"optional"` // parameter has default value
 }
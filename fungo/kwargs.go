@@ -0,0 +1,128 @@
+package fungo
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// CallFuncKw calls function with positional and keyword arguments.
+// Keyword arguments are bound to parameters named by paramNames (see WithParamNames),
+// or decoded into the last struct parameter by its JSON tags if positional arguments
+// fill the parameters before it. Parameters given by neither are set to zero values.
+func CallFuncKw(ctx context.Context, fn reflect.Value, paramNames []string,
+	args []interface{}, kwargs map[string]interface{},
+) (interface{}, error) {
+	if len(kwargs) == 0 {
+		return CallFuncContext(ctx, fn, args...)
+	}
+
+	args, err := bindKwargs(fn.Type(), paramNames, args, kwargs)
+	if err != nil {
+		logger.Error("bind keyword arguments failed", "error", err)
+		return nil, &PluginError{Kind: ErrKindArgMismatch, Message: err.Error(), cause: err}
+	}
+	return CallFuncContext(ctx, fn, args...)
+}
+
+// bindKwargs merges keyword arguments into positional arguments,
+// leading context.Context parameter is not counted.
+func bindKwargs(fnType reflect.Type, paramNames []string,
+	args []interface{}, kwargs map[string]interface{},
+) ([]interface{}, error) {
+	start := 0
+	if acceptsContext(fnType) {
+		start = 1
+	}
+	fixed := fnType.NumIn() - start // number of non-variadic parameters
+	if fnType.IsVariadic() {
+		fixed--
+	}
+
+	// keyword arguments decoded into the last struct parameter
+	if fixed > 0 && len(args) == fixed-1 {
+		paramType := fnType.In(start + fixed - 1)
+		if isStructParam(paramType) {
+			param, err := decodeStruct(kwargs, paramType)
+			if err != nil {
+				return nil, err
+			}
+			return append(args, param), nil
+		}
+	}
+
+	if len(paramNames) == 0 {
+		return nil, fmt.Errorf("keyword arguments not accepted, parameter names not registered")
+	}
+
+	n := fixed
+	if len(args) > n {
+		n = len(args) // extra positional arguments for variadic parameter
+	}
+	bound := make([]interface{}, n)
+	given := make([]bool, n)
+	for i, arg := range args {
+		bound[i] = arg
+		given[i] = true
+	}
+
+	names := make([]string, 0, len(kwargs))
+	for name := range kwargs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		index := -1
+		for i := 0; i < fixed && i < len(paramNames); i++ {
+			if paramNames[i] == name {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("unexpected keyword argument %s", name)
+		}
+		if given[index] {
+			return nil, fmt.Errorf("got multiple values for argument %s", name)
+		}
+		bound[index] = kwargs[name]
+		given[index] = true
+	}
+	// parameters not given are nil, which are converted to zero values
+	return bound, nil
+}
+
+// isStructParam checks if parameter is a struct or pointer to struct,
+// time.Time and big numbers are regarded as values instead of structs.
+func isStructParam(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && t != timeType && t != bigIntType && t != bigFloatType
+}
+
+// decodeStruct decodes keyword arguments to struct or pointer to struct by JSON tags, unknown names are rejected
+func decodeStruct(kwargs map[string]interface{}, t reflect.Type) (interface{}, error) {
+	elemType := t
+	if t.Kind() == reflect.Ptr {
+		elemType = t.Elem()
+	}
+	fields := structFields(elemType)
+	names := make([]string, 0, len(kwargs))
+	for name := range kwargs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := fields[name]; !ok {
+			return nil, fmt.Errorf("unexpected keyword argument %s", name)
+		}
+	}
+
+	value, err := convertValue(reflect.ValueOf(kwargs), t, "argument kwargs")
+	if err != nil {
+		return nil, err
+	}
+	return value.Interface(), nil
+}
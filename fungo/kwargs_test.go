@@ -0,0 +1,83 @@
+package fungo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallFuncKw(t *testing.T) {
+	ctx := context.Background()
+	names := []string{"a", "b", "c"}
+	fn := reflect.ValueOf(func(ctx context.Context, a int, b string, c float64) string {
+		return fmt.Sprintf("%d-%s-%v", a, b, c)
+	})
+
+	params := []struct {
+		args   []interface{}
+		kwargs map[string]interface{}
+		expVal interface{}
+	}{
+		{[]interface{}{1}, map[string]interface{}{"b": "x", "c": 1.5}, "1-x-1.5"},
+		{nil, map[string]interface{}{"c": 2, "a": 3}, "3--2"},
+		{[]interface{}{1, "x", 2.5}, nil, "1-x-2.5"},
+	}
+	for _, p := range params {
+		val, err := CallFuncKw(ctx, fn, names, p.args, p.kwargs)
+		if !assert.NoError(t, err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, p.expVal, val) {
+			t.Fatal()
+		}
+	}
+
+	// keyword arguments decoded into struct parameter
+	type options struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+	structFn := reflect.ValueOf(func(prefix string, opts *options) string {
+		return fmt.Sprintf("%s%s*%d", prefix, opts.Name, opts.Count)
+	})
+	val, err := CallFuncKw(ctx, structFn, nil, []interface{}{"#"},
+		map[string]interface{}{"name": "foo", "count": 2})
+	if !assert.NoError(t, err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, "#foo*2", val) {
+		t.Fatal()
+	}
+
+	errParams := []struct {
+		fn      reflect.Value
+		names   []string
+		args    []interface{}
+		kwargs  map[string]interface{}
+		message string
+	}{
+		{fn, names, nil, map[string]interface{}{"d": 1}, "unexpected keyword argument d"},
+		{fn, names, []interface{}{1}, map[string]interface{}{"a": 1}, "got multiple values for argument a"},
+		{fn, nil, nil, map[string]interface{}{"a": 1}, "keyword arguments not accepted, parameter names not registered"},
+		{structFn, nil, []interface{}{"#"}, map[string]interface{}{"size": 1}, "unexpected keyword argument size"},
+		{structFn, nil, []interface{}{"#"}, map[string]interface{}{"count": 1.5},
+			"function argument kwargs.count has fraction, cannot convert 1.5 to int"},
+	}
+	for _, p := range errParams {
+		_, err := CallFuncKw(ctx, p.fn, p.names, p.args, p.kwargs)
+		var pluginErr *PluginError
+		if !assert.True(t, errors.As(err, &pluginErr), err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, ErrKindArgMismatch, pluginErr.Kind) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, p.message, pluginErr.Message) {
+			t.Fatal()
+		}
+	}
+}
@@ -1,16 +1,25 @@
 package fungo
 
 import (
-	"fmt"
+	"context"
 	"os"
 	"reflect"
+	"strconv"
 
 	hclog "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
 )
 
+// function is a registered plugin function
+type function struct {
+	fn         reflect.Value
+	paramNames []string // parameter names, optional
+	doc        string   // function documentation, optional
+}
+
 // functionsMap stores plugin functions
-type functionsMap map[string]reflect.Value
+type functionsMap map[string]*function
 
 // functionPlugin implements the FuncCaller interface
 type functionPlugin struct {
@@ -28,27 +37,96 @@ func (p *functionPlugin) GetNames() ([]string, error) {
 }
 
 func (p *functionPlugin) Call(funcName string, args ...interface{}) (interface{}, error) {
+	return p.CallContext(context.Background(), funcName, args...)
+}
+
+func (p *functionPlugin) CallContext(ctx context.Context, funcName string, args ...interface{}) (interface{}, error) {
 	// notice: this is the actual place where plugin function is called
-	p.logger.Debug("plugin function execution", "funcName", funcName, "args", args)
+	p.logger.Debug("plugin function execution", "funcName", funcName, "args", logValue(args))
+
+	f, ok := p.functions[funcName]
+	if !ok {
+		return nil, newFuncNotFoundError(funcName)
+	}
+
+	result, err := CallFuncContext(ctx, f.fn, args...)
+	if err != nil {
+		return nil, AsPluginError(funcName, err)
+	}
+	return result, nil
+}
+
+// CallKw calls function with keyword arguments bound to registered parameter names or struct parameter
+func (p *functionPlugin) CallKw(ctx context.Context, funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	p.logger.Debug("plugin function execution", "funcName", funcName, "args", logValue(args), "kwargs", logValue(kwargs))
+
+	f, ok := p.functions[funcName]
+	if !ok {
+		return nil, newFuncNotFoundError(funcName)
+	}
+
+	result, err := CallFuncKw(ctx, f.fn, f.paramNames, args, kwargs)
+	if err != nil {
+		return nil, AsPluginError(funcName, err)
+	}
+	return result, nil
+}
+
+func (p *functionPlugin) CallStream(ctx context.Context, funcName string, args ...interface{}) (Stream, error) {
+	p.logger.Debug("plugin stream function execution", "funcName", funcName, "args", logValue(args))
 
-	fn, ok := p.functions[funcName]
+	f, ok := p.functions[funcName]
 	if !ok {
-		return nil, fmt.Errorf("function %s not found", funcName)
+		return nil, newFuncNotFoundError(funcName)
 	}
 
-	return CallFunc(fn, args...)
+	stream, err := CallFuncStream(ctx, f.fn, args...)
+	if err != nil {
+		return nil, AsPluginError(funcName, err)
+	}
+	return stream, nil
+}
+
+func (p *functionPlugin) Describe(funcName string) (*FuncSignature, error) {
+	f, ok := p.functions[funcName]
+	if !ok {
+		return nil, newFuncNotFoundError(funcName)
+	}
+	return DescribeFunc(funcName, f.fn, f.paramNames, f.doc), nil
 }
 
 var functions = make(functionsMap)
 
+// FuncOption specifies extra information when registering plugin function
+type FuncOption func(*function)
+
+// WithDoc specifies the documentation of plugin function
+func WithDoc(doc string) FuncOption {
+	return func(f *function) {
+		f.doc = doc
+	}
+}
+
+// WithParamNames specifies the parameter names of plugin function,
+// leading context.Context parameter should not be included.
+func WithParamNames(names ...string) FuncOption {
+	return func(f *function) {
+		f.paramNames = names
+	}
+}
+
 // Register registers a plugin function.
 // Every plugin function must be registered before Serve() is called.
-func Register(funcName string, fn interface{}) {
+func Register(funcName string, fn interface{}, options ...FuncOption) {
 	if _, ok := functions[funcName]; ok {
 		return
 	}
 	logger.Info("register plugin function", "funcName", funcName)
-	functions[funcName] = reflect.ValueOf(fn)
+	f := &function{fn: reflect.ValueOf(fn)}
+	for _, option := range options {
+		option(f)
+	}
+	functions[funcName] = f
 	// automatic registration with common name
 	functions[ConvertCommonName(funcName)] = functions[funcName]
 }
@@ -61,13 +139,18 @@ func serveRPC() {
 		logger:    logger.Named("func_exec"),
 		functions: functions,
 	}
-	var pluginMap = map[string]plugin.Plugin{
-		rpcPluginName: &RPCPlugin{Impl: funcPlugin},
-	}
-	// start RPC server
+	// start RPC server, function call error is replied in CallResult
+	// to hosts negotiating protocol version 2, and in rpc.ServerError to older hosts
 	plugin.Serve(&plugin.ServeConfig{
 		HandshakeConfig: HandshakeConfig,
-		Plugins:         pluginMap,
+		VersionedPlugins: map[int]plugin.PluginSet{
+			ProtocolVersionJSON: {
+				rpcPluginName: &RPCPlugin{Impl: funcPlugin},
+			},
+			ProtocolVersionTyped: {
+				rpcPluginName: &RPCPlugin{Impl: funcPlugin, StructuredReply: true},
+			},
+		},
 	})
 }
 
@@ -79,17 +162,35 @@ func serveGRPC() {
 		logger:    logger.Named("func_exec"),
 		functions: functions,
 	}
+	maxSendSize := getEnvInt(MaxSendSizeEnvName, DefaultMaxMessageSize)
+	maxRecvSize := getEnvInt(MaxRecvSizeEnvName, DefaultMaxMessageSize)
 	var pluginMap = map[string]plugin.Plugin{
-		grpcPluginName: &GRPCPlugin{Impl: funcPlugin},
+		grpcPluginName: &GRPCPlugin{Impl: funcPlugin, MaxSendSize: maxSendSize},
 	}
-	// start gRPC server
+	// start gRPC server, typed values are decided by host per request,
+	// responses are compressed in the same compressor as requests
 	plugin.Serve(&plugin.ServeConfig{
 		HandshakeConfig: HandshakeConfig,
-		Plugins:         pluginMap,
-		GRPCServer:      plugin.DefaultGRPCServer,
+		VersionedPlugins: map[int]plugin.PluginSet{
+			ProtocolVersionJSON:  pluginMap,
+			ProtocolVersionTyped: pluginMap,
+		},
+		GRPCServer: func(opts []grpc.ServerOption) *grpc.Server {
+			opts = append(opts, grpc.MaxSendMsgSize(maxSendSize), grpc.MaxRecvMsgSize(maxRecvSize))
+			return plugin.DefaultGRPCServer(opts)
+		},
 	})
 }
 
+// getEnvInt returns integer value of environment variable, or defaultValue if not set or invalid
+func getEnvInt(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
 // default to run plugin in gRPC mode
 func Serve() {
 	if os.Getenv(PluginTypeEnvName) == "rpc" {
@@ -24,6 +24,12 @@ const _ = grpc.SupportPackageIsVersion7
 type DebugTalkClient interface {
 	GetNames(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*GetNamesResponse, error)
 	Call(ctx context.Context, in *CallRequest, opts ...grpc.CallOption) (*CallResponse, error)
+	CallStream(ctx context.Context, in *CallRequest, opts ...grpc.CallOption) (DebugTalk_CallStreamClient, error)
+	Describe(ctx context.Context, in *DescribeRequest, opts ...grpc.CallOption) (*DescribeResponse, error)
+	ConnectHost(ctx context.Context, in *ConnectHostRequest, opts ...grpc.CallOption) (*Empty, error)
+	Negotiate(ctx context.Context, in *NegotiateRequest, opts ...grpc.CallOption) (*NegotiateResponse, error)
+	CallChunked(ctx context.Context, opts ...grpc.CallOption) (DebugTalk_CallChunkedClient, error)
+	FetchChunks(ctx context.Context, in *FetchChunksRequest, opts ...grpc.CallOption) (DebugTalk_FetchChunksClient, error)
 }
 
 type debugTalkClient struct {
@@ -52,12 +58,143 @@ func (c *debugTalkClient) Call(ctx context.Context, in *CallRequest, opts ...grp
 	return out, nil
 }
 
+func (c *debugTalkClient) CallStream(ctx context.Context, in *CallRequest, opts ...grpc.CallOption) (DebugTalk_CallStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DebugTalk_ServiceDesc.Streams[0], "/proto.DebugTalk/CallStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &debugTalkCallStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DebugTalk_CallStreamClient interface {
+	Recv() (*CallResponse, error)
+	grpc.ClientStream
+}
+
+type debugTalkCallStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *debugTalkCallStreamClient) Recv() (*CallResponse, error) {
+	m := new(CallResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *debugTalkClient) Describe(ctx context.Context, in *DescribeRequest, opts ...grpc.CallOption) (*DescribeResponse, error) {
+	out := new(DescribeResponse)
+	err := c.cc.Invoke(ctx, "/proto.DebugTalk/Describe", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *debugTalkClient) ConnectHost(ctx context.Context, in *ConnectHostRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/proto.DebugTalk/ConnectHost", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *debugTalkClient) Negotiate(ctx context.Context, in *NegotiateRequest, opts ...grpc.CallOption) (*NegotiateResponse, error) {
+	out := new(NegotiateResponse)
+	err := c.cc.Invoke(ctx, "/proto.DebugTalk/Negotiate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *debugTalkClient) CallChunked(ctx context.Context, opts ...grpc.CallOption) (DebugTalk_CallChunkedClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DebugTalk_ServiceDesc.Streams[1], "/proto.DebugTalk/CallChunked", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &debugTalkCallChunkedClient{stream}
+	return x, nil
+}
+
+type DebugTalk_CallChunkedClient interface {
+	Send(*Chunk) error
+	CloseAndRecv() (*CallResponse, error)
+	grpc.ClientStream
+}
+
+type debugTalkCallChunkedClient struct {
+	grpc.ClientStream
+}
+
+func (x *debugTalkCallChunkedClient) Send(m *Chunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *debugTalkCallChunkedClient) CloseAndRecv() (*CallResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(CallResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *debugTalkClient) FetchChunks(ctx context.Context, in *FetchChunksRequest, opts ...grpc.CallOption) (DebugTalk_FetchChunksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DebugTalk_ServiceDesc.Streams[2], "/proto.DebugTalk/FetchChunks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &debugTalkFetchChunksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DebugTalk_FetchChunksClient interface {
+	Recv() (*Chunk, error)
+	grpc.ClientStream
+}
+
+type debugTalkFetchChunksClient struct {
+	grpc.ClientStream
+}
+
+func (x *debugTalkFetchChunksClient) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // DebugTalkServer is the server API for DebugTalk service.
 // All implementations must embed UnimplementedDebugTalkServer
 // for forward compatibility
 type DebugTalkServer interface {
 	GetNames(context.Context, *Empty) (*GetNamesResponse, error)
 	Call(context.Context, *CallRequest) (*CallResponse, error)
+	CallStream(*CallRequest, DebugTalk_CallStreamServer) error
+	Describe(context.Context, *DescribeRequest) (*DescribeResponse, error)
+	ConnectHost(context.Context, *ConnectHostRequest) (*Empty, error)
+	Negotiate(context.Context, *NegotiateRequest) (*NegotiateResponse, error)
+	CallChunked(DebugTalk_CallChunkedServer) error
+	FetchChunks(*FetchChunksRequest, DebugTalk_FetchChunksServer) error
 	mustEmbedUnimplementedDebugTalkServer()
 }
 
@@ -71,6 +208,24 @@ func (UnimplementedDebugTalkServer) GetNames(context.Context, *Empty) (*GetNames
 func (UnimplementedDebugTalkServer) Call(context.Context, *CallRequest) (*CallResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Call not implemented")
 }
+func (UnimplementedDebugTalkServer) CallStream(*CallRequest, DebugTalk_CallStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method CallStream not implemented")
+}
+func (UnimplementedDebugTalkServer) Describe(context.Context, *DescribeRequest) (*DescribeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Describe not implemented")
+}
+func (UnimplementedDebugTalkServer) ConnectHost(context.Context, *ConnectHostRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConnectHost not implemented")
+}
+func (UnimplementedDebugTalkServer) Negotiate(context.Context, *NegotiateRequest) (*NegotiateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Negotiate not implemented")
+}
+func (UnimplementedDebugTalkServer) CallChunked(DebugTalk_CallChunkedServer) error {
+	return status.Errorf(codes.Unimplemented, "method CallChunked not implemented")
+}
+func (UnimplementedDebugTalkServer) FetchChunks(*FetchChunksRequest, DebugTalk_FetchChunksServer) error {
+	return status.Errorf(codes.Unimplemented, "method FetchChunks not implemented")
+}
 func (UnimplementedDebugTalkServer) mustEmbedUnimplementedDebugTalkServer() {}
 
 // UnsafeDebugTalkServer may be embedded to opt out of forward compatibility for this service.
@@ -120,6 +275,128 @@ func _DebugTalk_Call_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DebugTalk_CallStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CallRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DebugTalkServer).CallStream(m, &debugTalkCallStreamServer{stream})
+}
+
+type DebugTalk_CallStreamServer interface {
+	Send(*CallResponse) error
+	grpc.ServerStream
+}
+
+type debugTalkCallStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *debugTalkCallStreamServer) Send(m *CallResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _DebugTalk_Describe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DebugTalkServer).Describe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.DebugTalk/Describe",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DebugTalkServer).Describe(ctx, req.(*DescribeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DebugTalk_ConnectHost_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConnectHostRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DebugTalkServer).ConnectHost(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.DebugTalk/ConnectHost",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DebugTalkServer).ConnectHost(ctx, req.(*ConnectHostRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DebugTalk_Negotiate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NegotiateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DebugTalkServer).Negotiate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.DebugTalk/Negotiate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DebugTalkServer).Negotiate(ctx, req.(*NegotiateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DebugTalk_CallChunked_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DebugTalkServer).CallChunked(&debugTalkCallChunkedServer{stream})
+}
+
+type DebugTalk_CallChunkedServer interface {
+	SendAndClose(*CallResponse) error
+	Recv() (*Chunk, error)
+	grpc.ServerStream
+}
+
+type debugTalkCallChunkedServer struct {
+	grpc.ServerStream
+}
+
+func (x *debugTalkCallChunkedServer) SendAndClose(m *CallResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *debugTalkCallChunkedServer) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _DebugTalk_FetchChunks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FetchChunksRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DebugTalkServer).FetchChunks(m, &debugTalkFetchChunksServer{stream})
+}
+
+type DebugTalk_FetchChunksServer interface {
+	Send(*Chunk) error
+	grpc.ServerStream
+}
+
+type debugTalkFetchChunksServer struct {
+	grpc.ServerStream
+}
+
+func (x *debugTalkFetchChunksServer) Send(m *Chunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // DebugTalk_ServiceDesc is the grpc.ServiceDesc for DebugTalk service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -135,7 +412,35 @@ var DebugTalk_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Call",
 			Handler:    _DebugTalk_Call_Handler,
 		},
+		{
+			MethodName: "Describe",
+			Handler:    _DebugTalk_Describe_Handler,
+		},
+		{
+			MethodName: "ConnectHost",
+			Handler:    _DebugTalk_ConnectHost_Handler,
+		},
+		{
+			MethodName: "Negotiate",
+			Handler:    _DebugTalk_Negotiate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "CallStream",
+			Handler:       _DebugTalk_CallStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "CallChunked",
+			Handler:       _DebugTalk_CallChunked_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "FetchChunks",
+			Handler:       _DebugTalk_FetchChunks_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/debugtalk.proto",
 }
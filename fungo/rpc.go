@@ -1,25 +1,47 @@
 package fungo
 
 import (
+	"context"
 	"encoding/gob"
+	"fmt"
+	"io"
 	"net/rpc"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/go-plugin"
+	"github.com/pkg/errors"
 )
 
 func init() {
 	gob.Register(new(funcData))
+	// nested arguments and values, e.g. JSON objects bound to struct parameters
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
 }
 
 // funcData is used to transfer between plugin and host via RPC.
 type funcData struct {
-	Name string        // function name
-	Args []interface{} // function arguments
+	ID       uint64                 // call id, used to cancel the call
+	Name     string                 // function name
+	Args     []interface{}          // function arguments
+	Kwargs   map[string]interface{} // function keyword arguments
+	Deadline time.Time              // call deadline, zero means no deadline
+}
+
+// CallResult is the reply of Plugin.Call via RPC if StructuredReply is negotiated, exported as required by net/rpc
+type CallResult struct {
+	Value interface{}  // function return value
+	Error *PluginError // function call error
+	EOF   bool         // stream ends, only used by Plugin.StreamRecv
 }
 
 // functionRPCClient runs on the host side, it implements FuncCaller interface
 type functionRPCClient struct {
-	client *rpc.Client
+	client          *rpc.Client
+	callID          uint64 // atomic counter for call id
+	structuredReply bool   // plugin replies Plugin.Call in CallResult, otherwise in value and rpc.ServerError
 }
 
 func (g *functionRPCClient) GetNames() ([]string, error) {
@@ -36,30 +58,173 @@ func (g *functionRPCClient) GetNames() ([]string, error) {
 
 // host -> plugin
 func (g *functionRPCClient) Call(funcName string, funcArgs ...interface{}) (interface{}, error) {
-	logger.Info("rpc_client Call() start", "funcName", funcName, "funcArgs", funcArgs)
+	return g.CallContext(context.Background(), funcName, funcArgs...)
+}
+
+// CallContext calls plugin function, ctx deadline is sent to plugin along with the call,
+// and plugin will be notified to cancel the call once ctx is done.
+func (g *functionRPCClient) CallContext(ctx context.Context, funcName string, funcArgs ...interface{}) (interface{}, error) {
+	return g.CallKw(ctx, funcName, funcArgs, nil)
+}
+
+// CallKw calls plugin function with keyword arguments
+func (g *functionRPCClient) CallKw(ctx context.Context, funcName string, funcArgs []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	logger.Info("rpc_client Call() start", "funcName", funcName, "funcArgs", logValue(funcArgs), "kwargs", logValue(kwargs))
+	f := funcData{
+		ID:     atomic.AddUint64(&g.callID, 1),
+		Name:   funcName,
+		Args:   funcArgs,
+		Kwargs: kwargs,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		f.Deadline = deadline
+	}
+	if len(kwargs) > 0 && !g.structuredReply {
+		// plugins negotiating protocol version 1 ignore keyword arguments
+		return nil, &PluginError{
+			Kind:     ErrKindArgMismatch,
+			FuncName: funcName,
+			Message:  "keyword arguments not supported by plugin built with older fungo",
+		}
+	}
+
+	var resp CallResult
+	var err error
+	if g.structuredReply {
+		err = g.call(ctx, f.ID, funcName, "Plugin.Call", f, &resp)
+	} else {
+		err = g.call(ctx, f.ID, funcName, "Plugin.Call", f, &resp.Value)
+	}
+	if err != nil {
+		logger.Error("rpc_client Call() failed",
+			"funcName", funcName,
+			"funcArgs", logValue(funcArgs),
+			"error", err,
+		)
+		return nil, err
+	}
+	logger.Info("rpc_client Call() success", "result", logValue(resp.Value))
+	return resp.Value, nil
+}
+
+// CallStream calls plugin function and pulls values from plugin one at a time,
+// ctx deadline and cancellation are applied to the whole stream.
+func (g *functionRPCClient) CallStream(ctx context.Context, funcName string, funcArgs ...interface{}) (Stream, error) {
+	logger.Info("rpc_client CallStream() start", "funcName", funcName, "funcArgs", logValue(funcArgs))
 	f := funcData{
+		ID:   atomic.AddUint64(&g.callID, 1),
 		Name: funcName,
 		Args: funcArgs,
 	}
+	if deadline, ok := ctx.Deadline(); ok {
+		f.Deadline = deadline
+	}
 
-	var args interface{} = f
-	var resp interface{}
-	err := g.client.Call("Plugin.Call", &args, &resp)
+	var resp CallResult
+	err := g.call(ctx, f.ID, funcName, "Plugin.StreamStart", f, &resp)
 	if err != nil {
-		logger.Error("rpc_client Call() failed",
+		logger.Error("rpc_client CallStream() failed",
 			"funcName", funcName,
-			"funcArgs", funcArgs,
+			"funcArgs", logValue(funcArgs),
 			"error", err,
 		)
 		return nil, err
 	}
-	logger.Info("rpc_client Call() success", "result", resp)
-	return resp, nil
+	return &rpcStream{ctx: ctx, client: g, id: f.ID, funcName: funcName}, nil
+}
+
+// call invokes the plugin method and waits for its reply, resp is *CallResult or reply value of
+// plugin without StructuredReply. Plugin will be notified to cancel the running call once ctx is done.
+func (g *functionRPCClient) call(ctx context.Context, id uint64, funcName, serviceMethod string, args, resp interface{}) error {
+	call := g.client.Go(serviceMethod, &args, resp, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		if serverErr, ok := call.Error.(rpc.ServerError); ok && !g.structuredReply {
+			// error returned by function of plugin built with older fungo
+			return &PluginError{Kind: ErrKindUser, FuncName: funcName, Message: string(serverErr), cause: call.Error}
+		}
+		if call.Error != nil {
+			return newTransportError(funcName, call.Error)
+		}
+		if result, ok := resp.(*CallResult); ok && result.Error != nil {
+			return result.Error
+		}
+		return nil
+	case <-ctx.Done():
+		// notify plugin to cancel the running call, no need to wait for the reply
+		var cancelID interface{} = id
+		g.client.Go("Plugin.Cancel", &cancelID, new(interface{}), make(chan *rpc.Call, 1))
+		return newTransportError(funcName, ctx.Err())
+	}
+}
+
+// rpcStream pulls values of plugin function via Plugin.StreamRecv
+type rpcStream struct {
+	ctx      context.Context
+	client   *functionRPCClient
+	id       uint64 // call id of the stream
+	funcName string
+}
+
+func (s *rpcStream) Recv() (interface{}, error) {
+	var resp CallResult
+	err := s.client.call(s.ctx, s.id, s.funcName, "Plugin.StreamRecv", s.id, &resp)
+	if err != nil {
+		logger.Error("rpc_client CallStream() recv failed", "funcName", s.funcName, "error", err)
+		return nil, err
+	}
+	if resp.EOF {
+		return nil, io.EOF
+	}
+	return resp.Value, nil
+}
+
+func (s *rpcStream) Close() error {
+	var id interface{} = s.id
+	return s.client.client.Call("Plugin.StreamClose", &id, new(interface{}))
+}
+
+// connectHost serves host functions via broker and lets plugin connect to them
+func (g *functionRPCClient) connectHost(broker *plugin.MuxBroker, host IFuncCaller) error {
+	brokerID := broker.NextId()
+	go broker.AcceptAndServe(brokerID, &functionRPCServer{Impl: host})
+
+	logger.Debug("rpc_client ConnectHost() start", "brokerID", brokerID)
+	var args interface{} = brokerID
+	err := g.client.Call("Plugin.ConnectHost", &args, new(interface{}))
+	if err != nil {
+		logger.Error("rpc_client ConnectHost() failed", "error", err)
+		return errors.Wrap(err, "connect host functions failed")
+	}
+	logger.Debug("rpc_client ConnectHost() success")
+	return nil
+}
+
+func (g *functionRPCClient) Describe(funcName string) (*FuncSignature, error) {
+	logger.Debug("rpc_client Describe() start", "funcName", funcName)
+	var args interface{} = funcName
+	var resp FuncSignature
+	err := g.client.Call("Plugin.Describe", &args, &resp)
+	if err != nil {
+		logger.Error("rpc_client Describe() failed", "funcName", funcName, "error", err)
+		return nil, err
+	}
+	logger.Debug("rpc_client Describe() success")
+	return &resp, nil
 }
 
 // functionRPCServer runs on the plugin side, executing the user custom function.
 type functionRPCServer struct {
-	Impl IFuncCaller
+	Impl    IFuncCaller
+	broker  *plugin.MuxBroker // used to connect host functions, nil when serving host functions
+	cancels sync.Map          // running calls, key is call id, value is context.CancelFunc
+	streams sync.Map          // opened streams, key is call id, value is *serverStream
+}
+
+// serverStream is a stream opened by Plugin.StreamStart
+type serverStream struct {
+	Stream
+	funcName string
 }
 
 // plugin execution
@@ -75,29 +240,185 @@ func (s *functionRPCServer) GetNames(args interface{}, resp *[]string) error {
 	return nil
 }
 
-// plugin execution
-func (s *functionRPCServer) Call(args interface{}, resp *interface{}) error {
+// plugin execution,
+// function call error is returned in reply instead of rpc.ServerError to keep it structured
+func (s *functionRPCServer) Call(args interface{}, resp *CallResult) error {
 	logger.Debug("rpc_server Call() start")
 	f := args.(*funcData)
-	var err error
-	*resp, err = s.Impl.Call(f.Name, f.Args...)
+
+	ctx, cancel := s.callContext(f)
+	defer func() {
+		s.cancels.Delete(f.ID)
+		cancel()
+	}()
+
+	value, err := s.Impl.CallKw(ctx, f.Name, f.Args, f.Kwargs)
 	if err != nil {
 		logger.Error("rpc_server Call() failed", "args", args, "error", err)
-		return err
+		resp.Error = AsPluginError(f.Name, err)
+		return nil
 	}
+	resp.Value = value
 	logger.Debug("rpc_server Call() success")
 	return nil
 }
 
+// legacyRPCServer serves hosts negotiating protocol version 1, i.e. built with fungo before v0.6.0,
+// which reply Plugin.Call in function value and return function error as rpc.ServerError.
+type legacyRPCServer struct {
+	*functionRPCServer
+}
+
+// plugin execution
+func (s *legacyRPCServer) Call(args interface{}, resp *interface{}) error {
+	var result CallResult
+	if err := s.functionRPCServer.Call(args, &result); err != nil {
+		return err
+	}
+	if result.Error != nil {
+		return errors.New(result.Error.Message)
+	}
+	*resp = result.Value
+	return nil
+}
+
+// callContext creates context with deadline of the call,
+// the cancel func is stored to cancel the call by Plugin.Cancel.
+func (s *functionRPCServer) callContext(f *funcData) (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if f.Deadline.IsZero() {
+		ctx, cancel = context.WithCancel(context.Background())
+	} else {
+		ctx, cancel = context.WithDeadline(context.Background(), f.Deadline)
+	}
+	s.cancels.Store(f.ID, cancel)
+	return ctx, cancel
+}
+
+// plugin execution, the opened stream is kept until it ends or Plugin.StreamClose is called
+func (s *functionRPCServer) StreamStart(args interface{}, resp *CallResult) error {
+	logger.Debug("rpc_server StreamStart() start")
+	f := args.(*funcData)
+
+	ctx, _ := s.callContext(f) // cancelled by closeStream
+	stream, err := s.Impl.CallStream(ctx, f.Name, f.Args...)
+	if err != nil {
+		logger.Error("rpc_server StreamStart() failed", "args", args, "error", err)
+		s.closeStream(f.ID)
+		resp.Error = AsPluginError(f.Name, err)
+		return nil
+	}
+	s.streams.Store(f.ID, &serverStream{Stream: stream, funcName: f.Name})
+	logger.Debug("rpc_server StreamStart() success")
+	return nil
+}
+
+// plugin execution
+func (s *functionRPCServer) StreamRecv(args interface{}, resp *CallResult) error {
+	id, _ := args.(uint64)
+	v, ok := s.streams.Load(id)
+	if !ok {
+		return fmt.Errorf("stream %d not found", id)
+	}
+	stream := v.(*serverStream)
+
+	value, err := stream.Recv()
+	if err == io.EOF {
+		s.closeStream(id)
+		resp.EOF = true
+		return nil
+	} else if err != nil {
+		logger.Error("rpc_server StreamRecv() failed", "funcName", stream.funcName, "error", err)
+		s.closeStream(id)
+		resp.Error = AsPluginError(stream.funcName, err)
+		return nil
+	}
+	resp.Value = value
+	return nil
+}
+
+// plugin execution
+func (s *functionRPCServer) StreamClose(args interface{}, resp *interface{}) error {
+	id, _ := args.(uint64)
+	logger.Debug("rpc_server StreamClose() start", "id", id)
+	s.closeStream(id)
+	return nil
+}
+
+// closeStream closes the stream and cancels its context
+func (s *functionRPCServer) closeStream(id uint64) {
+	if v, ok := s.streams.LoadAndDelete(id); ok {
+		v.(*serverStream).Close()
+	}
+	if cancel, ok := s.cancels.LoadAndDelete(id); ok {
+		cancel.(context.CancelFunc)()
+	}
+}
+
+// plugin execution
+func (s *functionRPCServer) Cancel(args interface{}, resp *interface{}) error {
+	id, _ := args.(uint64)
+	logger.Debug("rpc_server Cancel() start", "id", id)
+	if cancel, ok := s.cancels.Load(id); ok {
+		cancel.(context.CancelFunc)()
+	}
+	return nil
+}
+
+// plugin execution
+func (s *functionRPCServer) ConnectHost(args interface{}, resp *interface{}) error {
+	brokerID, _ := args.(uint32)
+	logger.Debug("rpc_server ConnectHost() start", "brokerID", brokerID)
+	if s.broker == nil {
+		return errors.New("broker not available")
+	}
+	conn, err := s.broker.Dial(brokerID)
+	if err != nil {
+		logger.Error("rpc_server ConnectHost() failed", "error", err)
+		return err
+	}
+	// host functions are served by host since v0.6.0, which always replies in CallResult
+	setHost(&functionRPCClient{client: rpc.NewClient(conn), structuredReply: true})
+	logger.Debug("rpc_server ConnectHost() success")
+	return nil
+}
+
+// plugin execution
+func (s *functionRPCServer) Describe(args interface{}, resp *FuncSignature) error {
+	logger.Debug("rpc_server Describe() start")
+	funcName, _ := args.(string)
+	sig, err := s.Impl.Describe(funcName)
+	if err != nil {
+		logger.Error("rpc_server Describe() failed", "funcName", funcName, "error", err)
+		return err
+	}
+	*resp = *sig
+	logger.Debug("rpc_server Describe() success")
+	return nil
+}
+
 // RPCPlugin implements hashicorp's plugin.Plugin.
 type RPCPlugin struct {
-	Impl IFuncCaller
+	Impl            IFuncCaller            // plugin functions, used on plugin side
+	HostFunctions   map[string]interface{} // host functions called back by plugin, used on host side
+	StructuredReply bool                   // reply Plugin.Call in CallResult, negotiated by protocol version 2
 }
 
-func (p *RPCPlugin) Server(*plugin.MuxBroker) (interface{}, error) {
-	return &functionRPCServer{Impl: p.Impl}, nil
+func (p *RPCPlugin) Server(b *plugin.MuxBroker) (interface{}, error) {
+	server := &functionRPCServer{Impl: p.Impl, broker: b}
+	if !p.StructuredReply {
+		return &legacyRPCServer{functionRPCServer: server}, nil
+	}
+	return server, nil
 }
 
 func (p *RPCPlugin) Client(b *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
-	return &functionRPCClient{client: c}, nil
+	client := &functionRPCClient{client: c, structuredReply: p.StructuredReply}
+	if len(p.HostFunctions) > 0 {
+		if err := client.connectHost(b, newHostFuncCaller(p.HostFunctions)); err != nil {
+			return nil, err
+		}
+	}
+	return client, nil
 }
@@ -0,0 +1,63 @@
+package fungo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRPCLegacyReply(t *testing.T) {
+	impl := newHostFuncCaller(map[string]interface{}{
+		"sum": func(a, b int) int { return a + b },
+		"fail": func() (int, error) {
+			return 0, fmt.Errorf("failed")
+		},
+	})
+	client, server := plugin.TestRPCConn(t)
+	defer client.Close()
+	server.RegisterName("Plugin", &legacyRPCServer{functionRPCServer: &functionRPCServer{Impl: impl}})
+
+	// host built with older fungo gets function value in reply
+	var args interface{} = funcData{Name: "sum", Args: []interface{}{1, 2}}
+	var resp interface{}
+	if err := client.Call("Plugin.Call", &args, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, resp) {
+		t.Fail()
+	}
+
+	// host negotiating protocol version 1 gets function error as user error
+	caller := &functionRPCClient{client: client}
+	v, err := caller.Call("sum", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+	_, err = caller.Call("fail")
+	var pluginErr *PluginError
+	if !assert.True(t, errors.As(err, &pluginErr), err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, ErrKindUser, pluginErr.Kind) {
+		t.Fail()
+	}
+	if !assert.Equal(t, "failed", pluginErr.Message) {
+		t.Fail()
+	}
+
+	// keyword arguments are ignored by plugin built with older fungo
+	_, err = caller.CallKw(context.Background(), "sum", nil, map[string]interface{}{"a": 1})
+	if !assert.True(t, errors.As(err, &pluginErr), err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, ErrKindArgMismatch, pluginErr.Kind) {
+		t.Fail()
+	}
+}
@@ -0,0 +1,91 @@
+package fungo
+
+import (
+	"context"
+	"io"
+	"reflect"
+)
+
+// Stream receives values pushed by plugin function one at a time
+type Stream interface {
+	Recv() (interface{}, error) // receive next value, io.EOF is returned when stream ends
+	Close() error               // stop receiving and cancel plugin function
+}
+
+// CallFuncStream calls function with arguments and returns its result as stream.
+// If function returns a receivable channel, values are received from the channel
+// until it is closed, otherwise the stream yields the single result.
+func CallFuncStream(ctx context.Context, fn reflect.Value, args ...interface{}) (Stream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	result, err := CallFuncContext(ctx, fn, args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	ch := reflect.ValueOf(result)
+	if ch.Kind() != reflect.Chan || ch.Type().ChanDir()&reflect.RecvDir == 0 {
+		cancel()
+		return &valueStream{value: result}, nil
+	}
+	return &chanStream{ctx: ctx, cancel: cancel, ch: ch}, nil
+}
+
+// chanStream receives values from channel returned by plugin function,
+// ctx passed to the function is cancelled when stream is closed.
+type chanStream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	ch     reflect.Value
+}
+
+func (s *chanStream) Recv() (interface{}, error) {
+	if s.ch.IsNil() {
+		s.cancel()
+		return nil, io.EOF
+	}
+
+	chosen, value, ok := reflect.Select([]reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s.ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: s.ch},
+	})
+	if chosen == 0 {
+		return nil, s.ctx.Err()
+	}
+	if !ok {
+		// channel closed
+		s.cancel()
+		return nil, io.EOF
+	}
+	return value.Interface(), nil
+}
+
+func (s *chanStream) Close() error {
+	s.cancel()
+	return nil
+}
+
+// NewValueStream returns stream which yields the single value,
+// e.g. result of plugin function which does not support streaming.
+func NewValueStream(value interface{}) Stream {
+	return &valueStream{value: value}
+}
+
+// valueStream yields the single result of non-streaming plugin function
+type valueStream struct {
+	value    interface{}
+	received bool
+}
+
+func (s *valueStream) Recv() (interface{}, error) {
+	if s.received {
+		return nil, io.EOF
+	}
+	s.received = true
+	return s.value, nil
+}
+
+func (s *valueStream) Close() error {
+	s.received = true
+	return nil
+}
@@ -1,107 +1,220 @@
 package fungo
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"runtime/debug"
+	"strconv"
 	"strings"
 )
 
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
 // CallFunc calls function with arguments
 func CallFunc(fn reflect.Value, args ...interface{}) (interface{}, error) {
+	return CallFuncContext(context.Background(), fn, args...)
+}
+
+// CallFuncContext calls function with arguments,
+// ctx will be passed in if function's first argument is context.Context
+func CallFuncContext(ctx context.Context, fn reflect.Value, args ...interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if acceptsContext(fn.Type()) {
+		args = append([]interface{}{ctx}, args...)
+	}
+
 	argumentsValue, err := convertArgs(fn, args...)
 	if err != nil {
 		logger.Error("convert arguments failed", "error", err)
-		return nil, err
+		return nil, &PluginError{Kind: ErrKindArgMismatch, Message: err.Error(), cause: err}
 	}
 	return call(fn, argumentsValue)
 }
 
+// DescribeFunc describes function signature via reflection,
+// parameters are named as arg0, arg1... if paramNames are not specified.
+func DescribeFunc(funcName string, fn reflect.Value, paramNames []string, doc string) *FuncSignature {
+	fnType := fn.Type()
+	sig := &FuncSignature{
+		Name:     funcName,
+		Params:   []FuncParam{},
+		Variadic: fnType.IsVariadic(),
+		Returns:  []string{},
+		Doc:      doc,
+	}
+
+	start := 0
+	if acceptsContext(fnType) {
+		start = 1 // context is not counted as parameter
+	}
+	for i := start; i < fnType.NumIn(); i++ {
+		index := i - start
+		param := FuncParam{
+			Name: fmt.Sprintf("arg%d", index),
+			Type: fnType.In(i).String(),
+		}
+		if index < len(paramNames) {
+			param.Name = paramNames[index]
+		}
+		if sig.Variadic && i == fnType.NumIn()-1 {
+			param.Type = fnType.In(i).Elem().String()
+			param.Optional = true
+		}
+		sig.Params = append(sig.Params, param)
+	}
+
+	for i := 0; i < fnType.NumOut(); i++ {
+		sig.Returns = append(sig.Returns, fnType.Out(i).String())
+	}
+	return sig
+}
+
+// acceptsContext checks if function's first argument is context.Context
+func acceptsContext(fnType reflect.Type) bool {
+	return fnType.NumIn() > 0 && fnType.In(0) == contextType
+}
+
 func convertArgs(fn reflect.Value, args ...interface{}) ([]reflect.Value, error) {
-	fnArgsNum := fn.Type().NumIn()
+	fnType := fn.Type()
+	fnArgsNum := fnType.NumIn()
 
-	// function arguments should match exactly if function's last argument is not slice
-	if len(args) != fnArgsNum && (fnArgsNum == 0 || fn.Type().In(fnArgsNum-1).Kind() != reflect.Slice) {
+	// function arguments should match exactly unless function is variadic
+	if fnType.IsVariadic() {
+		if len(args) < fnArgsNum-1 {
+			return nil, fmt.Errorf("function expect at least %d arguments, but got %d", fnArgsNum-1, len(args))
+		}
+	} else if len(args) != fnArgsNum {
 		return nil, fmt.Errorf("function expect %d arguments, but got %d", fnArgsNum, len(args))
 	}
 
 	argumentsValue := make([]reflect.Value, len(args))
-	for index := 0; index < len(args); index++ {
-		argument := args[index]
-		if argument == nil {
-			argumentsValue[index] = reflect.Zero(fn.Type().In(index))
-			continue
-		}
-
-		argumentValue := reflect.ValueOf(argument)
-		actualArgumentType := reflect.TypeOf(argument)
-
+	for index, argument := range args {
 		var expectArgumentType reflect.Type
-		if (index == fnArgsNum-1 && fn.Type().In(fnArgsNum-1).Kind() == reflect.Slice) || index > fnArgsNum-1 {
-			// last fn argument is slice
-			expectArgumentType = fn.Type().In(fnArgsNum - 1).Elem() // slice element type
-
-			// last argument is also slice, e.g. []int
-			if actualArgumentType.Kind() == reflect.Slice {
-				if actualArgumentType.Elem() != expectArgumentType {
-					err := fmt.Errorf("function argument %d's slice element type is not match, expect %v, actual %v",
-						index, expectArgumentType, actualArgumentType)
-					return nil, err
-				}
-				argumentsValue[index] = argumentValue
-				continue
-			}
+		if fnType.IsVariadic() && index >= fnArgsNum-1 {
+			expectArgumentType = fnType.In(fnArgsNum - 1).Elem() // variadic element type
 		} else {
-			expectArgumentType = fn.Type().In(index)
-		}
-
-		// type match
-		if expectArgumentType == actualArgumentType {
-			argumentsValue[index] = argumentValue
-			continue
+			expectArgumentType = fnType.In(index)
 		}
 
-		// type not match, check if convertible
-		if !actualArgumentType.ConvertibleTo(expectArgumentType) {
-			// function argument type not match and not convertible
-			err := fmt.Errorf("function argument %d's type is neither match nor convertible, expect %v, actual %v",
-				index, expectArgumentType, actualArgumentType)
+		argumentValue, err := convertValue(reflect.ValueOf(argument), expectArgumentType, "argument "+strconv.Itoa(index))
+		if err != nil {
 			return nil, err
 		}
-		// convert argument to expect type
-		argumentsValue[index] = argumentValue.Convert(expectArgumentType)
+		argumentsValue[index] = argumentValue
 	}
 	return argumentsValue, nil
 }
 
-func call(fn reflect.Value, args []reflect.Value) (interface{}, error) {
-	resultValues := fn.Call(args)
-	if resultValues == nil {
-		// no returns
-		return nil, nil
-	} else if len(resultValues) == 2 {
-		// return two arguments: interface{}, error
-		if resultValues[1].Interface() != nil {
-			return resultValues[0].Interface(), resultValues[1].Interface().(error)
-		} else {
-			return resultValues[0].Interface(), nil
+func call(fn reflect.Value, args []reflect.Value) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("function panicked", "panic", r)
+			result = nil
+			err = &PluginError{Kind: ErrKindPanic, Message: fmt.Sprint(r), Stack: string(debug.Stack())}
 		}
-	} else if len(resultValues) == 1 {
+	}()
+
+	resultValues := fn.Call(args)
+	if len(resultValues) == 1 {
 		// return one argument
 		if err, ok := resultValues[0].Interface().(error); ok {
 			// return error
 			return nil, err
-		} else {
-			// return interface{}
-			return resultValues[0].Interface(), nil
 		}
-	} else {
-		// return more than 2 arguments, unexpected
-		err := fmt.Errorf("function should return at most 2 values")
+		// return interface{}
+		return resultValues[0].Interface(), nil
+	}
+
+	// last return value is error if declared as error
+	fnType := fn.Type()
+	if n := len(resultValues); n > 0 && fnType.Out(n-1) == errorType {
+		if !resultValues[n-1].IsNil() {
+			err = resultValues[n-1].Interface().(error)
+		}
+		resultValues = resultValues[:n-1]
+	}
+	switch len(resultValues) {
+	case 0:
+		// no returns
 		return nil, err
+	case 1:
+		return resultValues[0].Interface(), err
+	default:
+		// multiple return values are returned as slice, see Unpack
+		values := make([]interface{}, len(resultValues))
+		for i, value := range resultValues {
+			values[i] = value.Interface()
+		}
+		return values, err
 	}
 }
 
+// Unpack converts multiple return values of function, which are returned as slice,
+// to typed values pointed by dests, e.g. for function returning (int, string, error):
+//
+//	var n int
+//	var s string
+//	err := fungo.Unpack(result, &n, &s)
+func Unpack(result interface{}, dests ...interface{}) error {
+	values, ok := result.([]interface{})
+	if !ok {
+		return fmt.Errorf("function return values should be slice, but got %T", result)
+	}
+	if len(values) != len(dests) {
+		return fmt.Errorf("function return %d values, but got %d dests", len(values), len(dests))
+	}
+	for i, dest := range dests {
+		destValue := reflect.ValueOf(dest)
+		if destValue.Kind() != reflect.Ptr || destValue.IsNil() {
+			return fmt.Errorf("dest %d should be non-nil pointer, but got %T", i, dest)
+		}
+		value, err := convertValue(reflect.ValueOf(values[i]), destValue.Elem().Type(), "return value "+strconv.Itoa(i))
+		if err != nil {
+			return err
+		}
+		destValue.Elem().Set(value)
+	}
+	return nil
+}
+
 // ConvertCommonName returns name which deleted "_" and converted capital letter to their lower case
 func ConvertCommonName(name string) string {
 	return strings.ToLower(strings.Replace(name, "_", "", -1))
 }
+
+// maxLogValueSize limits size of strings and bytes in logs
+const maxLogValueSize = 1 << 10
+
+// logValue summarizes strings and bytes of value beyond maxLogValueSize,
+// avoid rendering large payloads, e.g. big fixtures, in logs.
+func logValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		if len(val) > maxLogValueSize {
+			return fmt.Sprintf("%s...(%d bytes)", val[:maxLogValueSize], len(val))
+		}
+	case []byte:
+		if len(val) > maxLogValueSize {
+			return fmt.Sprintf("[]byte(%d bytes)", len(val))
+		}
+	case []interface{}:
+		values := make([]interface{}, len(val))
+		for i, item := range val {
+			values[i] = logValue(item)
+		}
+		return values
+	case map[string]interface{}:
+		values := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			values[key] = logValue(item)
+		}
+		return values
+	}
+	return v
+}
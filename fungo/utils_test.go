@@ -1,9 +1,12 @@
 package fungo
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -86,7 +89,7 @@ func TestCallFuncBasic(t *testing.T) {
 				sum += int(a) + int(b)
 				return sum
 			},
-			args:   []interface{}{1, 2, 3, 4.5},
+			args:   []interface{}{1, 2, 3, 4.0},
 			expVal: 10,
 		},
 		{
@@ -183,14 +186,41 @@ func TestCallFuncComplex(t *testing.T) {
 
 }
 
-func TestCallFuncAbnormal(t *testing.T) {
+// valueError implements error with value receiver, which can not be nil
+type valueError struct{}
+
+func (valueError) Error() string { return "value error" }
+
+func TestCallFuncMultiValues(t *testing.T) {
 	params := []data{
 		// return more than 2 values
 		{
 			f:      func() (int, int, error) { return 1, 2, nil },
 			args:   []interface{}{},
-			expVal: nil,
-			expErr: fmt.Errorf("function should return at most 2 values"),
+			expVal: []interface{}{1, 2},
+		},
+		{
+			f:      func() (int, string, float64) { return 1, "a", 1.5 },
+			args:   []interface{}{},
+			expVal: []interface{}{1, "a", 1.5},
+		},
+		{
+			f:      func() (int, string, error) { return 1, "a", errors.New("xxx") },
+			args:   []interface{}{},
+			expVal: []interface{}{1, "a"},
+			expErr: errors.New("xxx"),
+		},
+		// second value is not error
+		{
+			f:      func() (int, bool) { return 1, true },
+			args:   []interface{}{},
+			expVal: []interface{}{1, true},
+		},
+		// last value implements error but is not declared as error
+		{
+			f:      func() (int, valueError) { return 1, valueError{} },
+			args:   []interface{}{},
+			expVal: []interface{}{1, valueError{}},
 		},
 	}
 
@@ -205,6 +235,205 @@ func TestCallFuncAbnormal(t *testing.T) {
 		}
 	}
 
+	// unpack return values to typed values
+	var n int
+	var s string
+	var f float32
+	if !assert.NoError(t, Unpack([]interface{}{int64(1), "a", 1.5}, &n, &s, &f)) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, 1, n) || !assert.Equal(t, "a", s) || !assert.Equal(t, float32(1.5), f) {
+		t.Fatal()
+	}
+
+	errParams := []struct {
+		result  interface{}
+		dests   []interface{}
+		message string
+	}{
+		{1, []interface{}{&n}, "function return values should be slice, but got int"},
+		{[]interface{}{1, "a"}, []interface{}{&n}, "function return 2 values, but got 1 dests"},
+		{[]interface{}{1}, []interface{}{n}, "dest 0 should be non-nil pointer, but got int"},
+		{[]interface{}{1, 2.5}, []interface{}{&n, &n}, "function return value 1 has fraction, cannot convert 2.5 to int"},
+	}
+	for _, p := range errParams {
+		err := Unpack(p.result, p.dests...)
+		if !assert.EqualError(t, err, p.message) {
+			t.Fatal()
+		}
+	}
+}
+
+func TestCallFuncContext(t *testing.T) {
+	type ctxKey struct{}
+	fn := reflect.ValueOf(func(ctx context.Context, a, b int) (int, error) {
+		if ctx.Value(ctxKey{}) != "v" {
+			return 0, errors.New("context not passed")
+		}
+		return a + b, ctx.Err()
+	})
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "v")
+	val, err := CallFuncContext(ctx, fn, 1, 2)
+	if !assert.NoError(t, err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, 3, val) {
+		t.Fatal()
+	}
+
+	// context is not counted as argument
+	_, err = CallFuncContext(ctx, fn, 1)
+	if !assert.Error(t, err) {
+		t.Fatal()
+	}
+
+	// cancelled context
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = CallFuncContext(ctx, fn, 1, 2)
+	if !assert.ErrorIs(t, err, context.Canceled) {
+		t.Fatal()
+	}
+
+	// background context is passed in by CallFunc
+	val, err = CallFunc(reflect.ValueOf(func(ctx context.Context) bool {
+		return ctx != nil
+	}))
+	if !assert.NoError(t, err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, true, val) {
+		t.Fatal()
+	}
+}
+
+func TestCallFuncPluginError(t *testing.T) {
+	// arguments mismatch
+	_, err := CallFunc(reflect.ValueOf(func(a int) int { return a }), "a")
+	var pluginErr *PluginError
+	if !assert.True(t, errors.As(err, &pluginErr)) {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, ErrKindArgMismatch, pluginErr.Kind) {
+		t.Fatal()
+	}
+
+	// function panic
+	_, err = CallFunc(reflect.ValueOf(func(m map[string]int) { m["a"] = 1 }), nil)
+	if !assert.True(t, errors.As(err, &pluginErr)) {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, ErrKindPanic, pluginErr.Kind) {
+		t.Fatal()
+	}
+	if !assert.Contains(t, pluginErr.Stack, "runtime/debug.Stack") {
+		t.Fatal()
+	}
+
+	// user error is returned as is, and converted by AsPluginError
+	userErr := errors.New("xxx")
+	_, err = CallFunc(reflect.ValueOf(func() error { return userErr }))
+	if !assert.Equal(t, userErr, err) {
+		t.Fatal()
+	}
+	pluginErr = AsPluginError("foo", err)
+	if !assert.Equal(t, ErrKindUser, pluginErr.Kind) {
+		t.Fatal()
+	}
+	if !assert.ErrorIs(t, pluginErr, userErr) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, "call function foo failed, user_error: xxx", pluginErr.Error()) {
+		t.Fatal()
+	}
+}
+
+func TestCallFuncStream(t *testing.T) {
+	generate := func(ctx context.Context, n int) <-chan int {
+		ch := make(chan int)
+		go func() {
+			defer close(ch)
+			for i := 0; i < n; i++ {
+				select {
+				case ch <- i:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		return ch
+	}
+
+	// receive values from channel until closed
+	stream, err := CallFuncStream(context.Background(), reflect.ValueOf(generate), 3)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	var values []interface{}
+	for {
+		v, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if !assert.Nil(t, err) {
+			t.Fatal()
+		}
+		values = append(values, v)
+	}
+	if !assert.Equal(t, []interface{}{0, 1, 2}, values) {
+		t.Fatal()
+	}
+
+	// function context is cancelled after stream closed
+	stream, err = CallFuncStream(context.Background(), reflect.ValueOf(generate), 100)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	stream.Close()
+	_, err = stream.Recv()
+	if !assert.ErrorIs(t, err, context.Canceled) {
+		t.Fatal()
+	}
+
+	// non-channel result yields single value
+	stream, err = CallFuncStream(context.Background(), reflect.ValueOf(func() string { return "a" }))
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	v, err := stream.Recv()
+	if !assert.Nil(t, err) || !assert.Equal(t, "a", v) {
+		t.Fatal()
+	}
+	_, err = stream.Recv()
+	if !assert.Equal(t, io.EOF, err) {
+		t.Fatal()
+	}
+}
+
+func TestDescribeFunc(t *testing.T) {
+	fn := func(ctx context.Context, name string, n ...int) (map[string]interface{}, error) {
+		return nil, nil
+	}
+	sig := DescribeFunc("foo", reflect.ValueOf(fn), []string{"name"}, "foo doc")
+	expected := &FuncSignature{
+		Name: "foo",
+		Params: []FuncParam{
+			{Name: "name", Type: "string"},
+			{Name: "arg1", Type: "int", Optional: true},
+		},
+		Variadic: true,
+		Returns:  []string{"map[string]interface {}", "error"},
+		Doc:      "foo doc",
+	}
+	if !assert.Equal(t, expected, sig) {
+		t.Fatal()
+	}
+
+	sig = DescribeFunc("bar", reflect.ValueOf(func() {}), nil, "")
+	if !assert.Equal(t, &FuncSignature{Name: "bar", Params: []FuncParam{}, Returns: []string{}}, sig) {
+		t.Fatal()
+	}
 }
 
 func TestConvertCommonName(t *testing.T) {
@@ -236,3 +465,16 @@ func TestConvertCommonName(t *testing.T) {
 		}
 	}
 }
+
+func TestLogValue(t *testing.T) {
+	large := strings.Repeat("x", maxLogValueSize+1)
+	v := logValue([]interface{}{1, []byte(large), map[string]interface{}{"s": large}})
+	expected := []interface{}{
+		1,
+		"[]byte(1025 bytes)",
+		map[string]interface{}{"s": large[:maxLogValueSize] + "...(1025 bytes)"},
+	}
+	if !assert.Equal(t, expected, v) {
+		t.Fail()
+	}
+}
@@ -0,0 +1,231 @@
+package fungo
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/httprunner/funplugin/fungo/protoGen"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	numberType   = reflect.TypeOf(stdjson.Number(""))
+)
+
+// encodeValue encodes go value to typed value.
+// Integers, bytes, time.Time, *big.Int and *big.Float are kept as is,
+// other types which are not list or map, e.g. struct, are encoded via JSON.
+func encodeValue(v interface{}) (*protoGen.Value, error) {
+	if v == nil {
+		return &protoGen.Value{Kind: &protoGen.Value_NullValue{NullValue: &protoGen.Empty{}}}, nil
+	}
+	return encodeReflectValue(reflect.ValueOf(v))
+}
+
+func encodeReflectValue(rv reflect.Value) (*protoGen.Value, error) {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return encodeValue(nil)
+		}
+		if rv.Kind() == reflect.Ptr && (rv.Elem().Type() == bigIntType || rv.Elem().Type() == bigFloatType) {
+			break
+		}
+		rv = rv.Elem()
+	}
+	if rv.Type() == numberType {
+		return encodeJSONNumber(stdjson.Number(rv.String())), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return &protoGen.Value{Kind: &protoGen.Value_BoolValue{BoolValue: rv.Bool()}}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &protoGen.Value{Kind: &protoGen.Value_IntValue{IntValue: rv.Int()}}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &protoGen.Value{Kind: &protoGen.Value_UintValue{UintValue: rv.Uint()}}, nil
+	case reflect.Float32, reflect.Float64:
+		return &protoGen.Value{Kind: &protoGen.Value_FloatValue{FloatValue: rv.Float()}}, nil
+	case reflect.String:
+		return &protoGen.Value{Kind: &protoGen.Value_StringValue{StringValue: rv.String()}}, nil
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return &protoGen.Value{Kind: &protoGen.Value_BytesValue{BytesValue: b}}, nil
+		}
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return encodeValue(nil)
+		}
+		list := &protoGen.ValueList{Values: make([]*protoGen.Value, rv.Len())}
+		for i := 0; i < rv.Len(); i++ {
+			item, err := encodeReflectValue(rv.Index(i))
+			if err != nil {
+				return nil, errors.Wrapf(err, "encode [%d] failed", i)
+			}
+			list.Values[i] = item
+		}
+		return &protoGen.Value{Kind: &protoGen.Value_ListValue{ListValue: list}}, nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return encodeValue(nil)
+		}
+		if key := rv.Type().Key().Kind(); key == reflect.String || isIntegerKind(key) {
+			m := &protoGen.ValueMap{Fields: make(map[string]*protoGen.Value, rv.Len())}
+			iter := rv.MapRange()
+			for iter.Next() {
+				key := fmt.Sprint(iter.Key().Interface())
+				item, err := encodeReflectValue(iter.Value())
+				if err != nil {
+					return nil, errors.Wrapf(err, "encode [%s] failed", key)
+				}
+				m.Fields[key] = item
+			}
+			return &protoGen.Value{Kind: &protoGen.Value_MapValue{MapValue: m}}, nil
+		}
+	case reflect.Ptr:
+		// *big.Int or *big.Float
+		switch val := rv.Interface().(type) {
+		case *big.Int:
+			return &protoGen.Value{Kind: &protoGen.Value_BigIntValue{BigIntValue: val.String()}}, nil
+		case *big.Float:
+			return &protoGen.Value{Kind: &protoGen.Value_DecimalValue{DecimalValue: val.Text('g', -1)}}, nil
+		}
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			t := rv.Interface().(time.Time)
+			return &protoGen.Value{Kind: &protoGen.Value_TimeValue{TimeValue: timestamppb.New(t)}}, nil
+		}
+		if rv.Type() == bigIntType || rv.Type() == bigFloatType {
+			ptr := reflect.New(rv.Type())
+			ptr.Elem().Set(rv)
+			return encodeReflectValue(ptr)
+		}
+	}
+
+	// fallback to JSON, e.g. struct
+	data, err := stdjson.Marshal(rv.Interface())
+	if err != nil {
+		return nil, errors.Wrapf(err, "type %v not supported", rv.Type())
+	}
+	var v interface{}
+	decoder := stdjson.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, errors.Wrapf(err, "type %v not supported", rv.Type())
+	}
+	return encodeValue(v)
+}
+
+// encodeJSONNumber keeps integer as int value, otherwise float value
+func encodeJSONNumber(n stdjson.Number) *protoGen.Value {
+	if i, err := n.Int64(); err == nil {
+		return &protoGen.Value{Kind: &protoGen.Value_IntValue{IntValue: i}}
+	}
+	if i, ok := new(big.Int).SetString(n.String(), 10); ok {
+		return encodeBigInt(i)
+	}
+	f, _ := n.Float64()
+	return &protoGen.Value{Kind: &protoGen.Value_FloatValue{FloatValue: f}}
+}
+
+func encodeBigInt(i *big.Int) *protoGen.Value {
+	if i.IsUint64() {
+		return &protoGen.Value{Kind: &protoGen.Value_UintValue{UintValue: i.Uint64()}}
+	}
+	return &protoGen.Value{Kind: &protoGen.Value_BigIntValue{BigIntValue: i.String()}}
+}
+
+// decodeValue decodes typed value to go value,
+// int value is decoded as int64, uint value as uint64, big int value as *big.Int,
+// decimal value as *big.Float, list as []interface{} and map as map[string]interface{}.
+func decodeValue(v *protoGen.Value) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch kind := v.Kind.(type) {
+	case nil, *protoGen.Value_NullValue:
+		return nil, nil
+	case *protoGen.Value_BoolValue:
+		return kind.BoolValue, nil
+	case *protoGen.Value_IntValue:
+		return kind.IntValue, nil
+	case *protoGen.Value_UintValue:
+		return kind.UintValue, nil
+	case *protoGen.Value_FloatValue:
+		return kind.FloatValue, nil
+	case *protoGen.Value_StringValue:
+		return kind.StringValue, nil
+	case *protoGen.Value_BytesValue:
+		return kind.BytesValue, nil
+	case *protoGen.Value_TimeValue:
+		if err := kind.TimeValue.CheckValid(); err != nil {
+			return nil, errors.Wrap(err, "invalid time value")
+		}
+		return kind.TimeValue.AsTime(), nil
+	case *protoGen.Value_BigIntValue:
+		i, ok := new(big.Int).SetString(kind.BigIntValue, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid big int value %q", kind.BigIntValue)
+		}
+		return i, nil
+	case *protoGen.Value_DecimalValue:
+		f, ok := new(big.Float).SetPrec(256).SetString(kind.DecimalValue)
+		if !ok {
+			return nil, fmt.Errorf("invalid decimal value %q", kind.DecimalValue)
+		}
+		return f, nil
+	case *protoGen.Value_ListValue:
+		return decodeValues(kind.ListValue)
+	case *protoGen.Value_MapValue:
+		m := make(map[string]interface{}, len(kind.MapValue.GetFields()))
+		for key, item := range kind.MapValue.GetFields() {
+			value, err := decodeValue(item)
+			if err != nil {
+				return nil, errors.Wrapf(err, "decode [%s] failed", key)
+			}
+			m[key] = value
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unknown value kind %T", kind)
+	}
+}
+
+// encodeValues encodes function arguments to typed value list
+func encodeValues(values []interface{}) (*protoGen.ValueList, error) {
+	list := &protoGen.ValueList{Values: make([]*protoGen.Value, len(values))}
+	for i, v := range values {
+		value, err := encodeValue(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encode [%d] failed", i)
+		}
+		list.Values[i] = value
+	}
+	return list, nil
+}
+
+// decodeValues decodes typed value list to function arguments
+func decodeValues(list *protoGen.ValueList) ([]interface{}, error) {
+	values := make([]interface{}, len(list.GetValues()))
+	for i, item := range list.GetValues() {
+		value, err := decodeValue(item)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode [%d] failed", i)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	return kind >= reflect.Int && kind <= reflect.Uintptr
+}
@@ -0,0 +1,71 @@
+package fungo
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedValue(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	type user struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	params := []struct {
+		value  interface{}
+		expVal interface{}
+	}{
+		{nil, nil},
+		{true, true},
+		{int64(9007199254740993), int64(9007199254740993)},
+		{1, int64(1)},
+		{uint64(18446744073709551615), uint64(18446744073709551615)},
+		{1.5, 1.5},
+		{"a", "a"},
+		{[]byte{0, 1, 255}, []byte{0, 1, 255}},
+		{now, now},
+		{bigInt, bigInt},
+		{[]int{1, 2}, []interface{}{int64(1), int64(2)}},
+		{map[int]string{1: "a"}, map[string]interface{}{"1": "a"}},
+		{map[string]interface{}{"t": now, "n": nil}, map[string]interface{}{"t": now, "n": nil}},
+		{user{Name: "a", Age: 18}, map[string]interface{}{"name": "a", "age": int64(18)}},
+		{&user{Name: "a", Age: 18}, map[string]interface{}{"name": "a", "age": int64(18)}},
+	}
+	for _, p := range params {
+		value, err := encodeValue(p.value)
+		if !assert.Nil(t, err) {
+			t.Fatal()
+		}
+		v, err := decodeValue(value)
+		if !assert.Nil(t, err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, p.expVal, v) {
+			t.Fail()
+		}
+	}
+
+	// decimal keeps precision
+	value, err := encodeValue(new(big.Float).SetPrec(256).SetFloat64(0.1))
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	v, err := decodeValue(value)
+	if !assert.Nil(t, err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, "0.1000000000000000055511151231257827021181583404541015625", v.(*big.Float).Text('f', -1)) {
+		t.Fail()
+	}
+
+	// unsupported type
+	_, err = encodeValue(make(chan int))
+	if !assert.Error(t, err) {
+		t.Fail()
+	}
+}
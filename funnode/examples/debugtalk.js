@@ -0,0 +1,51 @@
+const funnode = require("funnode");
+
+function sum(...args) {
+  return args.reduce((result, arg) => result + arg, 0);
+}
+
+function concatenate(...args) {
+  return args.map(String).join("");
+}
+
+async function sleep(seconds) {
+  // this.signal is aborted if host cancels the call
+  const deadline = Date.now() + seconds * 1000;
+  while (Date.now() < deadline) {
+    if (this.signal.aborted) {
+      throw new Error("sleep cancelled");
+    }
+    await new Promise((resolve) => setTimeout(resolve, 10));
+  }
+  return `slept ${seconds}s`;
+}
+
+function* generateInts(n) {
+  for (let i = 0; i < n; i++) {
+    yield i;
+  }
+}
+
+funnode.register("sum", sum);
+funnode.register("sum_ints", sum);
+funnode.register("concatenate", concatenate);
+funnode.register("sum_two_int", (a, b) => a + b, {
+  params: ["a", "b"],
+  doc: "Return the sum of two integers.",
+});
+funnode.register("div_mod", (a, b) => [Math.floor(a / b), a % b], { params: ["a", "b"] });
+funnode.register("sum_two_string", (a, b) => a + b, { params: ["a", "b"] });
+funnode.register("sum_strings", concatenate);
+funnode.register("sleep", sleep, { params: ["seconds"] });
+funnode.register("generate_ints", generateInts, {
+  params: ["n"],
+  doc: "Yield integers from 0 to n-1 one by one.",
+});
+funnode.register("echo", (value) => value, { params: ["value"] });
+funnode.register("greet", (name, greeting = "Hello") => `${greeting}, ${name}!`, {
+  params: ["name", "greeting"],
+});
+funnode.register("get_pid", () => process.pid);
+funnode.register("setup_hook_example", (name) => `setup_hook_example: ${name}`, { params: ["name"] });
+funnode.register("teardown_hook_example", (name) => `teardown_hook_example: ${name}`, { params: ["name"] });
+funnode.serve();
@@ -0,0 +1,8 @@
+// ES modules do not resolve NODE_PATH, require funnode installed by host via createRequire
+import { createRequire } from "node:module";
+
+const funnode = createRequire(import.meta.url)("funnode");
+
+funnode.register("sum_two_int", (a, b) => a + b, { params: ["a", "b"] });
+funnode.register("concatenate", (...args) => args.map(String).join(""));
+funnode.serve();
@@ -0,0 +1,429 @@
+"use strict";
+
+const { once } = require("events");
+const path = require("path");
+
+const grpc = require("@grpc/grpc-js");
+const protobuf = require("protobufjs");
+
+const { toValue, fromValues, fromValueMap, jsonReplacer } = require("./values");
+
+// copy of proto/debugtalk.proto, keep consistent with fungo and funppy
+const PROTO_PATH = path.join(__dirname, "debugtalk.proto");
+
+// google.rpc.Status attached to gRPC trailers, Any is declared inline with the same wire format
+const STATUS_PROTO = `
+syntax = "proto3";
+package google.rpc;
+message Status {
+  int32 code = 1;
+  string message = 2;
+  repeated Any details = 3;
+}
+message Any {
+  string type_url = 1;
+  bytes value = 2;
+}
+`;
+
+// health service is probed by host to check if plugin is alive
+const HEALTH_PROTO = `
+syntax = "proto3";
+package grpc.health.v1;
+message HealthCheckRequest {
+  string service = 1;
+}
+message HealthCheckResponse {
+  enum ServingStatus {
+    UNKNOWN = 0;
+    SERVING = 1;
+    NOT_SERVING = 2;
+    SERVICE_UNKNOWN = 3;
+  }
+  ServingStatus status = 1;
+}
+service Health {
+  rpc Check(HealthCheckRequest) returns (HealthCheckResponse);
+}
+`;
+
+const TO_OBJECT = { longs: String, enums: String, oneofs: true };
+
+// plugin protocol versions negotiated with host, keep consistent with fungo
+const PROTOCOL_VERSION_JSON = 1; // values are encoded in JSON
+const PROTOCOL_VERSION_TYPED = 2; // values are encoded in typed values
+
+// environment variables specified by host, keep consistent with fungo
+const MAGIC_COOKIE_KEY = "HttpRunnerPlus";
+const MAGIC_COOKIE_VALUE = "debugtalk";
+const MAX_SEND_SIZE_ENV_NAME = "HRP_PLUGIN_MAX_SEND_SIZE";
+const MAX_RECV_SIZE_ENV_NAME = "HRP_PLUGIN_MAX_RECV_SIZE";
+const COMPRESSION_ENV_NAME = "HRP_PLUGIN_COMPRESSION";
+
+// CallResponse beyond max message size is kept until fetched in chunks
+const DEFAULT_MAX_MESSAGE_SIZE = 4 << 20;
+const MAX_CHUNK_SIZE = 1 << 20;
+const CHUNK_OVERHEAD = 1 << 10; // reserved for fields of Chunk message
+const CHUNK_TIMEOUT = 60 * 1000; // milliseconds to keep oversized response until it is fetched
+
+// PluginError kinds, keep consistent with fungo.ErrorKind
+const ERR_KIND_FUNC_NOT_FOUND = "function_not_found";
+const ERR_KIND_ARG_MISMATCH = "argument_mismatch";
+const ERR_KIND_USER = "user_error";
+
+const ERR_KIND_CODES = {
+  [ERR_KIND_FUNC_NOT_FOUND]: grpc.status.NOT_FOUND,
+  [ERR_KIND_ARG_MISMATCH]: grpc.status.INVALID_ARGUMENT,
+  [ERR_KIND_USER]: grpc.status.UNKNOWN,
+};
+
+const functions = new Map();
+
+class PluginError extends Error {
+  constructor(kind, funcName, message, stack = "") {
+    super(message);
+    this.kind = kind;
+    this.funcName = funcName;
+    this.remoteStack = stack;
+  }
+}
+
+/**
+ * Register function with name. Functions accept any number of arguments unless parameter names
+ * are specified by options.params, then the number of arguments is checked and keyword arguments
+ * of host CallKw are bound to parameters by names. options.doc is returned by Describe.
+ */
+function register(name, func, options = {}) {
+  if (typeof func !== "function") {
+    throw new TypeError(`function ${name} is not callable`);
+  }
+  functions.set(name, { name, func, params: options.params || null, doc: options.doc || "" });
+}
+
+/** Bind positional and keyword arguments to parameters registered by options.params. */
+function bind(info, args, kwargs) {
+  const kwargNames = Object.keys(kwargs);
+  if (!info.params) {
+    if (kwargNames.length > 0) {
+      throw argMismatch(info, "keyword arguments not accepted, parameter names not registered");
+    }
+    return args;
+  }
+
+  const names = info.params;
+  if (args.length > names.length || (kwargNames.length === 0 && args.length < names.length)) {
+    throw argMismatch(info, `function expect ${names.length} arguments, but got ${args.length}`);
+  }
+  // missing arguments are undefined, so that default parameter values apply
+  const bound = names.map((_, i) => args[i]);
+  for (const key of kwargNames) {
+    const index = names.indexOf(key);
+    if (index < 0) {
+      throw argMismatch(info, `unexpected keyword argument ${key}`);
+    }
+    if (index < args.length) {
+      throw argMismatch(info, `got multiple values for argument ${key}`);
+    }
+    bound[index] = kwargs[key];
+  }
+  return bound;
+}
+
+function argMismatch(info, message) {
+  return new PluginError(ERR_KIND_ARG_MISMATCH, info.name, message);
+}
+
+/** Decode arguments in typed values, or JSON for host offering plugin protocol version 1 only. */
+function decodeArgs(request) {
+  if (request.typed_args) {
+    return { args: fromValues(request.typed_args), kwargs: fromValueMap(request.typed_kwargs) };
+  }
+  if (request.codec) {
+    throw new PluginError(ERR_KIND_ARG_MISMATCH, request.name, `codec ${request.codec} not supported`);
+  }
+  const args = request.args && request.args.length ? JSON.parse(request.args.toString()) : [];
+  const kwargs = request.kwargs && request.kwargs.length ? JSON.parse(request.kwargs.toString()) : {};
+  return { args: args || [], kwargs: kwargs || {} };
+}
+
+function encodeResponse(request, value) {
+  try {
+    if (request.typed_args) {
+      return { typed_value: toValue(value) };
+    }
+    return { value: Buffer.from(JSON.stringify(value === undefined ? null : value, jsonReplacer)) };
+  } catch (err) {
+    throw new PluginError(ERR_KIND_USER, request.name, `encode return value failed: ${err.message}`);
+  }
+}
+
+/**
+ * Context is bound as `this` of plugin functions, signal is aborted when host cancels the call
+ * or its deadline is exceeded. Use function expressions instead of arrow functions to access it.
+ */
+function newContext(call) {
+  const controller = new AbortController();
+  call.on("cancelled", () => controller.abort());
+  const deadline = call.getDeadline();
+  return {
+    signal: controller.signal,
+    deadline: deadline instanceof Date ? deadline : null,
+  };
+}
+
+async function invoke(request, call, acceptKwargs) {
+  const info = functions.get(request.name);
+  if (!info) {
+    throw new PluginError(ERR_KIND_FUNC_NOT_FOUND, request.name, `Function ${request.name} not registered!`);
+  }
+  let args;
+  let kwargs;
+  try {
+    ({ args, kwargs } = decodeArgs(request));
+  } catch (err) {
+    if (err instanceof PluginError) {
+      throw err;
+    }
+    throw new PluginError(ERR_KIND_ARG_MISMATCH, request.name, `decode arguments failed: ${err.message}`);
+  }
+  if (!acceptKwargs && Object.keys(kwargs).length > 0) {
+    throw new PluginError(ERR_KIND_ARG_MISMATCH, request.name, "keyword arguments not supported by stream");
+  }
+  const bound = bind(info, args, kwargs);
+  try {
+    return await info.func.apply(newContext(call), bound);
+  } catch (err) {
+    throw userError(request.name, err);
+  }
+}
+
+function userError(funcName, err) {
+  if (err instanceof PluginError) {
+    return err;
+  }
+  const message = err instanceof Error ? err.message : String(err);
+  const stack = err instanceof Error ? err.stack : "";
+  return new PluginError(ERR_KIND_USER, funcName, message, stack);
+}
+
+class DebugTalkService {
+  constructor(root, maxSendSize) {
+    this.maxSendSize = maxSendSize;
+    this.callResponseType = root.lookupType("proto.CallResponse");
+    this.callRequestType = root.lookupType("proto.CallRequest");
+    this.pluginErrorType = root.lookupType("proto.PluginError");
+    this.statusType = protobuf.parse(STATUS_PROTO, { keepCase: true }).root.lookupType("google.rpc.Status");
+    this.lastChunkID = 0;
+    this.chunks = new Map(); // chunk id: marshaled CallResponse
+  }
+
+  handlers() {
+    return {
+      GetNames: (call, callback) => callback(null, { names: Array.from(functions.keys()) }),
+      Call: (call, callback) => this.call(call.request, call, callback),
+      CallStream: (call) => this.callStream(call),
+      Describe: (call, callback) => this.describe(call, callback),
+      // no codec is supported, host falls back to typed values
+      Negotiate: (call, callback) => callback(null, {}),
+      CallChunked: (call, callback) => this.callChunked(call, callback),
+      FetchChunks: (call) => this.fetchChunks(call),
+    };
+  }
+
+  call(request, call, callback) {
+    invoke(request, call, true)
+      .then((value) => callback(null, this.deferOversized(request, encodeResponse(request, value))))
+      .catch((err) => callback(this.toServiceError(userError(request.name, err), call)));
+  }
+
+  async callStream(call) {
+    const request = call.request;
+    try {
+      let values = await invoke(request, call, false);
+      if (values === null || values === undefined
+        || (typeof values[Symbol.asyncIterator] !== "function" && typeof values.next !== "function")) {
+        values = [values]; // single value
+      }
+      for await (const value of values) {
+        if (call.cancelled) {
+          break;
+        }
+        if (!call.write(encodeResponse(request, value))) {
+          await once(call, "drain");
+        }
+      }
+      call.end();
+    } catch (err) {
+      call.emit("error", this.toServiceError(userError(request.name, err), call));
+    }
+  }
+
+  describe(call, callback) {
+    const info = functions.get(call.request.name);
+    if (!info) {
+      callback(this.toServiceError(new PluginError(ERR_KIND_FUNC_NOT_FOUND, call.request.name,
+        `Function ${call.request.name} not registered!`), call));
+      return;
+    }
+    const params = info.params
+      ? info.params.map((name) => ({ name, type: "any" }))
+      : [{ name: "args", type: "...any", optional: true }];
+    callback(null, { name: info.name, params, variadic: !info.params, returns: ["any"], doc: info.doc });
+  }
+
+  /** Receive oversized CallRequest in chunks and call function. */
+  callChunked(call, callback) {
+    const data = [];
+    call.on("data", (chunk) => data.push(chunk.data));
+    call.on("end", () => {
+      let request;
+      try {
+        request = this.callRequestType.toObject(this.callRequestType.decode(Buffer.concat(data)), TO_OBJECT);
+      } catch (err) {
+        callback({ code: grpc.status.INVALID_ARGUMENT, details: `unmarshal CallRequest failed: ${err.message}` });
+        return;
+      }
+      this.call(request, call, callback);
+    });
+  }
+
+  /** Send oversized CallResponse kept by Call in chunks. */
+  async fetchChunks(call) {
+    const chunkID = call.request.chunk_id;
+    const data = this.chunks.get(chunkID);
+    if (!data) {
+      call.emit("error", { code: grpc.status.NOT_FOUND, details: `chunks ${chunkID} not found or expired` });
+      return;
+    }
+    this.chunks.delete(chunkID);
+    const size = Math.min(MAX_CHUNK_SIZE, this.maxSendSize - CHUNK_OVERHEAD);
+    for (let offset = 0; offset < data.length && !call.cancelled; offset += size) {
+      if (!call.write({ data: data.subarray(offset, offset + size) })) {
+        await once(call, "drain");
+      }
+    }
+    call.end();
+  }
+
+  /** Keep oversized response to be fetched by FetchChunks if host accepts chunks. */
+  deferOversized(request, response) {
+    if (!request.accept_chunks) {
+      return response;
+    }
+    const type = this.callResponseType;
+    const data = Buffer.from(type.encode(type.fromObject(response)).finish());
+    if (data.length <= this.maxSendSize) {
+      return response;
+    }
+    const chunkID = String(++this.lastChunkID);
+    this.chunks.set(chunkID, data);
+    // drop response not fetched in time, e.g. host call cancelled
+    setTimeout(() => this.chunks.delete(chunkID), CHUNK_TIMEOUT).unref();
+    return { chunk_id: chunkID };
+  }
+
+  /** Convert PluginError to gRPC status error with PluginError attached to status details. */
+  toServiceError(err, call) {
+    let code = ERR_KIND_CODES[err.kind] || grpc.status.UNKNOWN;
+    if (err.kind === ERR_KIND_USER && call.cancelled) {
+      code = grpc.status.DEADLINE_EXCEEDED;
+    }
+    const detail = this.pluginErrorType.encode(this.pluginErrorType.fromObject({
+      kind: err.kind,
+      func_name: err.funcName,
+      message: err.message,
+      stack: err.remoteStack,
+    })).finish();
+    const status = this.statusType.encode(this.statusType.fromObject({
+      code,
+      message: err.message,
+      details: [{ type_url: "type.googleapis.com/proto.PluginError", value: detail }],
+    })).finish();
+    const metadata = new grpc.Metadata();
+    metadata.set("grpc-status-details-bin", Buffer.from(status));
+    return { code, details: err.message, metadata };
+  }
+}
+
+/** Build grpc-js service definition of protobufjs service. */
+function serviceDefinition(service) {
+  const definition = {};
+  for (const method of service.methodsArray) {
+    method.resolve();
+    const requestType = method.resolvedRequestType;
+    const responseType = method.resolvedResponseType;
+    definition[method.name] = {
+      path: `/${service.fullName.slice(1)}/${method.name}`,
+      requestStream: Boolean(method.requestStream),
+      responseStream: Boolean(method.responseStream),
+      requestSerialize: (obj) => Buffer.from(requestType.encode(requestType.fromObject(obj)).finish()),
+      requestDeserialize: (data) => requestType.toObject(requestType.decode(data), TO_OBJECT),
+      responseSerialize: (obj) => Buffer.from(responseType.encode(responseType.fromObject(obj)).finish()),
+      responseDeserialize: (data) => responseType.toObject(responseType.decode(data), TO_OBJECT),
+    };
+  }
+  return definition;
+}
+
+function envInt(name, defaultValue) {
+  const value = parseInt(process.env[name] || "", 10);
+  return value > 0 ? value : defaultValue;
+}
+
+/** Negotiate protocol version with versions offered by host. */
+function protocolVersion() {
+  const versions = (process.env.PLUGIN_PROTOCOL_VERSIONS || "").split(",");
+  return versions.includes(String(PROTOCOL_VERSION_TYPED)) ? PROTOCOL_VERSION_TYPED : PROTOCOL_VERSION_JSON;
+}
+
+/** Start plugin server, the process keeps running until it is killed by host. */
+function serve() {
+  if (process.env[MAGIC_COOKIE_KEY] !== MAGIC_COOKIE_VALUE) {
+    console.error("This binary is a plugin. These are not meant to be executed directly.\n"
+      + "Please execute the program that consumes these plugins, which will\n"
+      + "load any plugins automatically");
+    process.exit(1);
+  }
+  // stdout is reserved for handshake, go-plugin reads plugin address from the first line
+  console.log = console.error;
+  console.info = console.error;
+
+  const root = new protobuf.Root().loadSync(PROTO_PATH, { keepCase: true });
+  root.resolveAll();
+  const healthRoot = protobuf.parse(HEALTH_PROTO, { keepCase: true }).root;
+
+  // max message sizes and compression are specified by host
+  const maxSendSize = envInt(MAX_SEND_SIZE_ENV_NAME, DEFAULT_MAX_MESSAGE_SIZE);
+  const options = {
+    "grpc.max_send_message_length": maxSendSize,
+    "grpc.max_receive_message_length": envInt(MAX_RECV_SIZE_ENV_NAME, DEFAULT_MAX_MESSAGE_SIZE),
+  };
+  const compression = { deflate: 1, gzip: 2 }[process.env[COMPRESSION_ENV_NAME]];
+  if (compression) {
+    options["grpc.default_compression_algorithm"] = compression;
+  }
+
+  const server = new grpc.Server(options);
+  server.addService(serviceDefinition(root.lookupService("proto.DebugTalk")),
+    new DebugTalkService(root, maxSendSize).handlers());
+  server.addService(serviceDefinition(healthRoot.lookupService("grpc.health.v1.Health")), {
+    Check: (call, callback) => callback(null, { status: "SERVING" }),
+  });
+
+  server.bindAsync("127.0.0.1:0", grpc.ServerCredentials.createInsecure(), (err, port) => {
+    if (err) {
+      console.error(`start plugin server failed: ${err.message}`);
+      process.exit(1);
+    }
+    if (typeof server.start === "function") {
+      server.start(); // required before grpc-js 1.10
+    }
+    process.stdout.write(`1|${protocolVersion()}|tcp|127.0.0.1:${port}|grpc\n`);
+  });
+}
+
+module.exports = {
+  PluginError,
+  register,
+  serve,
+};
@@ -0,0 +1,134 @@
+"use strict";
+
+// Typed values are converted from and to plain objects of protobufjs with keepCase and oneofs,
+// keep consistent with to_value and from_value in funppy.
+
+const INT64_MIN = -(2n ** 63n);
+const INT64_MAX = 2n ** 63n - 1n;
+const UINT64_MAX = 2n ** 64n - 1n;
+
+/**
+ * Encode javascript value to typed value, throw TypeError if not supported.
+ * Integers beyond Number.MAX_SAFE_INTEGER should be passed as BigInt.
+ */
+function toValue(value) {
+  if (value === null || value === undefined) {
+    return { null_value: {} };
+  }
+  switch (typeof value) {
+    case "boolean":
+      return { bool_value: value };
+    case "number":
+      return Number.isSafeInteger(value) ? { int_value: String(value) } : { float_value: value };
+    case "bigint":
+      if (value >= INT64_MIN && value <= INT64_MAX) {
+        return { int_value: value.toString() };
+      } else if (value > 0n && value <= UINT64_MAX) {
+        return { uint_value: value.toString() };
+      }
+      return { big_int_value: value.toString() };
+    case "string":
+      return { string_value: value };
+    case "object":
+      break;
+    default:
+      throw new TypeError(`type ${typeof value} not supported`);
+  }
+
+  if (value instanceof Uint8Array) {
+    return { bytes_value: Buffer.from(value) };
+  } else if (value instanceof Date) {
+    const ms = value.getTime();
+    const seconds = Math.floor(ms / 1000);
+    return { time_value: { seconds: String(seconds), nanos: (ms - seconds * 1000) * 1e6 } };
+  } else if (value instanceof Map) {
+    const fields = {};
+    for (const [key, item] of value) {
+      fields[String(key)] = toValue(item);
+    }
+    return { map_value: { fields } };
+  } else if (typeof value[Symbol.iterator] === "function") {
+    // arrays, sets and generators
+    return { list_value: { values: Array.from(value, toValue) } };
+  } else if (typeof value.toJSON === "function") {
+    return toValue(value.toJSON());
+  }
+  const fields = {};
+  for (const [key, item] of Object.entries(value)) {
+    fields[key] = toValue(item);
+  }
+  return { map_value: { fields } };
+}
+
+/**
+ * Decode typed value to javascript value. Integers beyond Number.MAX_SAFE_INTEGER are decoded
+ * as BigInt, and decimals as string since javascript has no decimal type.
+ */
+function fromValue(msg) {
+  switch (msg.kind) {
+    case "bool_value":
+      return msg.bool_value;
+    case "int_value":
+      return toInteger(msg.int_value);
+    case "uint_value":
+      return BigInt(msg.uint_value);
+    case "float_value":
+      return msg.float_value;
+    case "string_value":
+      return msg.string_value;
+    case "bytes_value":
+      return Buffer.from(msg.bytes_value);
+    case "time_value":
+      return new Date(Number(msg.time_value.seconds || 0) * 1000
+        + Math.floor((msg.time_value.nanos || 0) / 1e6));
+    case "big_int_value":
+      return BigInt(msg.big_int_value);
+    case "decimal_value":
+      return msg.decimal_value;
+    case "list_value":
+      return fromValues(msg.list_value);
+    case "map_value":
+      return fromValueMap(msg.map_value);
+    default: // null value or not set
+      return null;
+  }
+}
+
+function fromValues(list) {
+  return ((list && list.values) || []).map(fromValue);
+}
+
+function fromValueMap(map) {
+  const result = {};
+  for (const [key, item] of Object.entries((map && map.fields) || {})) {
+    result[key] = fromValue(item);
+  }
+  return result;
+}
+
+function toInteger(value) {
+  const i = BigInt(value);
+  return i >= BigInt(Number.MIN_SAFE_INTEGER) && i <= BigInt(Number.MAX_SAFE_INTEGER) ? Number(i) : i;
+}
+
+/**
+ * Replace values not supported by JSON for plugin protocol version 1,
+ * bytes are encoded in base64 and BigInt as number like json_default in funppy.
+ */
+function jsonReplacer(key, value) {
+  const original = this[key];
+  if (original instanceof Uint8Array) {
+    return Buffer.from(original).toString("base64");
+  } else if (typeof value === "bigint") {
+    return Number(value);
+  }
+  return value;
+}
+
+module.exports = {
+  toValue,
+  fromValue,
+  fromValues,
+  fromValueMap,
+  jsonReplacer,
+};
@@ -1,5 +1,5 @@
-__version__ = 'v0.5.2'
+__version__ = 'v0.6.0'
 
-from funppy.plugin import register, serve
+from funppy.plugin import Context, HostError, call_host, register, serve
 
-__all__ = ["register", "serve"]
+__all__ = ["Context", "HostError", "call_host", "register", "serve"]
@@ -12,16 +12,30 @@ from google.protobuf import symbol_database as _symbol_database
 _sym_db = _symbol_database.Default()
 
 
+from google.protobuf import timestamp_pb2 as google_dot_protobuf_dot_timestamp__pb2
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0f\x64\x65\x62ugtalk.proto\x12\x05proto\"\x07\n\x05\x45mpty\"!\n\x10GetNamesResponse\x12\r\n\x05names\x18\x01 \x03(\t\")\n\x0b\x43\x61llRequest\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x0c\n\x04\x61rgs\x18\x02 \x01(\x0c\"\x1d\n\x0c\x43\x61llResponse\x12\r\n\x05value\x18\x01 \x01(\x0c\x32o\n\tDebugTalk\x12\x31\n\x08GetNames\x12\x0c.proto.Empty\x1a\x17.proto.GetNamesResponse\x12/\n\x04\x43\x61ll\x12\x12.proto.CallRequest\x1a\x13.proto.CallResponseB\rZ\x0bgo/protoGenb\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0f\x64\x65\x62ugtalk.proto\x12\x05proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x07\n\x05\x45mpty\"!\n\x10GetNamesResponse\x12\r\n\x05names\x18\x01 \x03(\t\"\xec\x02\n\x05Value\x12\"\n\nnull_value\x18\x01 \x01(\x0b\x32\x0c.proto.EmptyH\x00\x12\x14\n\nbool_value\x18\x02 \x01(\x08H\x00\x12\x13\n\tint_value\x18\x03 \x01(\x03H\x00\x12\x14\n\nuint_value\x18\x04 \x01(\x04H\x00\x12\x15\n\x0b\x66loat_value\x18\x05 \x01(\x01H\x00\x12\x16\n\x0cstring_value\x18\x06 \x01(\tH\x00\x12\x15\n\x0b\x62ytes_value\x18\x07 \x01(\x0cH\x00\x12\x30\n\ntime_value\x18\x08 \x01(\x0b\x32\x1a.google.protobuf.TimestampH\x00\x12\x17\n\rbig_int_value\x18\t \x01(\tH\x00\x12\x17\n\rdecimal_value\x18\n \x01(\tH\x00\x12&\n\nlist_value\x18\x0b \x01(\x0b\x32\x10.proto.ValueListH\x00\x12$\n\tmap_value\x18\x0c \x01(\x0b\x32\x0f.proto.ValueMapH\x00\x42\x06\n\x04kind\")\n\tValueList\x12\x1c\n\x06values\x18\x01 \x03(\x0b\x32\x0c.proto.Value\"t\n\x08ValueMap\x12+\n\x06\x66ields\x18\x01 \x03(\x0b\x32\x1b.proto.ValueMap.FieldsEntry\x1a;\n\x0b\x46ieldsEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x1b\n\x05value\x18\x02 \x01(\x0b\x32\x0c.proto.Value:\x02\x38\x01\"\xac\x01\n\x0b\x43\x61llRequest\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x0c\n\x04\x61rgs\x18\x02 \x01(\x0c\x12$\n\ntyped_args\x18\x03 \x01(\x0b\x32\x10.proto.ValueList\x12\r\n\x05\x63odec\x18\x04 \x01(\t\x12\x0e\n\x06kwargs\x18\x05 \x01(\x0c\x12%\n\x0ctyped_kwargs\x18\x06 \x01(\x0b\x32\x0f.proto.ValueMap\x12\x15\n\raccept_chunks\x18\x07 \x01(\x08\"R\n\x0c\x43\x61llResponse\x12\r\n\x05value\x18\x01 \x01(\x0c\x12!\n\x0btyped_value\x18\x02 \x01(\x0b\x32\x0c.proto.Value\x12\x10\n\x08\x63hunk_id\x18\x03 \x01(\t\"\x15\n\x05\x43hunk\x12\x0c\n\x04\x64\x61ta\x18\x01 \x01(\x0c\"&\n\x12\x46\x65tchChunksRequest\x12\x10\n\x08\x63hunk_id\x18\x01 \x01(\t\"\x1f\n\x0f\x44\x65scribeRequest\x12\x0c\n\x04name\x18\x01 \x01(\t\"9\n\tParameter\x12\x0c\n\x04name\x18\x01 \x01(\t\x12\x0c\n\x04type\x18\x02 \x01(\t\x12\x10\n\x08optional\x18\x03 \x01(\x08\"r\n\x10\x44\x65scribeResponse\x12\x0c\n\x04name\x18\x01 \x01(\t\x12 \n\x06params\x18\x02 \x03(\x0b\x32\x10.proto.Parameter\x12\x10\n\x08variadic\x18\x03 \x01(\x08\x12\x0f\n\x07returns\x18\x04 \x03(\t\x12\x0b\n\x03\x64oc\x18\x05 \x01(\t\"\"\n\x10NegotiateRequest\x12\x0e\n\x06\x63odecs\x18\x01 \x03(\t\"\"\n\x11NegotiateResponse\x12\r\n\x05\x63odec\x18\x01 \x01(\t\"\'\n\x12\x43onnectHostRequest\x12\x11\n\tbroker_id\x18\x01 \x01(\r\"N\n\x0bPluginError\x12\x0c\n\x04kind\x18\x01 \x01(\t\x12\x11\n\tfunc_name\x18\x02 \x01(\t\x12\x0f\n\x07message\x18\x03 \x01(\t\x12\r\n\x05stack\x18\x04 \x01(\t2\xcb\x03\n\tDebugTalk\x12\x31\n\x08GetNames\x12\x0c.proto.Empty\x1a\x17.proto.GetNamesResponse\x12/\n\x04\x43\x61ll\x12\x12.proto.CallRequest\x1a\x13.proto.CallResponse\x12\x37\n\nCallStream\x12\x12.proto.CallRequest\x1a\x13.proto.CallResponse0\x01\x12;\n\x08\x44\x65scribe\x12\x16.proto.DescribeRequest\x1a\x17.proto.DescribeResponse\x12\x36\n\x0b\x43onnectHost\x12\x19.proto.ConnectHostRequest\x1a\x0c.proto.Empty\x12>\n\tNegotiate\x12\x17.proto.NegotiateRequest\x1a\x18.proto.NegotiateResponse\x12\x32\n\x0b\x43\x61llChunked\x12\x0c.proto.Chunk\x1a\x13.proto.CallResponse(\x01\x12\x38\n\x0b\x46\x65tchChunks\x12\x19.proto.FetchChunksRequest\x1a\x0c.proto.Chunk0\x01\x42\x35\n$io.github.httprunner.funplugin.protoP\x01Z\x0bgo/protoGenb\x06proto3')
 
 
 
 _EMPTY = DESCRIPTOR.message_types_by_name['Empty']
 _GETNAMESRESPONSE = DESCRIPTOR.message_types_by_name['GetNamesResponse']
+_VALUE = DESCRIPTOR.message_types_by_name['Value']
+_VALUELIST = DESCRIPTOR.message_types_by_name['ValueList']
+_VALUEMAP = DESCRIPTOR.message_types_by_name['ValueMap']
+_VALUEMAP_FIELDSENTRY = _VALUEMAP.nested_types_by_name['FieldsEntry']
 _CALLREQUEST = DESCRIPTOR.message_types_by_name['CallRequest']
 _CALLRESPONSE = DESCRIPTOR.message_types_by_name['CallResponse']
+_CHUNK = DESCRIPTOR.message_types_by_name['Chunk']
+_FETCHCHUNKSREQUEST = DESCRIPTOR.message_types_by_name['FetchChunksRequest']
+_DESCRIBEREQUEST = DESCRIPTOR.message_types_by_name['DescribeRequest']
+_PARAMETER = DESCRIPTOR.message_types_by_name['Parameter']
+_DESCRIBERESPONSE = DESCRIPTOR.message_types_by_name['DescribeResponse']
+_NEGOTIATEREQUEST = DESCRIPTOR.message_types_by_name['NegotiateRequest']
+_NEGOTIATERESPONSE = DESCRIPTOR.message_types_by_name['NegotiateResponse']
+_CONNECTHOSTREQUEST = DESCRIPTOR.message_types_by_name['ConnectHostRequest']
+_PLUGINERROR = DESCRIPTOR.message_types_by_name['PluginError']
 Empty = _reflection.GeneratedProtocolMessageType('Empty', (_message.Message,), {
   'DESCRIPTOR' : _EMPTY,
   '__module__' : 'debugtalk_pb2'
@@ -36,6 +50,35 @@ GetNamesResponse = _reflection.GeneratedProtocolMessageType('GetNamesResponse',
   })
 _sym_db.RegisterMessage(GetNamesResponse)
 
+Value = _reflection.GeneratedProtocolMessageType('Value', (_message.Message,), {
+  'DESCRIPTOR' : _VALUE,
+  '__module__' : 'debugtalk_pb2'
+  # @@protoc_insertion_point(class_scope:proto.Value)
+  })
+_sym_db.RegisterMessage(Value)
+
+ValueList = _reflection.GeneratedProtocolMessageType('ValueList', (_message.Message,), {
+  'DESCRIPTOR' : _VALUELIST,
+  '__module__' : 'debugtalk_pb2'
+  # @@protoc_insertion_point(class_scope:proto.ValueList)
+  })
+_sym_db.RegisterMessage(ValueList)
+
+ValueMap = _reflection.GeneratedProtocolMessageType('ValueMap', (_message.Message,), {
+
+  'FieldsEntry' : _reflection.GeneratedProtocolMessageType('FieldsEntry', (_message.Message,), {
+    'DESCRIPTOR' : _VALUEMAP_FIELDSENTRY,
+    '__module__' : 'debugtalk_pb2'
+    # @@protoc_insertion_point(class_scope:proto.ValueMap.FieldsEntry)
+    })
+  ,
+  'DESCRIPTOR' : _VALUEMAP,
+  '__module__' : 'debugtalk_pb2'
+  # @@protoc_insertion_point(class_scope:proto.ValueMap)
+  })
+_sym_db.RegisterMessage(ValueMap)
+_sym_db.RegisterMessage(ValueMap.FieldsEntry)
+
 CallRequest = _reflection.GeneratedProtocolMessageType('CallRequest', (_message.Message,), {
   'DESCRIPTOR' : _CALLREQUEST,
   '__module__' : 'debugtalk_pb2'
@@ -50,19 +93,110 @@ CallResponse = _reflection.GeneratedProtocolMessageType('CallResponse', (_messag
   })
 _sym_db.RegisterMessage(CallResponse)
 
+Chunk = _reflection.GeneratedProtocolMessageType('Chunk', (_message.Message,), {
+  'DESCRIPTOR' : _CHUNK,
+  '__module__' : 'debugtalk_pb2'
+  # @@protoc_insertion_point(class_scope:proto.Chunk)
+  })
+_sym_db.RegisterMessage(Chunk)
+
+FetchChunksRequest = _reflection.GeneratedProtocolMessageType('FetchChunksRequest', (_message.Message,), {
+  'DESCRIPTOR' : _FETCHCHUNKSREQUEST,
+  '__module__' : 'debugtalk_pb2'
+  # @@protoc_insertion_point(class_scope:proto.FetchChunksRequest)
+  })
+_sym_db.RegisterMessage(FetchChunksRequest)
+
+DescribeRequest = _reflection.GeneratedProtocolMessageType('DescribeRequest', (_message.Message,), {
+  'DESCRIPTOR' : _DESCRIBEREQUEST,
+  '__module__' : 'debugtalk_pb2'
+  # @@protoc_insertion_point(class_scope:proto.DescribeRequest)
+  })
+_sym_db.RegisterMessage(DescribeRequest)
+
+Parameter = _reflection.GeneratedProtocolMessageType('Parameter', (_message.Message,), {
+  'DESCRIPTOR' : _PARAMETER,
+  '__module__' : 'debugtalk_pb2'
+  # @@protoc_insertion_point(class_scope:proto.Parameter)
+  })
+_sym_db.RegisterMessage(Parameter)
+
+DescribeResponse = _reflection.GeneratedProtocolMessageType('DescribeResponse', (_message.Message,), {
+  'DESCRIPTOR' : _DESCRIBERESPONSE,
+  '__module__' : 'debugtalk_pb2'
+  # @@protoc_insertion_point(class_scope:proto.DescribeResponse)
+  })
+_sym_db.RegisterMessage(DescribeResponse)
+
+NegotiateRequest = _reflection.GeneratedProtocolMessageType('NegotiateRequest', (_message.Message,), {
+  'DESCRIPTOR' : _NEGOTIATEREQUEST,
+  '__module__' : 'debugtalk_pb2'
+  # @@protoc_insertion_point(class_scope:proto.NegotiateRequest)
+  })
+_sym_db.RegisterMessage(NegotiateRequest)
+
+NegotiateResponse = _reflection.GeneratedProtocolMessageType('NegotiateResponse', (_message.Message,), {
+  'DESCRIPTOR' : _NEGOTIATERESPONSE,
+  '__module__' : 'debugtalk_pb2'
+  # @@protoc_insertion_point(class_scope:proto.NegotiateResponse)
+  })
+_sym_db.RegisterMessage(NegotiateResponse)
+
+ConnectHostRequest = _reflection.GeneratedProtocolMessageType('ConnectHostRequest', (_message.Message,), {
+  'DESCRIPTOR' : _CONNECTHOSTREQUEST,
+  '__module__' : 'debugtalk_pb2'
+  # @@protoc_insertion_point(class_scope:proto.ConnectHostRequest)
+  })
+_sym_db.RegisterMessage(ConnectHostRequest)
+
+PluginError = _reflection.GeneratedProtocolMessageType('PluginError', (_message.Message,), {
+  'DESCRIPTOR' : _PLUGINERROR,
+  '__module__' : 'debugtalk_pb2'
+  # @@protoc_insertion_point(class_scope:proto.PluginError)
+  })
+_sym_db.RegisterMessage(PluginError)
+
 _DEBUGTALK = DESCRIPTOR.services_by_name['DebugTalk']
 if _descriptor._USE_C_DESCRIPTORS == False:
 
   DESCRIPTOR._options = None
-  DESCRIPTOR._serialized_options = b'Z\013go/protoGen'
-  _EMPTY._serialized_start=26
-  _EMPTY._serialized_end=33
-  _GETNAMESRESPONSE._serialized_start=35
-  _GETNAMESRESPONSE._serialized_end=68
-  _CALLREQUEST._serialized_start=70
-  _CALLREQUEST._serialized_end=111
-  _CALLRESPONSE._serialized_start=113
-  _CALLRESPONSE._serialized_end=142
-  _DEBUGTALK._serialized_start=144
-  _DEBUGTALK._serialized_end=255
+  DESCRIPTOR._serialized_options = b'\012$io.github.httprunner.funplugin.protoP\001Z\013go/protoGen'
+  _VALUEMAP_FIELDSENTRY._options = None
+  _VALUEMAP_FIELDSENTRY._serialized_options = b'8\001'
+  _EMPTY._serialized_start=59
+  _EMPTY._serialized_end=66
+  _GETNAMESRESPONSE._serialized_start=68
+  _GETNAMESRESPONSE._serialized_end=101
+  _VALUE._serialized_start=104
+  _VALUE._serialized_end=468
+  _VALUELIST._serialized_start=470
+  _VALUELIST._serialized_end=511
+  _VALUEMAP._serialized_start=513
+  _VALUEMAP._serialized_end=629
+  _VALUEMAP_FIELDSENTRY._serialized_start=570
+  _VALUEMAP_FIELDSENTRY._serialized_end=629
+  _CALLREQUEST._serialized_start=632
+  _CALLREQUEST._serialized_end=804
+  _CALLRESPONSE._serialized_start=806
+  _CALLRESPONSE._serialized_end=888
+  _CHUNK._serialized_start=890
+  _CHUNK._serialized_end=911
+  _FETCHCHUNKSREQUEST._serialized_start=913
+  _FETCHCHUNKSREQUEST._serialized_end=951
+  _DESCRIBEREQUEST._serialized_start=953
+  _DESCRIBEREQUEST._serialized_end=984
+  _PARAMETER._serialized_start=986
+  _PARAMETER._serialized_end=1043
+  _DESCRIBERESPONSE._serialized_start=1045
+  _DESCRIBERESPONSE._serialized_end=1159
+  _NEGOTIATEREQUEST._serialized_start=1161
+  _NEGOTIATEREQUEST._serialized_end=1195
+  _NEGOTIATERESPONSE._serialized_start=1197
+  _NEGOTIATERESPONSE._serialized_end=1231
+  _CONNECTHOSTREQUEST._serialized_start=1233
+  _CONNECTHOSTREQUEST._serialized_end=1272
+  _PLUGINERROR._serialized_start=1274
+  _PLUGINERROR._serialized_end=1352
+  _DEBUGTALK._serialized_start=1355
+  _DEBUGTALK._serialized_end=1814
 # @@protoc_insertion_point(module_scope)
@@ -24,6 +24,36 @@ class DebugTalkStub(object):
                 request_serializer=debugtalk__pb2.CallRequest.SerializeToString,
                 response_deserializer=debugtalk__pb2.CallResponse.FromString,
                 )
+        self.CallStream = channel.unary_stream(
+                '/proto.DebugTalk/CallStream',
+                request_serializer=debugtalk__pb2.CallRequest.SerializeToString,
+                response_deserializer=debugtalk__pb2.CallResponse.FromString,
+                )
+        self.Describe = channel.unary_unary(
+                '/proto.DebugTalk/Describe',
+                request_serializer=debugtalk__pb2.DescribeRequest.SerializeToString,
+                response_deserializer=debugtalk__pb2.DescribeResponse.FromString,
+                )
+        self.ConnectHost = channel.unary_unary(
+                '/proto.DebugTalk/ConnectHost',
+                request_serializer=debugtalk__pb2.ConnectHostRequest.SerializeToString,
+                response_deserializer=debugtalk__pb2.Empty.FromString,
+                )
+        self.Negotiate = channel.unary_unary(
+                '/proto.DebugTalk/Negotiate',
+                request_serializer=debugtalk__pb2.NegotiateRequest.SerializeToString,
+                response_deserializer=debugtalk__pb2.NegotiateResponse.FromString,
+                )
+        self.CallChunked = channel.stream_unary(
+                '/proto.DebugTalk/CallChunked',
+                request_serializer=debugtalk__pb2.Chunk.SerializeToString,
+                response_deserializer=debugtalk__pb2.CallResponse.FromString,
+                )
+        self.FetchChunks = channel.unary_stream(
+                '/proto.DebugTalk/FetchChunks',
+                request_serializer=debugtalk__pb2.FetchChunksRequest.SerializeToString,
+                response_deserializer=debugtalk__pb2.Chunk.FromString,
+                )
 
 
 class DebugTalkServicer(object):
@@ -41,6 +71,42 @@ class DebugTalkServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def CallStream(self, request, context):
+        """Missing associated documentation comment in .proto file."""
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
+    def Describe(self, request, context):
+        """Missing associated documentation comment in .proto file."""
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
+    def ConnectHost(self, request, context):
+        """Missing associated documentation comment in .proto file."""
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
+    def Negotiate(self, request, context):
+        """Missing associated documentation comment in .proto file."""
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
+    def CallChunked(self, request_iterator, context):
+        """Missing associated documentation comment in .proto file."""
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
+    def FetchChunks(self, request, context):
+        """Missing associated documentation comment in .proto file."""
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
 
 def add_DebugTalkServicer_to_server(servicer, server):
     rpc_method_handlers = {
@@ -54,6 +120,36 @@ def add_DebugTalkServicer_to_server(servicer, server):
                     request_deserializer=debugtalk__pb2.CallRequest.FromString,
                     response_serializer=debugtalk__pb2.CallResponse.SerializeToString,
             ),
+            'CallStream': grpc.unary_stream_rpc_method_handler(
+                    servicer.CallStream,
+                    request_deserializer=debugtalk__pb2.CallRequest.FromString,
+                    response_serializer=debugtalk__pb2.CallResponse.SerializeToString,
+            ),
+            'Describe': grpc.unary_unary_rpc_method_handler(
+                    servicer.Describe,
+                    request_deserializer=debugtalk__pb2.DescribeRequest.FromString,
+                    response_serializer=debugtalk__pb2.DescribeResponse.SerializeToString,
+            ),
+            'ConnectHost': grpc.unary_unary_rpc_method_handler(
+                    servicer.ConnectHost,
+                    request_deserializer=debugtalk__pb2.ConnectHostRequest.FromString,
+                    response_serializer=debugtalk__pb2.Empty.SerializeToString,
+            ),
+            'Negotiate': grpc.unary_unary_rpc_method_handler(
+                    servicer.Negotiate,
+                    request_deserializer=debugtalk__pb2.NegotiateRequest.FromString,
+                    response_serializer=debugtalk__pb2.NegotiateResponse.SerializeToString,
+            ),
+            'CallChunked': grpc.stream_unary_rpc_method_handler(
+                    servicer.CallChunked,
+                    request_deserializer=debugtalk__pb2.Chunk.FromString,
+                    response_serializer=debugtalk__pb2.CallResponse.SerializeToString,
+            ),
+            'FetchChunks': grpc.unary_stream_rpc_method_handler(
+                    servicer.FetchChunks,
+                    request_deserializer=debugtalk__pb2.FetchChunksRequest.FromString,
+                    response_serializer=debugtalk__pb2.Chunk.SerializeToString,
+            ),
     }
     generic_handler = grpc.method_handlers_generic_handler(
             'proto.DebugTalk', rpc_method_handlers)
@@ -97,3 +193,105 @@ class DebugTalk(object):
             debugtalk__pb2.CallResponse.FromString,
             options, channel_credentials,
             insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def CallStream(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_stream(request, target, '/proto.DebugTalk/CallStream',
+            debugtalk__pb2.CallRequest.SerializeToString,
+            debugtalk__pb2.CallResponse.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def Describe(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(request, target, '/proto.DebugTalk/Describe',
+            debugtalk__pb2.DescribeRequest.SerializeToString,
+            debugtalk__pb2.DescribeResponse.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def ConnectHost(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(request, target, '/proto.DebugTalk/ConnectHost',
+            debugtalk__pb2.ConnectHostRequest.SerializeToString,
+            debugtalk__pb2.Empty.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def Negotiate(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_unary(request, target, '/proto.DebugTalk/Negotiate',
+            debugtalk__pb2.NegotiateRequest.SerializeToString,
+            debugtalk__pb2.NegotiateResponse.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def CallChunked(request_iterator,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.stream_unary(request_iterator, target, '/proto.DebugTalk/CallChunked',
+            debugtalk__pb2.Chunk.SerializeToString,
+            debugtalk__pb2.CallResponse.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
+
+    @staticmethod
+    def FetchChunks(request,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.unary_stream(request, target, '/proto.DebugTalk/FetchChunks',
+            debugtalk__pb2.FetchChunksRequest.SerializeToString,
+            debugtalk__pb2.Chunk.FromString,
+            options, channel_credentials,
+            insecure, call_credentials, compression, wait_for_ready, timeout, metadata)
@@ -1,6 +1,13 @@
+import dataclasses
 import logging
+import os
+import time
+from datetime import datetime, timezone
+from decimal import Decimal
 from typing import List
 
+import funppy
+
 
 def sum(*args):
     result = 0
@@ -15,8 +22,12 @@ def sum_ints(*args: List[int]) -> int:
     return result
 
 def sum_two_int(a: int, b: int) -> int:
+    """Return the sum of two integers."""
     return a + b
 
+def div_mod(a: int, b: int):
+    return divmod(a, b)
+
 def sum_two_string(a: str, b: str) -> str:
     return a + b
 
@@ -32,6 +43,49 @@ def concatenate(*args: List[str]) -> str:
         result += str(arg)
     return result
 
+def sleep(ctx: funppy.Context, seconds: float) -> str:
+    deadline = time.time() + seconds
+    while time.time() < deadline:
+        if not ctx.is_active():
+            raise Exception("sleep cancelled")
+        time.sleep(0.01)
+    return f"slept {seconds}s"
+
+def generate_ints(n: int):
+    """Yield integers from 0 to n-1 one by one."""
+    for i in range(n):
+        yield i
+
+def call_host_function(func_name: str, *args):
+    return funppy.call_host(func_name, *args)
+
+def echo(value):
+    return value
+
+@dataclasses.dataclass
+class User:
+    name: str
+    age: int
+
+def get_value(kind: str):
+    """Return value of python type specified by kind."""
+    return {
+        "none": None,
+        "bool": True,
+        "tuple": (1, "a"),
+        "set": {1},
+        "bytes": b"\x00bytes",
+        "datetime": datetime(2024, 1, 2, 3, 4, 5, tzinfo=timezone.utc),
+        "decimal": Decimal("1.25"),
+        "dataclass": User(name="debugtalk", age=18),
+    }[kind]
+
+def greet(name: str, greeting: str = "Hello") -> str:
+    return f"{greeting}, {name}!"
+
+def get_pid() -> int:
+    return os.getpid()
+
 def setup_hook_example(name):
     logging.warn("setup_hook_example")
     return f"setup_hook_example: {name}"
@@ -42,13 +96,20 @@ def teardown_hook_example(name):
 
 
 if __name__ == '__main__':
-    import funppy
     funppy.register("sum", sum)
     funppy.register("sum_ints", sum_ints)
     funppy.register("concatenate", concatenate)
     funppy.register("sum_two_int", sum_two_int)
+    funppy.register("div_mod", div_mod)
     funppy.register("sum_two_string", sum_two_string)
     funppy.register("sum_strings", sum_strings)
+    funppy.register("sleep", sleep)
+    funppy.register("generate_ints", generate_ints)
+    funppy.register("call_host_function", call_host_function)
+    funppy.register("echo", echo)
+    funppy.register("get_value", get_value)
+    funppy.register("greet", greet)
+    funppy.register("get_pid", get_pid)
     funppy.register("setup_hook_example", setup_hook_example)
     funppy.register("teardown_hook_example", teardown_hook_example)
     funppy.serve()
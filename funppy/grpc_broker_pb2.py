@@ -0,0 +1,38 @@
+# -*- coding: utf-8 -*-
+# Generated by the protocol buffer compiler.  DO NOT EDIT!
+# source: grpc_broker.proto
+"""Generated protocol buffer code."""
+from google.protobuf import descriptor as _descriptor
+from google.protobuf import descriptor_pool as _descriptor_pool
+from google.protobuf import message as _message
+from google.protobuf import reflection as _reflection
+from google.protobuf import symbol_database as _symbol_database
+# @@protoc_insertion_point(imports)
+
+_sym_db = _symbol_database.Default()
+
+
+
+
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x11grpc_broker.proto\x12\x06plugin\"@\n\x08\x43onnInfo\x12\x12\n\nservice_id\x18\x01 \x01(\r\x12\x0f\n\x07network\x18\x02 \x01(\t\x12\x0f\n\x07\x61\x64\x64ress\x18\x03 \x01(\t2C\n\nGRPCBroker\x12\x35\n\x0bStartStream\x12\x10.plugin.ConnInfo\x1a\x10.plugin.ConnInfo(\x01\x30\x01\x42\x08Z\x06pluginb\x06proto3')
+
+
+
+_CONNINFO = DESCRIPTOR.message_types_by_name['ConnInfo']
+ConnInfo = _reflection.GeneratedProtocolMessageType('ConnInfo', (_message.Message,), {
+  'DESCRIPTOR' : _CONNINFO,
+  '__module__' : 'grpc_broker_pb2'
+  # @@protoc_insertion_point(class_scope:plugin.ConnInfo)
+  })
+_sym_db.RegisterMessage(ConnInfo)
+
+_GRPCBROKER = DESCRIPTOR.services_by_name['GRPCBroker']
+if _descriptor._USE_C_DESCRIPTORS == False:
+
+  DESCRIPTOR._options = None
+  DESCRIPTOR._serialized_options = b'Z\006plugin'
+  _CONNINFO._serialized_start=29
+  _CONNINFO._serialized_end=93
+  _GRPCBROKER._serialized_start=95
+  _GRPCBROKER._serialized_end=162
+# @@protoc_insertion_point(module_scope)
@@ -1,19 +1,479 @@
+import base64
+import inspect
 import json
 import logging
+import os
 import random
 import sys
+import threading
 import time
 import socket
+import traceback
 from concurrent import futures
-from typing import Callable
+from datetime import datetime, timezone
+from decimal import Decimal
+from typing import Callable, Dict, List, Optional, Tuple
 
 import grpc
+from google.protobuf import any_pb2
+from google.rpc import status_pb2
+from grpc_health.v1 import health_pb2, health_pb2_grpc
+from grpc_health.v1.health import HealthServicer
+from grpc_status import rpc_status
 
-from funppy import debugtalk_pb2, debugtalk_pb2_grpc
+from funppy import debugtalk_pb2, debugtalk_pb2_grpc, grpc_broker_pb2_grpc
 
-__all__ = ["register", "serve"]
+try:
+    import dataclasses
+except ImportError:  # pragma: no cover, python 3.6
+    dataclasses = None
+
+try:
+    import msgpack
+except ImportError:  # pragma: no cover
+    msgpack = None
+
+try:
+    import cbor2
+except ImportError:  # pragma: no cover
+    cbor2 = None
+
+__all__ = ["Context", "HostError", "call_host", "register", "serve"]
 
 functions = {}
+host: Optional[debugtalk_pb2_grpc.DebugTalkStub] = None  # set when host connects
+
+
+class Context(object):
+    """Call context passed to plugin functions, carries the deadline and
+    cancellation of the host call.
+
+    A plugin function receives it if its first parameter is annotated with
+    `Context`, a parameter only named `ctx` receives the first argument as usual.
+    """
+
+    def __init__(self, context: grpc.ServicerContext):
+        self._context = context
+
+    def time_remaining(self) -> Optional[float]:
+        """Seconds remaining before the call deadline, None if no deadline."""
+        return self._context.time_remaining()
+
+    def is_active(self) -> bool:
+        """False if the call has been cancelled or exceeded its deadline."""
+        return self._context.is_active()
+
+    def add_callback(self, callback: Callable[[], None]) -> bool:
+        """Register a callback to be called when the call terminates."""
+        return self._context.add_callback(callback)
+
+
+def accepts_context(func: Callable) -> bool:
+    try:
+        params = list(inspect.signature(func).parameters.values())
+    except (TypeError, ValueError):
+        return False
+    if not params or params[0].kind not in (
+        inspect.Parameter.POSITIONAL_ONLY,
+        inspect.Parameter.POSITIONAL_OR_KEYWORD,
+    ):
+        return False
+    # postponed annotations are strings, see PEP 563
+    return params[0].annotation in (Context, "Context", "funppy.Context")
+
+
+def type_name(annotation) -> str:
+    if annotation is inspect.Parameter.empty:
+        return ""
+    if isinstance(annotation, type):
+        return annotation.__name__
+    return str(annotation).replace("typing.", "")
+
+
+def describe(func_name: str, func: Callable) -> debugtalk_pb2.DescribeResponse:
+    response = debugtalk_pb2.DescribeResponse(
+        name=func_name, doc=inspect.getdoc(func) or ""
+    )
+    sig = inspect.signature(func)
+    params = list(sig.parameters.values())
+    if accepts_context(func):
+        params = params[1:]  # context is not counted as parameter
+
+    for param in params:
+        if param.kind == inspect.Parameter.VAR_KEYWORD:
+            continue
+        if param.kind == inspect.Parameter.VAR_POSITIONAL:
+            response.variadic = True
+        response.params.append(
+            debugtalk_pb2.Parameter(
+                name=param.name,
+                type=type_name(param.annotation),
+                optional=param.default is not inspect.Parameter.empty
+                or param.kind == inspect.Parameter.VAR_POSITIONAL,
+            )
+        )
+
+    return_type = type_name(sig.return_annotation)
+    if return_type:
+        response.returns.append(return_type)
+    return response
+
+
+# PluginError kinds, keep consistent with fungo.ErrorKind
+ERR_KIND_FUNC_NOT_FOUND = "function_not_found"
+ERR_KIND_ARG_MISMATCH = "argument_mismatch"
+ERR_KIND_USER = "user_error"
+
+ERR_KIND_CODES = {
+    ERR_KIND_FUNC_NOT_FOUND: grpc.StatusCode.NOT_FOUND,
+    ERR_KIND_ARG_MISMATCH: grpc.StatusCode.INVALID_ARGUMENT,
+    ERR_KIND_USER: grpc.StatusCode.UNKNOWN,
+}
+
+
+def abort_with_error(
+    context: grpc.ServicerContext, kind: str, func_name: str, message: str, stack: str = ""
+):
+    """Abort the call with PluginError attached to gRPC status details."""
+    detail = any_pb2.Any()
+    detail.Pack(
+        debugtalk_pb2.PluginError(
+            kind=kind, func_name=func_name, message=message, stack=stack
+        )
+    )
+    code = ERR_KIND_CODES.get(kind, grpc.StatusCode.UNKNOWN)
+    if kind == ERR_KIND_USER and not context.is_active():
+        code = grpc.StatusCode.DEADLINE_EXCEEDED
+    status = status_pb2.Status(code=code.value[0], message=message, details=[detail])
+    context.abort_with_status(rpc_status.to_status(status))
+
+
+# plugin protocol versions negotiated with host, keep consistent with fungo
+PROTOCOL_VERSION_JSON = 1  # values are encoded in JSON
+PROTOCOL_VERSION_TYPED = 2  # values are encoded in typed values
+
+INT64_MIN, INT64_MAX = -(2**63), 2**63 - 1
+UINT64_MAX = 2**64 - 1
+
+# environment variables specified by host, keep consistent with fungo
+MAX_SEND_SIZE_ENV_NAME = "HRP_PLUGIN_MAX_SEND_SIZE"
+MAX_RECV_SIZE_ENV_NAME = "HRP_PLUGIN_MAX_RECV_SIZE"
+COMPRESSION_ENV_NAME = "HRP_PLUGIN_COMPRESSION"
+
+# CallRequest and CallResponse beyond max message size are transferred in chunks
+DEFAULT_MAX_MESSAGE_SIZE = 4 << 20
+MAX_CHUNK_SIZE = 1 << 20
+CHUNK_OVERHEAD = 1 << 10  # reserved for fields of Chunk message
+CHUNK_TIMEOUT = 60  # seconds to keep oversized response until it is fetched
+
+
+def to_value(value, msg: Optional[debugtalk_pb2.Value] = None) -> debugtalk_pb2.Value:
+    """Encode python value to typed value, raise TypeError if not supported."""
+    if msg is None:
+        msg = debugtalk_pb2.Value()
+
+    if value is None:
+        msg.null_value.SetInParent()
+    elif isinstance(value, bool):
+        msg.bool_value = value
+    elif isinstance(value, int):
+        if INT64_MIN <= value <= INT64_MAX:
+            msg.int_value = value
+        elif 0 <= value <= UINT64_MAX:
+            msg.uint_value = value
+        else:
+            msg.big_int_value = str(value)
+    elif isinstance(value, float):
+        msg.float_value = value
+    elif isinstance(value, str):
+        msg.string_value = value
+    elif isinstance(value, (bytes, bytearray)):
+        msg.bytes_value = bytes(value)
+    elif isinstance(value, datetime):
+        msg.time_value.FromDatetime(value)  # naive datetime is regarded as UTC
+    elif isinstance(value, Decimal):
+        msg.decimal_value = str(value)
+    elif isinstance(value, (list, tuple, set, frozenset)):
+        msg.list_value.SetInParent()
+        for item in value:
+            to_value(item, msg.list_value.values.add())
+    elif isinstance(value, dict):
+        msg.map_value.SetInParent()
+        for key, item in value.items():
+            to_value(item, msg.map_value.fields[str(key)])
+    elif is_dataclass_instance(value):
+        to_value(normalize(value), msg)
+    else:
+        raise TypeError(f"type {type(value)} not supported")
+    return msg
+
+
+def from_value(msg: debugtalk_pb2.Value):
+    """Decode typed value to python value."""
+    kind = msg.WhichOneof("kind")
+    if kind is None or kind == "null_value":
+        return None
+    elif kind == "time_value":
+        return msg.time_value.ToDatetime().replace(tzinfo=timezone.utc)
+    elif kind == "big_int_value":
+        return int(msg.big_int_value)
+    elif kind == "decimal_value":
+        return Decimal(msg.decimal_value)
+    elif kind == "list_value":
+        return [from_value(item) for item in msg.list_value.values]
+    elif kind == "map_value":
+        return {key: from_value(item) for key, item in msg.map_value.fields.items()}
+    return getattr(msg, kind)
+
+
+def is_dataclass_instance(value) -> bool:
+    if dataclasses is None:
+        return False  # dataclasses is available since python 3.7
+    return dataclasses.is_dataclass(value) and not isinstance(value, type)
+
+
+def normalize(value):
+    """Normalize python value for codecs, tuple and set are converted to list,
+    dataclass to dict, and naive datetime is regarded as UTC."""
+    if is_dataclass_instance(value):
+        return {
+            field.name: normalize(getattr(value, field.name))
+            for field in dataclasses.fields(value)
+        }
+    elif isinstance(value, (list, tuple, set, frozenset)):
+        return [normalize(item) for item in value]
+    elif isinstance(value, dict):
+        return {key: normalize(item) for key, item in value.items()}
+    elif isinstance(value, datetime) and value.tzinfo is None:
+        return value.replace(tzinfo=timezone.utc)
+    return value
+
+
+def json_default(value):
+    """Encode bytes in base64, datetime in RFC3339 and Decimal as string in JSON."""
+    if isinstance(value, (bytes, bytearray)):
+        return base64.b64encode(value).decode("ascii")
+    elif isinstance(value, datetime):
+        return value.isoformat()
+    elif isinstance(value, Decimal):
+        return str(value)
+    raise TypeError(f"type {type(value)} not supported")
+
+
+def msgpack_default(value):
+    """Encode Decimal as string in msgpack, which has no decimal type."""
+    if isinstance(value, Decimal):
+        return str(value)
+    raise TypeError(f"type {type(value)} not supported")
+
+
+# codecs negotiated with host, keep names consistent with fungo.Codec
+# name: (encode, decode), values are normalized before encoding
+codecs = {
+    "json": (
+        lambda value: json.dumps(value, default=json_default).encode("utf-8"),
+        json.loads,
+    ),
+}
+if msgpack is not None:
+    codecs["msgpack"] = (
+        lambda value: msgpack.packb(
+            value, use_bin_type=True, datetime=True, default=msgpack_default
+        ),
+        lambda data: msgpack.unpackb(data, raw=False, timestamp=3),
+    )
+if cbor2 is not None:
+    codecs["cbor"] = (
+        lambda value: cbor2.dumps(value, timezone=timezone.utc),
+        cbor2.loads,
+    )
+
+
+def request_codec(request: debugtalk_pb2.CallRequest):
+    """Return (encode, decode) of request codec, JSON if not specified."""
+    name = request.codec or "json"
+    if name not in codecs:
+        raise ValueError(f"codec {name} not supported")
+    return codecs[name]
+
+
+def decode_args(request: debugtalk_pb2.CallRequest) -> Tuple[List, Dict]:
+    """Decode function arguments and keyword arguments in typed values, or codec of request."""
+    if request.HasField("typed_args"):
+        args = [from_value(item) for item in request.typed_args.values]
+        kwargs = {
+            key: from_value(item) for key, item in request.typed_kwargs.fields.items()
+        }
+        return args, kwargs
+    _, decode = request_codec(request)
+    args = list(decode(request.args) or [])
+    kwargs = dict(decode(request.kwargs) or {}) if request.kwargs else {}
+    return args, kwargs
+
+
+def call_function(request: debugtalk_pb2.CallRequest, context: grpc.ServicerContext):
+    if request.name not in functions:
+        abort_with_error(
+            context,
+            ERR_KIND_FUNC_NOT_FOUND,
+            request.name,
+            f"Function {request.name} not registered!",
+        )
+
+    fn = functions[request.name]
+    try:
+        args, kwargs = decode_args(request)
+    except ValueError as ex:
+        abort_with_error(context, ERR_KIND_ARG_MISMATCH, request.name, str(ex))
+    if accepts_context(fn):
+        args.insert(0, Context(context))
+
+    try:
+        inspect.signature(fn).bind(*args, **kwargs)
+    except TypeError as ex:
+        abort_with_error(context, ERR_KIND_ARG_MISMATCH, request.name, str(ex))
+    except ValueError:
+        pass  # signature not available, e.g. builtin function
+
+    try:
+        return fn(*args, **kwargs)
+    except Exception as ex:
+        abort_with_error(
+            context, ERR_KIND_USER, request.name, str(ex), traceback.format_exc()
+        )
+
+
+def encode_response(
+    context: grpc.ServicerContext, request: debugtalk_pb2.CallRequest, value
+) -> debugtalk_pb2.CallResponse:
+    """Encode value in typed value if request has typed arguments, otherwise in codec of request."""
+    if request.HasField("typed_args"):
+        try:
+            return debugtalk_pb2.CallResponse(typed_value=to_value(value))
+        except TypeError:
+            pass
+    else:
+        encode, _ = request_codec(request)
+        try:
+            return debugtalk_pb2.CallResponse(value=encode(normalize(value)))
+        except (TypeError, ValueError):
+            pass
+
+    abort_with_error(
+        context,
+        ERR_KIND_USER,
+        request.name,
+        f"Function return type {type(value)} not supported!",
+    )
+
+
+def env_int(name: str, default: int) -> int:
+    try:
+        value = int(os.environ.get(name, ""))
+    except ValueError:
+        return default
+    return value if value > 0 else default
+
+
+def max_send_size() -> int:
+    return env_int(MAX_SEND_SIZE_ENV_NAME, DEFAULT_MAX_MESSAGE_SIZE)
+
+
+def chunk_size() -> int:
+    return min(MAX_CHUNK_SIZE, max_send_size() - CHUNK_OVERHEAD)
+
+
+class ChunkStore(object):
+    """Keep oversized responses until they are fetched by FetchChunks."""
+
+    def __init__(self):
+        self._lock = threading.Lock()
+        self._last_id = 0
+        self._data = {}  # chunk id: (marshaled CallResponse, expire time)
+
+    def put(self, data: bytes) -> str:
+        now = time.monotonic()
+        with self._lock:
+            # drop responses not fetched in time, e.g. host call cancelled
+            for key in [k for k, (_, expire) in self._data.items() if expire < now]:
+                del self._data[key]
+            self._last_id += 1
+            chunk_id = str(self._last_id)
+            self._data[chunk_id] = (data, now + CHUNK_TIMEOUT)
+        return chunk_id
+
+    def take(self, chunk_id: str) -> Optional[bytes]:
+        with self._lock:
+            data, _ = self._data.pop(chunk_id, (None, None))
+        return data
+
+
+chunks = ChunkStore()
+
+
+def defer_oversized(
+    request: debugtalk_pb2.CallRequest, response: debugtalk_pb2.CallResponse
+) -> debugtalk_pb2.CallResponse:
+    """Keep oversized response to be fetched by FetchChunks if host accepts chunks."""
+    if not request.accept_chunks or response.ByteSize() <= max_send_size():
+        return response
+    chunk_id = chunks.put(response.SerializeToString())
+    logging.debug(f"response of {request.name} oversized, chunk id: {chunk_id}")
+    return debugtalk_pb2.CallResponse(chunk_id=chunk_id)
+
+
+class HostError(Exception):
+    """Raised when calling host function failed."""
+
+
+def call_host(func_name: str, *args, timeout: Optional[float] = None):
+    """Call host function registered by funplugin.WithHostFunctions."""
+    if host is None:
+        raise HostError("host functions not connected")
+
+    # host functions are served by host since v0.6.0, which always supports typed values
+    request = debugtalk_pb2.CallRequest(name=func_name)
+    request.typed_args.SetInParent()
+    for arg in args:
+        to_value(arg, request.typed_args.values.add())
+    try:
+        response = host.Call(request, timeout=timeout)
+    except grpc.RpcError as ex:
+        raise HostError(
+            f"call host function {func_name} failed: {ex.details()}"
+        ) from ex
+    return from_value(response.typed_value)
+
+
+class GRPCBrokerServicer(grpc_broker_pb2_grpc.GRPCBrokerServicer):
+    """Implementation of hashicorp go-plugin GRPCBroker service,
+    receives connection info of services served by host."""
+
+    def __init__(self):
+        self._conn_info = {}
+        self._cond = threading.Condition()
+
+    def StartStream(self, request_iterator, context):
+        for conn_info in request_iterator:
+            with self._cond:
+                self._conn_info[conn_info.service_id] = conn_info
+                self._cond.notify_all()
+        return iter(())
+
+    def dial(self, service_id: int, timeout: float = 5) -> grpc.Channel:
+        with self._cond:
+            if not self._cond.wait_for(
+                lambda: service_id in self._conn_info, timeout=timeout
+            ):
+                raise HostError(f"timeout waiting for broker service {service_id}")
+            conn_info = self._conn_info.pop(service_id)
+
+        if conn_info.network == "unix":
+            return grpc.insecure_channel(f"unix:{conn_info.address}")
+        return grpc.insecure_channel(conn_info.address)
+
+
+broker = GRPCBrokerServicer()
 
 
 def register(func_name: str, func: Callable):
@@ -30,22 +490,80 @@ class DebugTalkServicer(debugtalk_pb2_grpc.DebugTalkServicer):
         return response
 
     def Call(self, request: debugtalk_pb2.CallRequest, context: grpc.ServicerContext):
+        value = call_function(request, context)
+        response = encode_response(context, request, value)
+        return defer_oversized(request, response)
+
+    def CallChunked(self, request_iterator, context: grpc.ServicerContext):
+        """Receive oversized CallRequest in chunks and call function."""
+        data = b"".join(chunk.data for chunk in request_iterator)
+        request = debugtalk_pb2.CallRequest.FromString(data)
+        return self.Call(request, context)
+
+    def FetchChunks(
+        self, request: debugtalk_pb2.FetchChunksRequest, context: grpc.ServicerContext
+    ):
+        """Send oversized CallResponse kept by Call in chunks."""
+        data = chunks.take(request.chunk_id)
+        if data is None:
+            context.abort(
+                grpc.StatusCode.NOT_FOUND,
+                f"chunks {request.chunk_id} not found or expired",
+            )
+        size = chunk_size()
+        for i in range(0, len(data), size):
+            yield debugtalk_pb2.Chunk(data=data[i : i + size])
+
+    def CallStream(
+        self, request: debugtalk_pb2.CallRequest, context: grpc.ServicerContext
+    ):
+        value = call_function(request, context)
+        if not inspect.isgenerator(value):
+            # non-generator function yields single value
+            yield encode_response(context, request, value)
+            return
+
+        try:
+            for item in value:
+                yield encode_response(context, request, item)
+                if not context.is_active():
+                    break  # stream cancelled by host
+        except Exception as ex:
+            abort_with_error(
+                context, ERR_KIND_USER, request.name, str(ex), traceback.format_exc()
+            )
+        finally:
+            value.close()
+
+    def Describe(self, request: debugtalk_pb2.DescribeRequest, context: grpc.ServicerContext):
         if request.name not in functions:
-            raise Exception(f"Function {request.name} not registered!")
+            abort_with_error(
+                context,
+                ERR_KIND_FUNC_NOT_FOUND,
+                request.name,
+                f"Function {request.name} not registered!",
+            )
 
-        fn = functions[request.name]
-        args = json.loads(request.args)
-        value = fn(*args)
+        return describe(request.name, functions[request.name])
 
-        if isinstance(value, (int, float)):
-            v = str(value).encode("utf-8")
-        elif isinstance(value, (str, dict, list)):
-            v = json.dumps(value).encode("utf-8")
-        else:
-            raise Exception(f"Function return type {type(value)} not supported!")
+    def Negotiate(
+        self, request: debugtalk_pb2.NegotiateRequest, context: grpc.ServicerContext
+    ):
+        for name in request.codecs:
+            if name in codecs:
+                return debugtalk_pb2.NegotiateResponse(codec=name)
+        return debugtalk_pb2.NegotiateResponse()
 
-        response = debugtalk_pb2.CallResponse(value=v)
-        return response
+    def ConnectHost(
+        self, request: debugtalk_pb2.ConnectHostRequest, context: grpc.ServicerContext
+    ):
+        global host
+        try:
+            channel = broker.dial(request.broker_id)
+        except HostError as ex:
+            context.abort(grpc.StatusCode.UNAVAILABLE, str(ex))
+        host = debugtalk_pb2_grpc.DebugTalkStub(channel)
+        return debugtalk_pb2.Empty()
 
 
 def get_available_port() -> int:
@@ -65,21 +583,51 @@ def get_available_port() -> int:
             continue
 
 
+def protocol_version() -> int:
+    """Negotiate protocol version with versions offered by host."""
+    versions = os.environ.get("PLUGIN_PROTOCOL_VERSIONS", "").split(",")
+    if str(PROTOCOL_VERSION_TYPED) in versions:
+        return PROTOCOL_VERSION_TYPED
+    return PROTOCOL_VERSION_JSON
+
+
 def serve():
     # Start the server.
 
     # Generate a random port
     random_port = get_available_port()
 
-    # Create the gRPC server and continue with the rest of your code
-    server = grpc.server(futures.ThreadPoolExecutor(max_workers=10))
+    # Create the gRPC server and continue with the rest of your code,
+    # max message sizes and compression are specified by host
+    options = [
+        ("grpc.max_send_message_length", max_send_size()),
+        (
+            "grpc.max_receive_message_length",
+            env_int(MAX_RECV_SIZE_ENV_NAME, DEFAULT_MAX_MESSAGE_SIZE),
+        ),
+    ]
+    compression = {
+        "gzip": grpc.Compression.Gzip,
+        "deflate": grpc.Compression.Deflate,
+    }.get(os.environ.get(COMPRESSION_ENV_NAME, ""))
+    server = grpc.server(
+        futures.ThreadPoolExecutor(max_workers=10),
+        options=options,
+        compression=compression,
+    )
     debugtalk_pb2_grpc.add_DebugTalkServicer_to_server(DebugTalkServicer(), server)
+    grpc_broker_pb2_grpc.add_GRPCBrokerServicer_to_server(broker, server)
+
+    # health service is probed by host to check if plugin is alive
+    health = HealthServicer()
+    health.set("plugin", health_pb2.HealthCheckResponse.ServingStatus.Value("SERVING"))
+    health_pb2_grpc.add_HealthServicer_to_server(health, server)
 
     server.add_insecure_port(f"127.0.0.1:{random_port}")
     server.start()
 
     # Output information
-    print(f"1|1|tcp|127.0.0.1:{random_port}|grpc")
+    print(f"1|{protocol_version()}|tcp|127.0.0.1:{random_port}|grpc")
     sys.stdout.flush()
 
     try:
@@ -1,10 +1,14 @@
 package funplugin
 
 import (
+	"context"
 	"fmt"
 	"plugin"
 	"reflect"
 	"runtime"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
 
 	"github.com/httprunner/funplugin/fungo"
 )
@@ -14,28 +18,39 @@ type goPlugin struct {
 	*plugin.Plugin
 	path            string                   // plugin file path
 	cachedFunctions map[string]reflect.Value // cache loaded functions to improve performance
+	mu              sync.RWMutex             // protect cachedFunctions from concurrent calls
+	logger          hclog.Logger
 }
 
-func newGoPlugin(path string) (*goPlugin, error) {
+func newGoPlugin(path string, option *pluginOption) (*goPlugin, error) {
 	if runtime.GOOS == "windows" {
 		logger.Warn("go plugin does not support windows")
 		return nil, fmt.Errorf("go plugin does not support windows")
 	}
 
 	// logger
-	logger = logger.ResetNamed("go-plugin")
+	pluginLogger := logger.ResetNamed("go-plugin")
 
 	plg, err := plugin.Open(path)
 	if err != nil {
-		logger.Error("load go plugin failed", "path", path, "error", err)
+		pluginLogger.Error("load go plugin failed", "path", path, "error", err)
 		return nil, err
 	}
 
-	logger.Info("load go plugin success", "path", path)
+	if len(option.hostFunctions) > 0 {
+		// go plugin runs in host process, host functions are called directly
+		if err := fungo.RegisterHostFunctions(option.hostFunctions); err != nil {
+			pluginLogger.Error("register host functions failed", "path", path, "error", err)
+			return nil, err
+		}
+	}
+
+	pluginLogger.Info("load go plugin success", "path", path)
 	p := &goPlugin{
 		Plugin:          plg,
 		path:            path,
 		cachedFunctions: make(map[string]reflect.Value),
+		logger:          pluginLogger,
 	}
 	return p, nil
 }
@@ -49,35 +64,91 @@ func (p *goPlugin) Path() string {
 }
 
 func (p *goPlugin) Has(funcName string) bool {
-	logger.Debug("check if plugin has function", "funcName", funcName)
+	p.logger.Debug("check if plugin has function", "funcName", funcName)
+	_, ok := p.lookup(funcName)
+	return ok
+}
+
+// lookup gets function by name and caches the result, which is safe for concurrent calls
+func (p *goPlugin) lookup(funcName string) (reflect.Value, bool) {
+	p.mu.RLock()
 	fn, ok := p.cachedFunctions[funcName]
+	p.mu.RUnlock()
 	if ok {
-		return fn.IsValid()
+		return fn, fn.IsValid()
 	}
 
 	sym, err := p.Plugin.Lookup(funcName)
-	if err != nil {
-		p.cachedFunctions[funcName] = reflect.Value{} // mark as invalid
-		return false
+	if err == nil {
+		fn = reflect.ValueOf(sym)
 	}
-	fn = reflect.ValueOf(sym)
-
 	// check function type
 	if fn.Kind() != reflect.Func {
-		p.cachedFunctions[funcName] = reflect.Value{} // mark as invalid
-		return false
+		fn = reflect.Value{} // mark as invalid
 	}
 
+	p.mu.Lock()
 	p.cachedFunctions[funcName] = fn
-	return true
+	p.mu.Unlock()
+	return fn, fn.IsValid()
 }
 
 func (p *goPlugin) Call(funcName string, args ...interface{}) (interface{}, error) {
-	if !p.Has(funcName) {
-		return nil, fmt.Errorf("function %s not found", funcName)
+	return p.CallContext(context.Background(), funcName, args...)
+}
+
+func (p *goPlugin) CallContext(ctx context.Context, funcName string, args ...interface{}) (interface{}, error) {
+	return p.CallKwContext(ctx, funcName, args, nil)
+}
+
+func (p *goPlugin) CallKw(funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	return p.CallKwContext(context.Background(), funcName, args, kwargs)
+}
+
+// CallKwContext calls function with keyword arguments,
+// parameter names are not available in go plugin, thus kwargs can only be decoded into struct parameter.
+func (p *goPlugin) CallKwContext(ctx context.Context, funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	fn, ok := p.lookup(funcName)
+	if !ok {
+		return nil, &PluginError{
+			Kind:     ErrKindFuncNotFound,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function %s not found", funcName),
+		}
+	}
+	result, err := fungo.CallFuncKw(ctx, fn, nil, args, kwargs)
+	if err != nil {
+		return nil, fungo.AsPluginError(funcName, err)
+	}
+	return result, nil
+}
+
+func (p *goPlugin) CallStream(ctx context.Context, funcName string, args ...interface{}) (fungo.Stream, error) {
+	fn, ok := p.lookup(funcName)
+	if !ok {
+		return nil, &PluginError{
+			Kind:     ErrKindFuncNotFound,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function %s not found", funcName),
+		}
+	}
+	stream, err := fungo.CallFuncStream(ctx, fn, args...)
+	if err != nil {
+		return nil, fungo.AsPluginError(funcName, err)
+	}
+	return stream, nil
+}
+
+func (p *goPlugin) Describe(funcName string) (*fungo.FuncSignature, error) {
+	fn, ok := p.lookup(funcName)
+	if !ok {
+		return nil, &PluginError{
+			Kind:     ErrKindFuncNotFound,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function %s not found", funcName),
+		}
 	}
-	fn := p.cachedFunctions[funcName]
-	return fungo.CallFunc(fn, args...)
+	return fungo.DescribeFunc(funcName, fn, nil, ""), nil
 }
 
 func (p *goPlugin) Quit() error {
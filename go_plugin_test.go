@@ -6,9 +6,12 @@
 package funplugin
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/httprunner/funplugin/myexec"
 	"github.com/stretchr/testify/assert"
@@ -33,7 +36,10 @@ func TestCallPluginFunction(t *testing.T) {
 	buildGoPlugin()
 	defer removeGoPlugin()
 
-	plugin, err := Init("debugtalk.so", WithDebugLogger(true))
+	plugin, err := Init("debugtalk.so", WithDebugLogger(true),
+		WithHostFunctions(map[string]interface{}{
+			"get_variable": func(name string) string { return "value of " + name },
+		}))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -42,6 +48,18 @@ func TestCallPluginFunction(t *testing.T) {
 		t.Fail()
 	}
 
+	// functions are looked up and cached by concurrent calls
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			plugin.Has("SumTwoInt")
+			plugin.Has("not_exist")
+		}()
+	}
+	wg.Wait()
+
 	// call function with arguments
 	result, err := plugin.Call("Concatenate", "1", 2, "3.14")
 	if !assert.NoError(t, err) {
@@ -50,4 +68,68 @@ func TestCallPluginFunction(t *testing.T) {
 	if !assert.Equal(t, "123.14", result) {
 		t.Fail()
 	}
+
+	// call function with context
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = plugin.CallContext(ctx, "Sleep", 10)
+	if !assert.ErrorIs(t, err, context.DeadlineExceeded) {
+		t.Fail()
+	}
+
+	// call function with keyword arguments decoded into struct parameter
+	result, err = plugin.CallKw("Greet", nil, map[string]interface{}{"name": "funplugin"})
+	if !assert.NoError(t, err) {
+		t.Fail()
+	}
+	if !assert.Equal(t, "Hello, funplugin!", result) {
+		t.Fail()
+	}
+
+	// multiple return values are returned as slice
+	result, err = plugin.Call("DivMod", 7, 2)
+	if !assert.NoError(t, err) {
+		t.Fail()
+	}
+	if !assert.Equal(t, []interface{}{3, 1}, result) {
+		t.Fail()
+	}
+
+	// call function as stream
+	stream, err := plugin.CallStream(context.Background(), "GenerateInts", 2)
+	if !assert.NoError(t, err) {
+		t.Fatal()
+	}
+	defer stream.Close()
+	for i := 0; i < 2; i++ {
+		v, err := stream.Recv()
+		if !assert.NoError(t, err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, i, v) {
+			t.Fail()
+		}
+	}
+
+	// call host function
+	result, err = plugin.Call("CallHostFunction", "get_variable", "token")
+	if !assert.NoError(t, err) {
+		t.Fail()
+	}
+	if !assert.Equal(t, "value of token", result) {
+		t.Fail()
+	}
+
+	// describe function signature
+	sig, err := plugin.Describe("SumTwoInt")
+	if !assert.NoError(t, err) {
+		t.Fail()
+	}
+	if !assert.Len(t, sig.Params, 2) {
+		t.Fail()
+	}
+	if !assert.Equal(t, []string{"int"}, sig.Returns) {
+		t.Fail()
+	}
+	assertDescribeNotFound(t, plugin)
 }
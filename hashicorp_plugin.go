@@ -1,16 +1,20 @@
 package funplugin
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/go-hclog"
 
 	"github.com/hashicorp/go-plugin"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc"
 
 	"github.com/httprunner/funplugin/fungo"
 )
@@ -26,38 +30,70 @@ func (t rpcType) String() string {
 	return string(t)
 }
 
+// callTimeoutGrace is the extra time to wait for response of timeout call,
+// plugin process is restarted only if it does not answer within the grace period.
+var callTimeoutGrace = time.Second
+
 // hashicorpPlugin implements hashicorp/go-plugin
 type hashicorpPlugin struct {
-	client          *plugin.Client
+	next            uint64           // round-robin counter for picking process, accessed atomically
+	mu              sync.RWMutex     // protects processes when plugin process restarts
+	processes       []*pluginProcess // plugin process pool
 	rpcType         rpcType
-	funcCaller      fungo.IFuncCaller
 	cachedFunctions sync.Map // cache loaded functions to improve performance, key is function name, value is bool
 	path            string   // plugin file path
 	option          *pluginOption
+	logger          hclog.Logger
+	quit            chan struct{} // closed when plugin quits
+	quitOnce        sync.Once
+}
+
+// pluginProcess is a plugin process in hashicorpPlugin process pool
+type pluginProcess struct {
+	inflight   int64 // number of running calls, accessed atomically
+	gaveUp     int32 // 1 if supervisor gave up restarting the process, accessed atomically
+	pid        int
+	client     *plugin.Client
+	funcCaller fungo.IFuncCaller
 }
 
 func newHashicorpPlugin(path string, option *pluginOption) (*hashicorpPlugin, error) {
 	p := &hashicorpPlugin{
 		path:   path,
 		option: option,
+		quit:   make(chan struct{}),
 	}
 
 	// plugin type, grpc or rpc
-	p.rpcType = rpcType(os.Getenv(fungo.PluginTypeEnvName))
-	if p.rpcType != rpcTypeRPC {
-		p.rpcType = rpcTypeGRPC // default
+	if p.option.manifest != nil {
+		// executable plugin declares its protocol in manifest
+		p.rpcType = rpcType(p.option.manifest.Protocol)
+	} else if p.option.langType != langTypeGo {
+		// hashicorp python, java and node plugins only support gRPC
+		p.rpcType = rpcTypeGRPC
+	} else {
+		// hashicorp go plugin supports grpc and rpc
+		p.rpcType = rpcType(os.Getenv(fungo.PluginTypeEnvName))
+		if p.rpcType != rpcTypeRPC {
+			p.rpcType = rpcTypeGRPC // default
+		}
 	}
 	// logger
-	logger = logger.ResetNamed(fmt.Sprintf("hc-%v-%v", p.rpcType, p.option.langType))
+	p.logger = logger.ResetNamed(fmt.Sprintf("hc-%v-%v", p.rpcType, p.option.langType))
 
-	// 失败则继续尝试，连续三次失败则返回错误
 	err := p.startPlugin()
-	if err == nil {
-		return p, err
+	if err != nil {
+		return nil, err
 	}
-	logger.Info("load hashicorp go plugin success", "path", path)
+	p.logger.Info("load hashicorp plugin success", "path", path,
+		"processes", len(p.processes))
 
-	return nil, err
+	// supervise plugin processes until quit
+	go newSupervisor(p, p.quit).run()
+	if p.option.watch {
+		go p.watch()
+	}
+	return p, nil
 }
 
 func (p *hashicorpPlugin) Type() string {
@@ -69,13 +105,18 @@ func (p *hashicorpPlugin) Path() string {
 }
 
 func (p *hashicorpPlugin) Has(funcName string) bool {
-	logger.Debug("check if plugin has function", "funcName", funcName)
+	p.logger.Debug("check if plugin has function", "funcName", funcName)
 	flag, ok := p.cachedFunctions.Load(funcName)
 	if ok {
 		return flag.(bool)
 	}
 
-	funcNames, err := p.funcCaller.GetNames()
+	proc, err := p.pick(funcName)
+	if err != nil {
+		return false
+	}
+	defer p.release(proc)
+	funcNames, err := proc.funcCaller.GetNames()
 	if err != nil {
 		return false
 	}
@@ -92,68 +133,368 @@ func (p *hashicorpPlugin) Has(funcName string) bool {
 }
 
 func (p *hashicorpPlugin) Call(funcName string, args ...interface{}) (interface{}, error) {
-	return p.funcCaller.Call(funcName, args...)
+	return p.CallContext(context.Background(), funcName, args...)
 }
 
-func (p *hashicorpPlugin) StartHeartbeat() {
-	ticker := time.NewTicker(15 * time.Second)
-	defer ticker.Stop()
-	var err error
+func (p *hashicorpPlugin) CallContext(ctx context.Context, funcName string, args ...interface{}) (interface{}, error) {
+	return p.CallKwContext(ctx, funcName, args, nil)
+}
+
+func (p *hashicorpPlugin) CallKw(funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	return p.CallKwContext(context.Background(), funcName, args, kwargs)
+}
+
+func (p *hashicorpPlugin) CallKwContext(ctx context.Context, funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	proc, err := p.pick(funcName)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := p.option.getCallTimeout(funcName)
+	if timeout <= 0 {
+		defer p.release(proc)
+		return proc.funcCaller.CallKw(ctx, funcName, args, kwargs)
+	}
+
+	// plugin gets the call deadline, while its response is awaited for an extra grace period
+	// to tell a plugin honouring the deadline from a hung one
+	deadline := time.Now().Add(timeout)
+	callerDeadline := false // deadline of ctx is earlier than timeout
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline, callerDeadline = d, true
+	}
+	graceCtx, cancel := context.WithDeadline(ctx, deadline.Add(callTimeoutGrace))
+	callCtx := &deadlineContext{Context: graceCtx, deadline: deadline}
+
+	type callResult struct {
+		result interface{}
+		err    error
+	}
+	done := make(chan callResult, 1)
+	go func() {
+		defer cancel()
+		result, err := proc.funcCaller.CallKw(callCtx, funcName, args, kwargs)
+		done <- callResult{result: result, err: err}
+		p.release(proc)
+
+		if err != nil && ctx.Err() == nil && graceCtx.Err() != nil {
+			// no response within grace period, the plugin process may be hung by the function
+			p.logger.Error("call function timeout, restarting plugin process...",
+				"funcName", funcName, "timeout", timeout)
+			if _, err := p.restartProcess(proc, true); err != nil {
+				p.logger.Error("restart plugin process failed", "error", err)
+			}
+		}
+	}()
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		if r.err == nil || ctx.Err() != nil || callerDeadline || !isDeadlineExceeded(callCtx, r.err) {
+			return r.result, r.err
+		}
+	case <-timer.C:
+		if callerDeadline {
+			return nil, context.DeadlineExceeded
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return nil, &CallTimeoutError{FuncName: funcName, Timeout: timeout}
+}
+
+// deadlineContext reports deadline of the call to plugin, while it is done after the embedded
+// context with extra grace period, thus the response to deadline can still be received.
+type deadlineContext struct {
+	context.Context
+	deadline time.Time
+}
+
+func (c *deadlineContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+// CallStream calls function and receives values as stream,
+// call timeout is not applied to stream, use ctx deadline instead.
+func (p *hashicorpPlugin) CallStream(ctx context.Context, funcName string, args ...interface{}) (fungo.Stream, error) {
+	proc, err := p.pick(funcName)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := proc.funcCaller.CallStream(ctx, funcName, args...)
+	if err != nil {
+		p.release(proc)
+		return nil, err
+	}
+	return &processStream{Stream: stream, release: func() { p.release(proc) }}, nil
+}
+
+// processStream releases the picked process once the stream ends or is closed
+type processStream struct {
+	fungo.Stream
+	once    sync.Once
+	release func()
+}
+
+func (s *processStream) Recv() (interface{}, error) {
+	value, err := s.Stream.Recv()
+	if err != nil {
+		s.once.Do(s.release)
+	}
+	return value, err
+}
+
+func (s *processStream) Close() error {
+	s.once.Do(s.release)
+	return s.Stream.Close()
+}
+
+func (p *hashicorpPlugin) Describe(funcName string) (*fungo.FuncSignature, error) {
+	proc, err := p.pick(funcName)
+	if err != nil {
+		return nil, err
+	}
+	defer p.release(proc)
+	return proc.funcCaller.Describe(funcName)
+}
+
+// pick picks the least busy process from pool in round-robin order, processes which
+// supervisor gave up restarting are skipped, and error is returned if all processes gave up.
+// release must be called after the picked process is used.
+func (p *hashicorpPlugin) pick(funcName string) (*pluginProcess, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	n := uint64(len(p.processes))
+	start := atomic.AddUint64(&p.next, 1)
+	var picked *pluginProcess
+	for i := uint64(0); i < n; i++ {
+		proc := p.processes[(start+i)%n]
+		if atomic.LoadInt32(&proc.gaveUp) == 1 {
+			continue
+		}
+		if picked == nil ||
+			atomic.LoadInt64(&proc.inflight) < atomic.LoadInt64(&picked.inflight) {
+			picked = proc
+		}
+	}
+	if picked == nil {
+		return nil, &PluginError{
+			Kind:     ErrKindTransportFailure,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("plugin %s gave up restarting all processes", p.path),
+		}
+	}
+	atomic.AddInt64(&picked.inflight, 1)
+	return picked, nil
+}
 
-	for range ticker.C {
-		// Check the client connection status
-		logger.Info("heartbreak......")
-		if p.client.Exited() {
-			logger.Error(fmt.Sprintf("plugin exited, restarting..."))
-			err = p.startPlugin()
-			if err != nil {
+func (p *hashicorpPlugin) release(proc *pluginProcess) {
+	atomic.AddInt64(&proc.inflight, -1)
+}
+
+// process returns the i-th process in pool
+func (p *hashicorpPlugin) process(i int) *pluginProcess {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.processes[i]
+}
+
+// restartProcess starts a new plugin process and swaps it in place of proc,
+// calls in flight keep using proc until proc is killed after swapping, or after they finish if drain is true.
+// It returns nil process if proc has already been replaced by another restart or plugin has quit.
+func (p *hashicorpPlugin) restartProcess(proc *pluginProcess, drain bool) (*pluginProcess, error) {
+	newProc, err := p.startProcess()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	swapped := false
+	select {
+	case <-p.quit:
+	default:
+		for i, current := range p.processes {
+			if current == proc {
+				p.processes[i] = newProc
+				swapped = true
 				break
 			}
 		}
 	}
+	p.mu.Unlock()
+
+	if !swapped {
+		newProc.client.Kill()
+		return nil, nil
+	}
+	if drain {
+		go p.drain([]*pluginProcess{proc})
+	} else {
+		proc.client.Kill()
+	}
+	p.logger.Info("plugin process restarted", "oldPid", proc.pid, "newPid", newProc.pid)
+	return newProc, nil
+}
+
+// StartHeartbeat blocks until plugin quits.
+//
+// Deprecated: plugin processes are supervised automatically since Init,
+// use WithHealthCheck, WithRestartPolicy and WithEventHandler to configure the supervisor.
+func (p *hashicorpPlugin) StartHeartbeat() {
+	<-p.quit
 }
 
+// startPlugin starts all plugin processes of the pool
 func (p *hashicorpPlugin) startPlugin() error {
-	var cmd *exec.Cmd
-	if p.option.langType == langTypePython {
-		// hashicorp python plugin
-		cmd = exec.Command(p.option.python3, p.path)
-		// hashicorp python plugin only supports gRPC
-		p.rpcType = rpcTypeGRPC
-	} else {
-		// hashicorp go plugin
-		cmd = exec.Command(p.path)
-		// hashicorp go plugin supports grpc and rpc
-		p.rpcType = rpcType(os.Getenv(fungo.PluginTypeEnvName))
-		if p.rpcType != rpcTypeRPC {
-			p.rpcType = rpcTypeGRPC // default
+	processes, err := p.startProcesses()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.processes = processes
+	p.mu.Unlock()
+	p.clearCachedFunctions()
+	return nil
+}
+
+func (p *hashicorpPlugin) clearCachedFunctions() {
+	p.cachedFunctions.Range(func(key, value interface{}) bool {
+		p.cachedFunctions.Delete(key)
+		return true
+	})
+}
+
+// startProcesses starts processes of the pool in parallel,
+// started processes are killed if any of them failed to start.
+func (p *hashicorpPlugin) startProcesses() ([]*pluginProcess, error) {
+	size := p.option.processPoolSize
+	if size < 1 {
+		size = 1
+	}
+
+	processes := make([]*pluginProcess, size)
+	errs := make([]error, size)
+	var wg sync.WaitGroup
+	for i := 0; i < size; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			processes[i], errs[i] = p.startProcess()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			continue
 		}
+		// kill started processes
+		for _, proc := range processes {
+			if proc != nil {
+				proc.client.Kill()
+			}
+		}
+		return nil, err
 	}
-	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", fungo.PluginTypeEnvName, p.rpcType))
+	return processes, nil
+}
 
+// startProcess starts a plugin process, retry at most 3 times
+func (p *hashicorpPlugin) startProcess() (*pluginProcess, error) {
 	var err error
 	maxRetryCount := 3
 	for i := 0; i < maxRetryCount; i++ {
-		err = p.tryStartPlugin(cmd, logger)
+		var proc *pluginProcess
+		proc, err = p.tryStartProcess(p.logger)
 		if err == nil {
-			return nil
+			return proc, nil
 		}
 		time.Sleep(time.Second * time.Duration(i*i)) // sleep temporarily before next try
 	}
-	logger.Error("failed to start plugin after max retries")
-	return errors.Wrap(err, "failed to start plugin after max retries")
+	p.logger.Error("failed to start plugin after max retries")
+	return nil, errors.Wrap(err, "failed to start plugin after max retries")
 }
 
-func (p *hashicorpPlugin) tryStartPlugin(cmd *exec.Cmd, logger hclog.Logger) error {
+func (p *hashicorpPlugin) command() *exec.Cmd {
+	var cmd *exec.Cmd
+	switch {
+	case p.option.manifest != nil:
+		// executable plugin declared by manifest
+		cmd = p.option.manifest.command()
+	case p.option.langType == langTypePython:
+		// hashicorp python plugin
+		cmd = exec.Command(p.option.python3, p.path)
+	case p.option.langType == langTypeJava && filepath.Ext(p.path) == ".jar":
+		// hashicorp java plugin packaged as executable jar
+		cmd = exec.Command(p.option.java, "-jar", p.path)
+	case p.option.langType == langTypeJava:
+		// hashicorp java plugin source file, SDK jar should be in CLASSPATH
+		cmd = exec.Command(p.option.java, p.path)
+	case p.option.langType == langTypeNode:
+		// hashicorp node plugin, funnode is resolved by NODE_PATH
+		cmd = exec.Command(p.option.node, p.path)
+	default:
+		// hashicorp go plugin
+		cmd = exec.Command(p.path)
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", fungo.PluginTypeEnvName, p.rpcType))
+	if p.option.langType == langTypeNode {
+		nodePath := p.option.nodePath
+		if env := os.Getenv("NODE_PATH"); env != "" {
+			nodePath += string(os.PathListSeparator) + env
+		}
+		cmd.Env = append(cmd.Env, "NODE_PATH="+nodePath)
+	}
+	// max sizes of plugin are the reverse of host
+	if p.option.maxRecvSize > 0 {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", fungo.MaxSendSizeEnvName, p.option.maxRecvSize))
+	}
+	if p.option.maxSendSize > 0 {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", fungo.MaxRecvSizeEnvName, p.option.maxSendSize))
+	}
+	if p.option.compression != "" {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", fungo.CompressionEnvName, p.option.compression))
+	}
+	return cmd
+}
+
+func (p *hashicorpPlugin) tryStartProcess(logger hclog.Logger) (*pluginProcess, error) {
 	// launch the plugin process
-	p.client = plugin.NewClient(&plugin.ClientConfig{
+	client := plugin.NewClient(&plugin.ClientConfig{
 		HandshakeConfig: fungo.HandshakeConfig,
-		Plugins: map[string]plugin.Plugin{
-			rpcTypeRPC.String():  &fungo.RPCPlugin{},
-			rpcTypeGRPC.String(): &fungo.GRPCPlugin{},
+		// plugins supporting typed values negotiate the newer protocol version
+		VersionedPlugins: map[int]plugin.PluginSet{
+			fungo.ProtocolVersionJSON: {
+				rpcTypeRPC.String(): &fungo.RPCPlugin{HostFunctions: p.option.hostFunctions},
+				rpcTypeGRPC.String(): &fungo.GRPCPlugin{
+					HostFunctions: p.option.hostFunctions,
+					Codec:         p.option.codec,
+					MaxSendSize:   p.option.maxSendSize,
+				},
+			},
+			fungo.ProtocolVersionTyped: {
+				rpcTypeRPC.String(): &fungo.RPCPlugin{
+					HostFunctions:   p.option.hostFunctions,
+					StructuredReply: true,
+				},
+				rpcTypeGRPC.String(): &fungo.GRPCPlugin{
+					HostFunctions: p.option.hostFunctions,
+					TypedValues:   true,
+					Codec:         p.option.codec,
+					MaxSendSize:   p.option.maxSendSize,
+				},
+			},
 		},
-		Cmd:    cmd,
-		Logger: logger,
+		Cmd:             p.command(),
+		Logger:          logger,
+		GRPCDialOptions: p.grpcDialOptions(),
 		AllowedProtocols: []plugin.Protocol{
 			plugin.ProtocolNetRPC,
 			plugin.ProtocolGRPC,
@@ -161,29 +502,69 @@ func (p *hashicorpPlugin) tryStartPlugin(cmd *exec.Cmd, logger hclog.Logger) err
 	})
 
 	// Connect via RPC/gRPC
-	rpcClient, err := p.client.Client()
+	rpcClient, err := client.Client()
 	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("connect %s plugin failed", p.rpcType))
+		client.Kill()
+		return nil, errors.Wrap(err, fmt.Sprintf("connect %s plugin failed", p.rpcType))
 	}
 
 	// Request the plugin
 	raw, err := rpcClient.Dispense(p.rpcType.String())
 	if err != nil {
-		return errors.Wrap(err, fmt.Sprintf("request %s plugin failed", p.rpcType))
+		client.Kill()
+		return nil, errors.Wrap(err, fmt.Sprintf("request %s plugin failed", p.rpcType))
 	}
 
 	// We should have a Function now! This feels like a normal interface
 	// implementation but is in fact over an RPC connection.
-	p.funcCaller = raw.(fungo.IFuncCaller)
+	proc := &pluginProcess{
+		client:     client,
+		funcCaller: raw.(fungo.IFuncCaller),
+	}
+	if reattach := client.ReattachConfig(); reattach != nil {
+		proc.pid = reattach.Pid
+	}
+	logger.Debug("plugin process started", "pid", proc.pid,
+		"protocolVersion", client.NegotiatedVersion())
+	return proc, nil
+}
 
-	p.cachedFunctions = sync.Map{}
+// grpcDialOptions returns dial options of max message sizes and compression
+func (p *hashicorpPlugin) grpcDialOptions() []grpc.DialOption {
+	var callOptions []grpc.CallOption
+	if p.option.maxSendSize > 0 {
+		callOptions = append(callOptions, grpc.MaxCallSendMsgSize(p.option.maxSendSize))
+	}
+	if p.option.maxRecvSize > 0 {
+		callOptions = append(callOptions, grpc.MaxCallRecvMsgSize(p.option.maxRecvSize))
+	}
+	if p.option.compression != "" {
+		callOptions = append(callOptions, grpc.UseCompressor(p.option.compression))
+	}
+	if len(callOptions) == 0 {
+		return nil
+	}
+	return []grpc.DialOption{grpc.WithDefaultCallOptions(callOptions...)}
+}
 
-	return nil
+// emit calls event handler specified by WithEventHandler
+func (p *hashicorpPlugin) emit(event PluginEvent) {
+	if p.option.eventHandler == nil {
+		return
+	}
+	event.Path = p.path
+	p.option.eventHandler(event)
 }
 
 func (p *hashicorpPlugin) Quit() error {
-	// kill hashicorp plugin process
-	logger.Info("quit hashicorp plugin process")
-	p.client.Kill()
+	// kill hashicorp plugin processes
+	p.logger.Info("quit hashicorp plugin process")
+	p.mu.Lock()
+	p.quitOnce.Do(func() { close(p.quit) })
+	processes := p.processes
+	p.mu.Unlock()
+	for _, proc := range processes {
+		proc.client.Kill()
+	}
 	return fungo.CloseLogFile()
 }
@@ -1,10 +1,17 @@
 package funplugin
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/httprunner/funplugin/fungo"
 	"github.com/httprunner/funplugin/myexec"
@@ -43,6 +50,12 @@ func TestHashicorpGRPCGoPlugin(t *testing.T) {
 	defer plugin.Quit()
 
 	assertPlugin(t, plugin)
+	assertPluginContext(t, plugin)
+	assertPluginDescribe(t, plugin)
+	assertPluginError(t, plugin)
+	assertPluginStream(t, plugin)
+	assertPluginCallKw(t, plugin)
+	assertPluginMultiValues(t, plugin)
 }
 
 func TestHashicorpRPCGoPlugin(t *testing.T) {
@@ -51,6 +64,7 @@ func TestHashicorpRPCGoPlugin(t *testing.T) {
 
 	logFile := filepath.Join("docs", "logs", "hashicorp_rpc_go.log")
 	os.Setenv(fungo.PluginTypeEnvName, "rpc")
+	defer os.Unsetenv(fungo.PluginTypeEnvName)
 	plugin, err := Init("fungo/examples/debugtalk.bin",
 		WithDebugLogger(true),
 		WithLogFile(logFile),
@@ -61,6 +75,445 @@ func TestHashicorpRPCGoPlugin(t *testing.T) {
 	defer plugin.Quit()
 
 	assertPlugin(t, plugin)
+	assertPluginContext(t, plugin)
+	assertPluginDescribe(t, plugin)
+	assertPluginError(t, plugin)
+	assertPluginStream(t, plugin)
+	assertPluginCallKw(t, plugin)
+	assertPluginMultiValues(t, plugin)
+}
+
+func TestHashicorpPluginCallTimeout(t *testing.T) {
+	buildHashicorpGoPlugin()
+	defer removeHashicorpGoPlugin()
+
+	plugin, err := Init("fungo/examples/debugtalk.bin",
+		WithCallTimeout(time.Second),
+		WithCallTimeout(200*time.Millisecond, "sleep_uninterruptible"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	// function timeout
+	start := time.Now()
+	_, err = plugin.Call("sleep_uninterruptible", 10)
+	var timeoutErr *CallTimeoutError
+	if !assert.True(t, errors.As(err, &timeoutErr)) {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "sleep_uninterruptible", timeoutErr.FuncName) {
+		t.Fail()
+	}
+	if !assert.Equal(t, 200*time.Millisecond, timeoutErr.Timeout) {
+		t.Fail()
+	}
+	if !assert.ErrorIs(t, err, context.DeadlineExceeded) {
+		t.Fail()
+	}
+	if !assert.Less(t, time.Since(start), 5*time.Second) {
+		t.Fail()
+	}
+
+	// plugin is restarted after grace period and works again
+	time.Sleep(callTimeoutGrace + 500*time.Millisecond)
+	v, err := plugin.Call("sum_two_int", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+
+	// global timeout, plugin process honouring the deadline is not restarted
+	pid, err := plugin.Call("get_pid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = plugin.Call("sleep", 10)
+	if !assert.True(t, errors.As(err, &timeoutErr)) {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, time.Second, timeoutErr.Timeout) {
+		t.Fail()
+	}
+	time.Sleep(callTimeoutGrace)
+	newPid, err := plugin.Call("get_pid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, pid, newPid) {
+		t.Fail()
+	}
+	v, err = plugin.Call("sleep", 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "slept 0.1s", v) {
+		t.Fail()
+	}
+}
+
+func TestHashicorpPluginProcessPool(t *testing.T) {
+	buildHashicorpGoPlugin()
+	defer removeHashicorpGoPlugin()
+
+	plugin, err := Init("fungo/examples/debugtalk.bin",
+		WithProcessPool(3),
+		WithCallTimeout(200*time.Millisecond, "sleep_uninterruptible"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	getPids := func() map[int64]bool {
+		pids := make(map[int64]bool)
+		for i := 0; i < 3; i++ {
+			pid, err := plugin.Call("get_pid")
+			if err != nil {
+				t.Fatal(err)
+			}
+			pids[pid.(int64)] = true
+		}
+		return pids
+	}
+
+	// calls are spread across processes
+	pids := getPids()
+	if !assert.Len(t, pids, 3) {
+		t.Fail()
+	}
+
+	// only the hung process is restarted, after grace period of waiting for its response
+	_, err = plugin.Call("sleep_uninterruptible", 10)
+	var timeoutErr *CallTimeoutError
+	if !assert.True(t, errors.As(err, &timeoutErr)) {
+		t.Fatal(err)
+	}
+	time.Sleep(callTimeoutGrace + 500*time.Millisecond)
+	newPids := getPids()
+	if !assert.Len(t, newPids, 3) {
+		t.Fail()
+	}
+	var kept int
+	for pid := range newPids {
+		if pids[pid] {
+			kept++
+		}
+	}
+	if !assert.Equal(t, 2, kept) {
+		t.Fail()
+	}
+
+}
+
+func TestHashicorpPluginHostFunctions(t *testing.T) {
+	buildHashicorpGoPlugin()
+	defer removeHashicorpGoPlugin()
+
+	hostFunctions := map[string]interface{}{
+		"get_variable": func(name string) (interface{}, error) {
+			if name == "token" {
+				return "abc", nil
+			}
+			return nil, fmt.Errorf("variable %s not found", name)
+		},
+	}
+
+	for _, rpcType := range []string{"grpc", "rpc"} {
+		os.Setenv(fungo.PluginTypeEnvName, rpcType)
+		plugin, err := Init("fungo/examples/debugtalk.bin",
+			WithHostFunctions(hostFunctions))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		v, err := plugin.Call("call_host_function", "get_variable", "token")
+		if !assert.NoError(t, err, rpcType) {
+			t.Fail()
+		}
+		if !assert.Equal(t, "abc", v, rpcType) {
+			t.Fail()
+		}
+
+		// host function error is returned to plugin
+		_, err = plugin.Call("call_host_function", "get_variable", "xxx")
+		var pluginErr *PluginError
+		if !assert.True(t, errors.As(err, &pluginErr), rpcType) {
+			t.Fatal(err)
+		}
+		if !assert.Equal(t, "call_host_function", pluginErr.FuncName, rpcType) {
+			t.Fail()
+		}
+		if !assert.Contains(t, pluginErr.Message, "variable xxx not found", rpcType) {
+			t.Fail()
+		}
+
+		plugin.Quit()
+	}
+	os.Unsetenv(fungo.PluginTypeEnvName)
+
+	// host functions not registered
+	plugin, err := Init("fungo/examples/debugtalk.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+	_, err = plugin.Call("call_host_function", "get_variable", "token")
+	if !assert.ErrorContains(t, err, fungo.ErrHostNotConnected.Error()) {
+		t.Fail()
+	}
+}
+
+func TestHashicorpPluginSupervisor(t *testing.T) {
+	buildHashicorpGoPlugin()
+	defer removeHashicorpGoPlugin()
+
+	events := make(chan PluginEvent, 10)
+	plugin, err := Init("fungo/examples/debugtalk.bin",
+		WithHealthCheck(100*time.Millisecond, time.Second),
+		WithRestartPolicy(RestartPolicy{MaxRestarts: 1, Backoff: 10 * time.Millisecond}),
+		WithEventHandler(func(event PluginEvent) {
+			events <- event
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	killPlugin := func() int {
+		pid, err := plugin.Call("get_pid")
+		if err != nil {
+			t.Fatal(err)
+		}
+		proc, err := os.FindProcess(int(pid.(int64)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := proc.Kill(); err != nil {
+			t.Fatal(err)
+		}
+		return int(pid.(int64))
+	}
+	waitEvent := func(eventType PluginEventType) PluginEvent {
+		select {
+		case event := <-events:
+			if !assert.Equal(t, eventType, event.Type, event.Error) {
+				t.Fatal()
+			}
+			return event
+		case <-time.After(10 * time.Second):
+			t.Fatalf("wait %s event timeout", eventType)
+		}
+		return PluginEvent{}
+	}
+
+	// killed process is restarted
+	pid := killPlugin()
+	event := waitEvent(PluginExited)
+	if !assert.Equal(t, pid, event.Pid) {
+		t.Fail()
+	}
+	event = waitEvent(PluginRestarted)
+	if !assert.Equal(t, 1, event.Restarts) {
+		t.Fail()
+	}
+	newPid, err := plugin.Call("get_pid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, event.Pid, newPid) {
+		t.Fail()
+	}
+
+	// give up after max restarts
+	killPlugin()
+	waitEvent(PluginExited)
+	event = waitEvent(PluginGaveUp)
+	if !assert.Equal(t, 1, event.Restarts) {
+		t.Fail()
+	}
+	// calls fail fast once all processes gave up
+	_, err = plugin.Call("get_pid")
+	var pluginErr *PluginError
+	if !assert.True(t, errors.As(err, &pluginErr), err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, ErrKindTransportFailure, pluginErr.Kind) || !assert.Contains(t, pluginErr.Message, "gave up") {
+		t.Fail()
+	}
+
+	// deprecated heartbeat returns after quit
+	done := make(chan struct{})
+	go func() {
+		plugin.StartHeartbeat()
+		close(done)
+	}()
+	plugin.Quit()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("heartbeat not stopped after quit")
+	}
+}
+
+func TestHashicorpPluginTypedValues(t *testing.T) {
+	buildHashicorpGoPlugin()
+	defer removeHashicorpGoPlugin()
+
+	plugin, err := Init("fungo/examples/debugtalk.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	now := time.Now().UTC()
+	bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	values := []interface{}{
+		nil,
+		true,
+		int64(9007199254740993),
+		[]byte("\x00bytes"),
+		now,
+		bigInt,
+		map[string]interface{}{"id": int64(9007199254740993), "created": now},
+	}
+	for _, value := range values {
+		v, err := plugin.Call("echo", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !assert.Equal(t, value, v) {
+			t.Fail()
+		}
+	}
+}
+
+func TestHashicorpPluginLargePayload(t *testing.T) {
+	buildHashicorpGoPlugin()
+	defer removeHashicorpGoPlugin()
+
+	testData := []struct {
+		options []Option
+		size    int
+	}{
+		// beyond default max message size
+		{nil, 5 << 20},
+		{[]Option{WithCodec(fungo.MsgpackCodec)}, 5 << 20},
+		// small max message size with compression
+		{[]Option{WithMaxSendSize(64 << 10), WithMaxRecvSize(64 << 10), WithCompression("gzip")}, 1 << 20},
+	}
+	for _, td := range testData {
+		plugin, err := Init("fungo/examples/debugtalk.bin", td.options...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// oversized request and response are transferred in chunks
+		value := bytes.Repeat([]byte("x"), td.size)
+		v, err := plugin.Call("echo", value)
+		if !assert.NoError(t, err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, value, v) {
+			t.Fail()
+		}
+		plugin.Quit()
+	}
+}
+
+func TestHashicorpPluginCodec(t *testing.T) {
+	buildHashicorpGoPlugin()
+	defer removeHashicorpGoPlugin()
+
+	for _, codec := range []fungo.Codec{fungo.JSONCodec, fungo.MsgpackCodec, fungo.CBORCodec} {
+		plugin, err := Init("fungo/examples/debugtalk.bin", WithCodec(codec))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertPlugin(t, plugin)
+		assertPluginError(t, plugin)
+		assertPluginStream(t, plugin)
+		assertPluginCallKw(t, plugin)
+
+		if codec != fungo.JSONCodec {
+			value := map[string]interface{}{"id": int64(9007199254740993), "data": []byte("bytes")}
+			v, err := plugin.Call("echo", value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !assert.Equal(t, value, v, codec.Name()) {
+				t.Fail()
+			}
+		} else {
+			// bytes and time are received as strings in JSON
+			now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+			v, err := plugin.Call("echo", map[string]interface{}{"data": []byte("bytes"), "created": now})
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := map[string]interface{}{"data": "Ynl0ZXM=", "created": "2024-01-02T03:04:05Z"}
+			if !assert.Equal(t, expected, v, codec.Name()) {
+				t.Fail()
+			}
+		}
+		plugin.Quit()
+	}
+}
+
+func TestHashicorpPluginWatch(t *testing.T) {
+	buildHashicorpGoPlugin()
+	defer removeHashicorpGoPlugin()
+
+	watchInterval = 100 * time.Millisecond
+	defer func() { watchInterval = time.Second }()
+
+	events := make(chan PluginEvent, 10)
+	plugin, err := Init("fungo/examples/debugtalk.bin",
+		WithWatch(true),
+		WithEventHandler(func(event PluginEvent) {
+			events <- event
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	oldPid, err := plugin.Call("get_pid")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// in-flight call keeps running on old process while reloading
+	result := make(chan error, 1)
+	go func() {
+		_, err := plugin.Call("sleep", 1)
+		result <- err
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(pluginBinPath, future, future); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		if !assert.Equal(t, PluginReloaded, event.Type) || !assert.Nil(t, event.Error) {
+			t.Fatal()
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("wait reloaded event timeout")
+	}
+
+	newPid, err := plugin.Call("get_pid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.NotEqual(t, oldPid, newPid) {
+		t.Fail()
+	}
+	if !assert.Nil(t, <-result) {
+		t.Fail()
+	}
 }
 
 func TestHashicorpPythonPluginWithVenv(t *testing.T) {
@@ -83,7 +536,8 @@ func TestHashicorpPythonPluginWithVenv(t *testing.T) {
 		python3 = filepath.Join(venvDir, "bin", "python3")
 	}
 
-	err = myexec.RunCommand(python3, "-m", "pip", "install", "funppy")
+	// install funppy in this repo instead of the released one on PyPI
+	err = myexec.RunCommand(python3, "-m", "pip", "install", ".")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -95,6 +549,160 @@ func TestHashicorpPythonPluginWithVenv(t *testing.T) {
 	defer plugin.Quit()
 
 	assertPlugin(t, plugin)
+	assertPythonReturnTypes(t, plugin)
+
+	// bytes, datetime and Decimal are received as strings in JSON
+	jsonPlugin, err := Init("funppy/examples/debugtalk.py", WithPython3(python3), WithCodec(fungo.JSONCodec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jsonPlugin.Quit()
+
+	for kind, expected := range map[string]interface{}{
+		"bytes":    "AGJ5dGVz",
+		"datetime": "2024-01-02T03:04:05+00:00",
+		"decimal":  "1.25",
+	} {
+		v, err := jsonPlugin.Call("get_value", kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !assert.Equal(t, expected, v, kind) {
+			t.Fail()
+		}
+	}
+}
+
+// assertPythonReturnTypes checks python return values are decoded to go values
+func assertPythonReturnTypes(t *testing.T, plugin IPlugin) {
+	values := map[string]interface{}{
+		"none":      nil,
+		"bool":      true,
+		"tuple":     []interface{}{int64(1), "a"},
+		"set":       []interface{}{int64(1)},
+		"bytes":     []byte("\x00bytes"),
+		"datetime":  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		"dataclass": map[string]interface{}{"name": "debugtalk", "age": int64(18)},
+	}
+	for kind, expected := range values {
+		v, err := plugin.Call("get_value", kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !assert.Equal(t, expected, v, kind) {
+			t.Fail()
+		}
+	}
+
+	v, err := plugin.Call("get_value", "decimal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decimal, ok := v.(*big.Float)
+	if !assert.True(t, ok, v) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, "1.25", decimal.Text('f', -1)) {
+		t.Fail()
+	}
+
+	// values sent by host are echoed back as is
+	for _, value := range []interface{}{nil, true, []byte("\x00bytes"), time.Now().UTC().Truncate(time.Microsecond)} {
+		v, err := plugin.Call("echo", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !assert.Equal(t, value, v) {
+			t.Fail()
+		}
+	}
+}
+
+func TestHashicorpJavaPluginCommand(t *testing.T) {
+	params := []struct {
+		path string
+		args []string
+	}{
+		{"debugtalk.jar", []string{"/opt/java", "-jar", "debugtalk.jar"}},
+		{"java/examples/DebugTalk.java", []string{"/opt/java", "java/examples/DebugTalk.java"}},
+	}
+	for _, p := range params {
+		plugin := &hashicorpPlugin{
+			path:   p.path,
+			option: &pluginOption{langType: langTypeJava, java: "/opt/java"},
+		}
+		if !assert.Equal(t, p.args, plugin.command().Args) {
+			t.Fail()
+		}
+	}
+}
+
+// TestHashicorpJavaPlugin runs java/examples/DebugTalk.java with SDK jar built by mvn package,
+// e.g. FUNPLUGIN_JAVA_SDK=java/target/funplugin-0.6.0-all.jar
+func TestHashicorpJavaPlugin(t *testing.T) {
+	sdk := os.Getenv("FUNPLUGIN_JAVA_SDK")
+	if sdk == "" {
+		t.Skip("FUNPLUGIN_JAVA_SDK not specified")
+	}
+	t.Setenv("CLASSPATH", sdk)
+
+	plugin, err := Init("java/examples/DebugTalk.java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	assertPlugin(t, plugin)
+	assertPluginStream(t, plugin)
+}
+
+func TestHashicorpNodePluginCommand(t *testing.T) {
+	t.Setenv("NODE_PATH", "/usr/lib/node_modules")
+	plugin := &hashicorpPlugin{
+		path:   "funnode/examples/debugtalk.js",
+		option: &pluginOption{langType: langTypeNode, node: "/opt/node", nodePath: "/opt/node_modules"},
+	}
+	cmd := plugin.command()
+	if !assert.Equal(t, []string{"/opt/node", "funnode/examples/debugtalk.js"}, cmd.Args) {
+		t.Fail()
+	}
+	nodePath := "NODE_PATH=/opt/node_modules" + string(os.PathListSeparator) + "/usr/lib/node_modules"
+	if !assert.Contains(t, cmd.Env, nodePath) {
+		t.Fail()
+	}
+}
+
+func TestFunnodeProto(t *testing.T) {
+	// funnode ships a copy of proto to be loaded by protobufjs
+	expected, err := os.ReadFile("proto/debugtalk.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := os.ReadFile("funnode/debugtalk.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, string(expected), string(actual), "funnode/debugtalk.proto is outdated") {
+		t.Fail()
+	}
+}
+
+// TestHashicorpNodePlugin runs funnode/examples/debugtalk.js with funnode installed from
+// package specified by FUNPLUGIN_NODE_PACKAGE, e.g. local package directory funnode
+func TestHashicorpNodePlugin(t *testing.T) {
+	pkg := os.Getenv("FUNPLUGIN_NODE_PACKAGE")
+	if pkg == "" {
+		t.Skip("FUNPLUGIN_NODE_PACKAGE not specified")
+	}
+
+	plugin, err := Init("funnode/examples/debugtalk.js", WithNodePackage(pkg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	assertPlugin(t, plugin)
+	assertPluginStream(t, plugin)
 }
 
 func assertPlugin(t *testing.T, plugin IPlugin) {
@@ -153,3 +761,285 @@ func assertPlugin(t *testing.T, plugin IPlugin) {
 		t.Fail()
 	}
 }
+
+func assertPluginContext(t *testing.T, plugin IPlugin) {
+	v, err := plugin.CallContext(context.Background(), "sleep", 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "slept 0.1s", v) {
+		t.Fail()
+	}
+
+	// deadline exceeded
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = plugin.CallContext(ctx, "sleep", 10)
+	if !assert.Error(t, err) {
+		t.Fail()
+	}
+	if !assert.Less(t, time.Since(start), 5*time.Second) {
+		t.Fail()
+	}
+
+	// cancelled
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	start = time.Now()
+	_, err = plugin.CallContext(ctx, "sleep", 10)
+	if !assert.Error(t, err) {
+		t.Fail()
+	}
+	if !assert.Less(t, time.Since(start), 5*time.Second) {
+		t.Fail()
+	}
+
+	// plugin still works after cancellation
+	v, err = plugin.Call("sum_two_int", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+}
+
+func assertPluginDescribe(t *testing.T, plugin IPlugin) {
+	sig, err := plugin.Describe("sum_two_int")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &fungo.FuncSignature{
+		Name: "sum_two_int",
+		Params: []fungo.FuncParam{
+			{Name: "a", Type: "int"},
+			{Name: "b", Type: "int"},
+		},
+		Returns: []string{"int"},
+		Doc:     "return the sum of two integers",
+	}
+	if !assert.Equal(t, expected, sig) {
+		t.Fail()
+	}
+
+	// variadic function, context is not counted as parameter
+	sig, err = plugin.Describe("sum_ints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.True(t, sig.Variadic) {
+		t.Fail()
+	}
+	if !assert.Equal(t, []fungo.FuncParam{{Name: "arg0", Type: "int", Optional: true}}, sig.Params) {
+		t.Fail()
+	}
+	sig, err = plugin.Describe("sleep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, []fungo.FuncParam{{Name: "arg0", Type: "float64"}}, sig.Params) {
+		t.Fail()
+	}
+
+	_, err = plugin.Describe("not_exist")
+	if !assert.Error(t, err) {
+		t.Fail()
+	}
+}
+
+// assertDescribeNotFound asserts Describe returns ErrKindFuncNotFound for missing function
+func assertDescribeNotFound(t *testing.T, plugin IPlugin) {
+	_, err := plugin.Describe("not_exist")
+	var pluginErr *PluginError
+	if !assert.True(t, errors.As(err, &pluginErr), err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, ErrKindFuncNotFound, pluginErr.Kind) {
+		t.Fail()
+	}
+}
+
+func assertPluginMultiValues(t *testing.T, plugin IPlugin) {
+	result, err := plugin.Call("div_mod", 7, 2)
+	if !assert.NoError(t, err) {
+		t.Fatal()
+	}
+	var div, mod int
+	if !assert.NoError(t, fungo.Unpack(result, &div, &mod)) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, 3, div) || !assert.Equal(t, 1, mod) {
+		t.Fail()
+	}
+
+	_, err = plugin.Call("div_mod", 7, 0)
+	var pluginErr *PluginError
+	if !assert.True(t, errors.As(err, &pluginErr), err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, ErrKindUser, pluginErr.Kind) || !assert.Equal(t, "division by zero", pluginErr.Message) {
+		t.Fail()
+	}
+}
+
+func assertPluginCallKw(t *testing.T, plugin IPlugin) {
+	// keyword arguments bound to registered parameter names
+	v, err := plugin.CallKw("sum_two_int", []interface{}{1}, map[string]interface{}{"b": 2})
+	if !assert.NoError(t, err) {
+		t.Fatal()
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+
+	// keyword arguments decoded into struct parameter
+	v, err = plugin.CallKw("greet", nil, map[string]interface{}{"name": "funplugin", "greeting": "Hi"})
+	if !assert.NoError(t, err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, "Hi, funplugin!", v) {
+		t.Fail()
+	}
+
+	// positional map bound to struct parameter
+	v, err = plugin.Call("greet", map[string]interface{}{"name": "funplugin"})
+	if !assert.NoError(t, err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, "Hello, funplugin!", v) {
+		t.Fail()
+	}
+
+	_, err = plugin.CallKw("sum_two_int", []interface{}{1}, map[string]interface{}{"c": 2})
+	var pluginErr *PluginError
+	if !assert.True(t, errors.As(err, &pluginErr), err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, ErrKindArgMismatch, pluginErr.Kind) {
+		t.Fail()
+	}
+	if !assert.Equal(t, "unexpected keyword argument c", pluginErr.Message) {
+		t.Fail()
+	}
+}
+
+func assertPluginError(t *testing.T, plugin IPlugin) {
+	testData := []struct {
+		funcName string
+		args     []interface{}
+		kind     ErrorKind
+		message  string
+		hasStack bool
+	}{
+		{"not_exist", nil, ErrKindFuncNotFound, "function not_exist not found", false},
+		{"sum_two_int", []interface{}{1}, ErrKindArgMismatch, "function expect 2 arguments, but got 1", false},
+		{"sum", []interface{}{"a"}, ErrKindUser, "unexpected type: string", false},
+		{"divide", []interface{}{1, 0}, ErrKindPanic, "runtime error: integer divide by zero", true},
+	}
+
+	for _, td := range testData {
+		_, err := plugin.Call(td.funcName, td.args...)
+		var pluginErr *PluginError
+		if !assert.True(t, errors.As(err, &pluginErr), err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, td.kind, pluginErr.Kind) {
+			t.Fail()
+		}
+		if !assert.Equal(t, td.funcName, pluginErr.FuncName) {
+			t.Fail()
+		}
+		if !assert.Equal(t, td.message, pluginErr.Message) {
+			t.Fail()
+		}
+		if !assert.Equal(t, td.hasStack, pluginErr.Stack != "") {
+			t.Fail()
+		}
+	}
+
+	// plugin still works after panic
+	result, err := plugin.Call("sum_two_int", 1, 2)
+	if !assert.Nil(t, err) {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, result) {
+		t.Fail()
+	}
+}
+
+func assertPluginStream(t *testing.T, plugin IPlugin) {
+	stream, err := plugin.CallStream(context.Background(), "generate_ints", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []interface{}
+	for {
+		v, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		values = append(values, v)
+	}
+	if !assert.Len(t, values, 3) {
+		t.Fatal()
+	}
+	for i, v := range values {
+		if !assert.EqualValues(t, i, v) { // int via RPC, float64 via gRPC
+			t.Fail()
+		}
+	}
+	if !assert.NoError(t, stream.Close()) {
+		t.Fail()
+	}
+
+	// close stream before it ends
+	stream, err = plugin.CallStream(context.Background(), "generate_ints", 1000000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := stream.Recv()
+	if !assert.NoError(t, err) {
+		t.Fatal()
+	}
+	if !assert.EqualValues(t, 0, v) {
+		t.Fail()
+	}
+	if !assert.NoError(t, stream.Close()) {
+		t.Fail()
+	}
+
+	// non-streaming function yields single value
+	stream, err = plugin.CallStream(context.Background(), "sum_two_int", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	v, err = stream.Recv()
+	if !assert.NoError(t, err) {
+		t.Fatal()
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+	_, err = stream.Recv()
+	if !assert.Equal(t, io.EOF, err) {
+		t.Fail()
+	}
+
+	// function not found
+	stream, err = plugin.CallStream(context.Background(), "not_exist")
+	if err == nil {
+		_, err = stream.Recv() // gRPC stream reports error on receiving
+	}
+	var pluginErr *PluginError
+	if !assert.True(t, errors.As(err, &pluginErr)) {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, ErrKindFuncNotFound, pluginErr.Kind) {
+		t.Fail()
+	}
+}
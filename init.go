@@ -1,8 +1,11 @@
 package funplugin
 
 import (
+	"context"
 	"fmt"
+	"os/exec"
 	"path/filepath"
+	"time"
 
 	"github.com/hashicorp/go-hclog"
 	"github.com/pkg/errors"
@@ -16,12 +19,17 @@ var (
 )
 
 type IPlugin interface {
-	Type() string                                                   // get plugin type
-	Path() string                                                   // get plugin file path
-	Has(funcName string) bool                                       // check if plugin has function
-	Call(funcName string, args ...interface{}) (interface{}, error) // call function
-	Quit() error                                                    // quit plugin
-	StartHeartbeat()                                                // heartbeat to keep the plugin alive
+	Type() string                                                                                                               // get plugin type
+	Path() string                                                                                                               // get plugin file path
+	Has(funcName string) bool                                                                                                   // check if plugin has function
+	Call(funcName string, args ...interface{}) (interface{}, error)                                                             // call function
+	CallContext(ctx context.Context, funcName string, args ...interface{}) (interface{}, error)                                 // call function with context
+	CallKw(funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error)                             // call function with keyword arguments
+	CallKwContext(ctx context.Context, funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) // call function with keyword arguments and context
+	CallStream(ctx context.Context, funcName string, args ...interface{}) (fungo.Stream, error)                                 // call function and receive values as stream
+	Describe(funcName string) (*fungo.FuncSignature, error)                                                                     // get function signature
+	Quit() error                                                                                                                // quit plugin
+	StartHeartbeat()                                                                                                            // Deprecated: plugin processes are supervised automatically
 }
 
 type langType string
@@ -30,14 +38,55 @@ const (
 	langTypeGo     langType = "go"
 	langTypePython langType = "py"
 	langTypeJava   langType = "java"
+	langTypeNode   langType = "node"
+	langTypeShell  langType = "sh"
+	langTypeRuby   langType = "rb"
+	langTypePerl   langType = "pl"
 )
 
 type pluginOption struct {
-	debugLogger    bool     // whether set log level to DEBUG
-	logFile        string   // specify log file path
-	disableLogTime bool     // whether disable log time
-	langType       langType // go or py
-	python3        string   // python3 path with funppy dependency
+	debugLogger         bool                     // whether set log level to DEBUG
+	logFile             string                   // specify log file path
+	disableLogTime      bool                     // whether disable log time
+	langType            langType                 // go, py, java, node, sh, rb, pl or language label of manifest
+	python3             string                   // python3 path with funppy dependency
+	java                string                   // java path to run .jar or .java plugin
+	node                string                   // node path to run .js or .mjs plugin
+	nodePackage         string                   // funnode package installed for node plugin
+	nodePath            string                   // node_modules path with funnode installed
+	embeddedJS          bool                     // whether run .js plugin in embedded javascript engine
+	manifest            *Manifest                // manifest of executable plugin declared by plugin.yaml or plugin.json
+	callTimeout         time.Duration            // timeout for all function calls, 0 means no timeout
+	funcCallTimeouts    map[string]time.Duration // timeout for specified function calls, override callTimeout
+	processPoolSize     int                      // number of hashicorp plugin processes
+	hostFunctions       map[string]interface{}   // host functions which can be called back by plugin
+	healthCheckInterval time.Duration            // interval of supervisor health check
+	healthCheckTimeout  time.Duration            // timeout of each health probe
+	restartPolicy       RestartPolicy            // restart policy of unhealthy plugin processes
+	eventHandler        func(PluginEvent)        // handler of supervisor events
+	watch               bool                     // whether reload hashicorp plugin when plugin file changes
+	codec               fungo.Codec              // codec of hashicorp gRPC plugin values
+	maxSendSize         int                      // max size of gRPC messages sent to plugin
+	maxRecvSize         int                      // max size of gRPC messages received from plugin
+	compression         string                   // compressor of gRPC messages, e.g. gzip
+	wasmMemoryLimit     int                      // memory limit of wasm module in bytes
+	starlarkMaxSteps    uint64                   // max execution steps of each starlark call, 0 means no limit
+}
+
+// getCallTimeout returns the call timeout of specified function
+func (o *pluginOption) getCallTimeout(funcName string) time.Duration {
+	if timeout, ok := o.funcCallTimeouts[funcName]; ok {
+		return timeout
+	}
+	return o.callTimeout
+}
+
+// getNodePackage returns funnode package to install for node plugin
+func (o *pluginOption) getNodePackage() string {
+	if o.nodePackage == "" {
+		return "funnode"
+	}
+	return o.nodePackage
 }
 
 type Option func(*pluginOption)
@@ -66,23 +115,194 @@ func WithPython3(python3 string) Option {
 	}
 }
 
+// WithJava specifies java executable to run .jar or .java plugin,
+// default is $JAVA_HOME/bin/java or java in $PATH.
+func WithJava(java string) Option {
+	return func(o *pluginOption) {
+		o.java = java
+	}
+}
+
+// WithNode specifies node executable to run .js or .mjs plugin, default is node in $PATH
+func WithNode(node string) Option {
+	return func(o *pluginOption) {
+		o.node = node
+	}
+}
+
+// WithNodePackage specifies funnode package installed in $HOME/.hrp/node for node plugin,
+// e.g. local package directory or funnode@0.6.0, default is funnode.
+func WithNodePackage(pkg string) Option {
+	return func(o *pluginOption) {
+		o.nodePackage = pkg
+	}
+}
+
+// WithEmbeddedJS runs .js plugin in embedded javascript engine instead of node,
+// functions exported by module.exports are plugin functions. Otherwise .js plugin is run by node with funnode.
+func WithEmbeddedJS(embedded bool) Option {
+	return func(o *pluginOption) {
+		o.embeddedJS = embedded
+	}
+}
+
+// WithCallTimeout sets timeout for hashicorp plugin function calls.
+// The timeout applies to all functions if funcNames is not specified, otherwise only to funcNames.
+// When a call exceeds its timeout, *CallTimeoutError is returned and the plugin process is restarted.
+func WithCallTimeout(timeout time.Duration, funcNames ...string) Option {
+	return func(o *pluginOption) {
+		if len(funcNames) == 0 {
+			o.callTimeout = timeout
+			return
+		}
+		if o.funcCallTimeouts == nil {
+			o.funcCallTimeouts = make(map[string]time.Duration)
+		}
+		for _, funcName := range funcNames {
+			o.funcCallTimeouts[funcName] = timeout
+		}
+	}
+}
+
+// WithProcessPool starts size processes for hashicorp plugin and spreads calls across them,
+// it is useful for CPU-bound python functions which are serialized by GIL in one process.
+func WithProcessPool(size int) Option {
+	return func(o *pluginOption) {
+		o.processPoolSize = size
+	}
+}
+
+// WithHostFunctions registers host functions which can be called back by plugin functions,
+// e.g. fungo.CallHost in go plugin and funppy.call_host in python plugin.
+// Host functions of go plugins (.so) are shared in process, go plugins with different host functions can not be loaded together.
+func WithHostFunctions(funcs map[string]interface{}) Option {
+	return func(o *pluginOption) {
+		o.hostFunctions = funcs
+	}
+}
+
+// WithHealthCheck sets the interval and timeout of supervisor health probes for hashicorp plugin,
+// default interval is 15s and timeout is 5s. Unhealthy processes are restarted following RestartPolicy.
+func WithHealthCheck(interval, timeout time.Duration) Option {
+	return func(o *pluginOption) {
+		o.healthCheckInterval = interval
+		o.healthCheckTimeout = timeout
+	}
+}
+
+// WithRestartPolicy sets how the supervisor restarts unhealthy hashicorp plugin processes
+func WithRestartPolicy(policy RestartPolicy) Option {
+	return func(o *pluginOption) {
+		o.restartPolicy = policy
+	}
+}
+
+// WithEventHandler sets handler of supervisor events, e.g. process exited, restarted and gave up.
+// The handler is called synchronously in supervisor or watcher goroutine.
+func WithEventHandler(handler func(PluginEvent)) Option {
+	return func(o *pluginOption) {
+		o.eventHandler = handler
+	}
+}
+
+// WithWatch reloads hashicorp plugin when the plugin file changes, e.g. .py source or .bin binary,
+// calls are switched to new processes once they work, and old processes are killed after running calls finish.
+func WithWatch(watch bool) Option {
+	return func(o *pluginOption) {
+		o.watch = watch
+	}
+}
+
+// WithCodec specifies codec to encode arguments and return values of hashicorp gRPC plugin,
+// e.g. fungo.MsgpackCodec. The codec is negotiated when plugin starts, plugins not supporting it
+// fall back to typed values, or JSON for plugins built with older fungo/funppy.
+func WithCodec(codec fungo.Codec) Option {
+	return func(o *pluginOption) {
+		o.codec = codec
+	}
+}
+
+// WithMaxSendSize specifies max size of gRPC messages sent to hashicorp plugin, default is 4MB.
+// Arguments beyond max size are sent in chunks to plugins built with fungo/funppy v0.6.0 or later.
+func WithMaxSendSize(size int) Option {
+	return func(o *pluginOption) {
+		o.maxSendSize = size
+	}
+}
+
+// WithMaxRecvSize specifies max size of gRPC messages received from hashicorp plugin, default is 4MB.
+// Return values beyond max size are fetched in chunks from plugins built with fungo/funppy v0.6.0 or later.
+func WithMaxRecvSize(size int) Option {
+	return func(o *pluginOption) {
+		o.maxRecvSize = size
+	}
+}
+
+// WithCompression compresses gRPC messages of hashicorp plugin with specified compressor, e.g. gzip
+func WithCompression(compressor string) Option {
+	return func(o *pluginOption) {
+		o.compression = compressor
+	}
+}
+
+// WithWasmMemoryLimit limits memory of wasm plugin module in bytes, rounded up to 64KB pages,
+// default is 64MB. Memory growth beyond the limit fails in the module.
+func WithWasmMemoryLimit(limit int) Option {
+	return func(o *pluginOption) {
+		o.wasmMemoryLimit = limit
+	}
+}
+
+// WithStarlarkMaxSteps limits execution steps of starlark plugin, including loading the script
+// and each function call, default is no limit. The call fails with ErrKindStepLimit when the limit is exceeded.
+func WithStarlarkMaxSteps(steps uint64) Option {
+	return func(o *pluginOption) {
+		o.starlarkMaxSteps = steps
+	}
+}
+
 // Init initializes plugin with plugin path
 func Init(path string, options ...Option) (plugin IPlugin, err error) {
+	option := newPluginOption(options...)
+	initLogger(option)
+	return initPlugin(path, option)
+}
+
+func newPluginOption(options ...Option) *pluginOption {
 	option := &pluginOption{}
 	for _, o := range options {
 		o(option)
 	}
+	return option
+}
 
-	// init logger
+func initLogger(option *pluginOption) {
 	logLevel := hclog.Info
 	if option.debugLogger {
 		logLevel = hclog.Debug
 	}
 	logger = fungo.InitLogger(
 		logLevel, option.logFile, option.disableLogTime)
+}
 
+// initPlugin initializes plugin by file extension, logger should be initialized before
+func initPlugin(path string, option *pluginOption) (plugin IPlugin, err error) {
 	logger.Info("init plugin", "path", path)
 
+	// executable plugin declared by manifest
+	if isManifest(path) {
+		option.manifest, err = LoadManifest(path)
+		if err != nil {
+			logger.Error("load plugin manifest failed", "path", path, "error", err)
+			return nil, err
+		}
+		option.langType = langType(option.manifest.Language)
+		if option.manifest.Protocol == manifestProtocolStdio {
+			return newStdioPlugin(path, option)
+		}
+		return newHashicorpPlugin(path, option)
+	}
+
 	// priority: hashicorp plugin > go plugin
 	ext := filepath.Ext(path)
 	switch ext {
@@ -103,9 +323,56 @@ func Init(path string, options ...Option) (plugin IPlugin, err error) {
 		}
 		option.langType = langTypePython
 		return newHashicorpPlugin(path, option)
+	case ".jar", ".java":
+		// found hashicorp java plugin file, .java source file is launched directly by java 11+
+		if option.java == "" {
+			option.java, err = myexec.LookupJava()
+			if err != nil {
+				logger.Error("lookup java failed", "error", err)
+				return nil, errors.Wrap(err, "miss java")
+			}
+		}
+		option.langType = langTypeJava
+		return newHashicorpPlugin(path, option)
+	case ".js", ".mjs":
+		if option.embedsJS(path) {
+			// found javascript plugin file, run in embedded javascript engine
+			return newJSPlugin(path, option)
+		}
+		// found hashicorp node plugin file
+		if option.node == "" {
+			option.node, err = exec.LookPath("node")
+			if err != nil {
+				logger.Error("lookup node failed", "error", err)
+				return nil, errors.Wrap(err, "miss node")
+			}
+		}
+		if option.nodePath == "" {
+			// install funnode in $HOME/.hrp/node if not installed
+			option.nodePath, err = myexec.EnsureNodeModules("", option.getNodePackage())
+			if err != nil {
+				logger.Error("prepare funnode node modules failed", "error", err)
+				return nil, errors.Wrap(err, "install funnode node modules failed")
+			}
+		}
+		option.langType = langTypeNode
+		return newHashicorpPlugin(path, option)
+	case ".sh", ".rb", ".pl":
+		// found script plugin speaking JSON-RPC over stdio
+		option.langType = langType(ext[1:])
+		return newStdioPlugin(path, option)
+	case ".wasm":
+		// found wasm plugin file, run in process
+		return newWasmPlugin(path, option)
+	case ".star":
+		// found starlark plugin file, run in process
+		return newStarlarkPlugin(path, option)
+	case ".go":
+		// found go source file, interpreted in process
+		return newYaegiPlugin(path, option)
 	case ".so":
 		// found go plugin file
-		return newGoPlugin(path)
+		return newGoPlugin(path, option)
 	default:
 		logger.Error("invalid plugin path", "path", path, "error", err)
 		return nil, fmt.Errorf("unsupported plugin type: %s", ext)
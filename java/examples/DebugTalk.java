@@ -0,0 +1,73 @@
+import java.util.List;
+import java.util.stream.LongStream;
+
+import io.github.httprunner.funplugin.FunPlugin;
+
+/**
+ * DebugTalk is an example java plugin, run it as .java source file with the SDK jar in CLASSPATH,
+ * or package it into an executable .jar.
+ */
+public class DebugTalk {
+    static Object sum(Object... args) {
+        long intSum = 0;
+        double floatSum = 0;
+        boolean isFloat = false;
+        for (Object arg : args) {
+            if (arg instanceof Double) {
+                isFloat = true;
+                floatSum += (Double) arg;
+            } else {
+                intSum += ((Number) arg).longValue();
+            }
+        }
+        return isFloat ? (Object) (intSum + floatSum) : (Object) intSum;
+    }
+
+    static String concatenate(Object... args) {
+        StringBuilder result = new StringBuilder();
+        for (Object arg : args) {
+            result.append(arg);
+        }
+        return result.toString();
+    }
+
+    static String sleep(double seconds) throws InterruptedException {
+        // sleep is interrupted if host cancels the call
+        long deadline = System.nanoTime() + (long) (seconds * 1e9);
+        while (System.nanoTime() < deadline) {
+            if (io.grpc.Context.current().isCancelled()) {
+                throw new InterruptedException("sleep cancelled");
+            }
+            Thread.sleep(10);
+        }
+        return "slept " + seconds + "s";
+    }
+
+    public static void main(String[] args) throws Exception {
+        FunPlugin.register("sum", DebugTalk::sum);
+        FunPlugin.register("sum_ints", DebugTalk::sum);
+        FunPlugin.register("sum_two_int", a -> (Long) a[0] + (Long) a[1],
+                FunPlugin.withDoc("Return the sum of two integers."),
+                FunPlugin.withParamNames("a", "b"));
+        FunPlugin.register("div_mod", a -> List.of((Long) a[0] / (Long) a[1], (Long) a[0] % (Long) a[1]),
+                FunPlugin.withParamNames("a", "b"));
+        FunPlugin.register("sum_two_string", a -> (String) a[0] + a[1],
+                FunPlugin.withParamNames("a", "b"));
+        FunPlugin.register("sum_strings", DebugTalk::concatenate);
+        FunPlugin.register("concatenate", DebugTalk::concatenate);
+        FunPlugin.register("sleep", a -> sleep(((Number) a[0]).doubleValue()),
+                FunPlugin.withParamNames("seconds"));
+        FunPlugin.register("generate_ints", a -> LongStream.range(0, (Long) a[0]).boxed(),
+                FunPlugin.withDoc("Yield integers from 0 to n-1 one by one."),
+                FunPlugin.withParamNames("n"));
+        FunPlugin.register("echo", a -> a[0], FunPlugin.withParamNames("value"));
+        FunPlugin.register("greet", a -> (a[1] == null ? "Hello" : a[1]) + ", " + a[0] + "!",
+                FunPlugin.withParamNames("name", "greeting"));
+        FunPlugin.register("get_pid", a -> ProcessHandle.current().pid());
+        FunPlugin.register("setup_hook_example", a -> "setup_hook_example: " + a[0],
+                FunPlugin.withParamNames("name"));
+        FunPlugin.register("teardown_hook_example", a -> "teardown_hook_example: " + a[0],
+                FunPlugin.withParamNames("name"));
+        FunPlugin.serve();
+    }
+}
@@ -0,0 +1,296 @@
+package io.github.httprunner.funplugin;
+
+import java.util.Collections;
+import java.util.Iterator;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.ConcurrentHashMap;
+import java.util.concurrent.Executors;
+import java.util.concurrent.ScheduledExecutorService;
+import java.util.concurrent.TimeUnit;
+import java.util.concurrent.atomic.AtomicLong;
+import java.util.logging.Level;
+import java.util.logging.Logger;
+import java.util.stream.Stream;
+
+import com.google.protobuf.ByteString;
+import com.google.protobuf.InvalidProtocolBufferException;
+
+import io.grpc.CompressorRegistry;
+import io.grpc.Context;
+import io.grpc.Status;
+import io.grpc.stub.ServerCallStreamObserver;
+import io.grpc.stub.StreamObserver;
+
+import io.github.httprunner.funplugin.proto.CallRequest;
+import io.github.httprunner.funplugin.proto.CallResponse;
+import io.github.httprunner.funplugin.proto.Chunk;
+import io.github.httprunner.funplugin.proto.DebugTalkGrpc;
+import io.github.httprunner.funplugin.proto.DescribeRequest;
+import io.github.httprunner.funplugin.proto.DescribeResponse;
+import io.github.httprunner.funplugin.proto.Empty;
+import io.github.httprunner.funplugin.proto.FetchChunksRequest;
+import io.github.httprunner.funplugin.proto.GetNamesResponse;
+import io.github.httprunner.funplugin.proto.NegotiateRequest;
+import io.github.httprunner.funplugin.proto.NegotiateResponse;
+import io.github.httprunner.funplugin.proto.Parameter;
+
+/**
+ * DebugTalkService serves registered functions in typed values. Codecs are not supported,
+ * and ConnectHost is left unimplemented, so host functions can not be called back by java plugins.
+ */
+final class DebugTalkService extends DebugTalkGrpc.DebugTalkImplBase {
+    private static final Logger logger = Logger.getLogger(DebugTalkService.class.getName());
+
+    // CallResponse beyond max send size is kept until fetched in chunks, keep consistent with fungo
+    private static final int MAX_CHUNK_SIZE = 1 << 20;
+    private static final int CHUNK_OVERHEAD = 1 << 10; // reserved for fields of Chunk message
+    private static final long CHUNK_TIMEOUT_SECONDS = 60;
+
+    private final int maxSendSize;
+    private final String compression;
+    private final AtomicLong lastChunkID = new AtomicLong();
+    private final Map<String, byte[]> chunks = new ConcurrentHashMap<>();
+    private final ScheduledExecutorService chunkCleaner = Executors.newSingleThreadScheduledExecutor(r -> {
+        Thread thread = new Thread(r, "funplugin-chunk-cleaner");
+        thread.setDaemon(true);
+        return thread;
+    });
+
+    DebugTalkService(int maxSendSize, String compression) {
+        this.maxSendSize = maxSendSize;
+        this.compression = compression;
+    }
+
+    @Override
+    public void getNames(Empty request, StreamObserver<GetNamesResponse> observer) {
+        observer.onNext(GetNamesResponse.newBuilder().addAllNames(FunPlugin.functions.keySet()).build());
+        observer.onCompleted();
+    }
+
+    @Override
+    public void call(CallRequest request, StreamObserver<CallResponse> observer) {
+        logger.fine("Call() start: " + request.getName());
+        setCompression(observer);
+        try {
+            Object value = invoke(request, true);
+            CallResponse response = CallResponse.newBuilder().setTypedValue(encode(request, value)).build();
+            observer.onNext(deferOversized(request, response));
+            observer.onCompleted();
+        } catch (PluginException e) {
+            logger.log(Level.WARNING, "Call() failed: " + request.getName(), e);
+            observer.onError(e.toStatusException(Context.current().isCancelled()));
+        }
+    }
+
+    @Override
+    public void callStream(CallRequest request, StreamObserver<CallResponse> observer) {
+        logger.fine("CallStream() start: " + request.getName());
+        ServerCallStreamObserver<CallResponse> serverObserver = (ServerCallStreamObserver<CallResponse>) observer;
+        setCompression(observer);
+        try {
+            Object value = invoke(request, false);
+            Iterator<?> iterator;
+            if (value instanceof Iterator) {
+                iterator = (Iterator<?>) value;
+            } else if (value instanceof Stream) {
+                iterator = ((Stream<?>) value).iterator();
+            } else {
+                iterator = Collections.singletonList(value).iterator(); // single value
+            }
+            while (!serverObserver.isCancelled() && next(request, iterator)) {
+                Object item = iterator.next();
+                observer.onNext(CallResponse.newBuilder().setTypedValue(encode(request, item)).build());
+            }
+            if (value instanceof Stream) {
+                ((Stream<?>) value).close();
+            }
+            observer.onCompleted();
+        } catch (PluginException e) {
+            logger.log(Level.WARNING, "CallStream() failed: " + request.getName(), e);
+            observer.onError(e.toStatusException(Context.current().isCancelled()));
+        }
+    }
+
+    @Override
+    public void describe(DescribeRequest request, StreamObserver<DescribeResponse> observer) {
+        FunPlugin.FunctionInfo info = FunPlugin.functions.get(request.getName());
+        if (info == null) {
+            observer.onError(new PluginException(PluginException.KIND_FUNC_NOT_FOUND, request.getName(),
+                    "Function " + request.getName() + " not registered!").toStatusException(false));
+            return;
+        }
+        DescribeResponse.Builder response = DescribeResponse.newBuilder()
+                .setName(info.name)
+                .setDoc(info.doc)
+                .setVariadic(info.variadic())
+                .addReturns("Object");
+        if (info.variadic()) {
+            response.addParams(Parameter.newBuilder().setName("args").setType("Object...").setOptional(true));
+        }
+        for (String name : info.paramNames) {
+            response.addParams(Parameter.newBuilder().setName(name).setType("Object"));
+        }
+        observer.onNext(response.build());
+        observer.onCompleted();
+    }
+
+    @Override
+    public void negotiate(NegotiateRequest request, StreamObserver<NegotiateResponse> observer) {
+        // no codec is supported, host falls back to typed values
+        observer.onNext(NegotiateResponse.getDefaultInstance());
+        observer.onCompleted();
+    }
+
+    /** CallChunked receives oversized CallRequest in chunks and calls function. */
+    @Override
+    public StreamObserver<Chunk> callChunked(StreamObserver<CallResponse> observer) {
+        return new StreamObserver<Chunk>() {
+            private ByteString data = ByteString.EMPTY;
+
+            @Override
+            public void onNext(Chunk chunk) {
+                data = data.concat(chunk.getData());
+            }
+
+            @Override
+            public void onError(Throwable t) {
+                logger.log(Level.WARNING, "CallChunked() failed", t);
+            }
+
+            @Override
+            public void onCompleted() {
+                CallRequest request;
+                try {
+                    request = CallRequest.parseFrom(data);
+                } catch (InvalidProtocolBufferException e) {
+                    observer.onError(Status.INVALID_ARGUMENT
+                            .withDescription("unmarshal CallRequest failed: " + e.getMessage())
+                            .asRuntimeException());
+                    return;
+                }
+                call(request, observer);
+            }
+        };
+    }
+
+    /** FetchChunks sends oversized CallResponse kept by Call in chunks. */
+    @Override
+    public void fetchChunks(FetchChunksRequest request, StreamObserver<Chunk> observer) {
+        byte[] data = chunks.remove(request.getChunkId());
+        if (data == null) {
+            observer.onError(Status.NOT_FOUND
+                    .withDescription("chunks " + request.getChunkId() + " not found or expired")
+                    .asRuntimeException());
+            return;
+        }
+        int size = Math.min(MAX_CHUNK_SIZE, maxSendSize - CHUNK_OVERHEAD);
+        for (int offset = 0; offset < data.length; offset += size) {
+            int length = Math.min(size, data.length - offset);
+            observer.onNext(Chunk.newBuilder().setData(ByteString.copyFrom(data, offset, length)).build());
+        }
+        observer.onCompleted();
+    }
+
+    /** Invokes registered function with arguments of request. */
+    private Object invoke(CallRequest request, boolean acceptKwargs) throws PluginException {
+        String name = request.getName();
+        FunPlugin.FunctionInfo info = FunPlugin.functions.get(name);
+        if (info == null) {
+            throw new PluginException(PluginException.KIND_FUNC_NOT_FOUND, name,
+                    "Function " + name + " not registered!");
+        }
+        if (!request.hasTypedArgs() && !request.getArgs().isEmpty()) {
+            throw new PluginException(PluginException.KIND_ARG_MISMATCH, name,
+                    "arguments are not encoded in typed values");
+        }
+        Map<String, Object> kwargs = Values.fromValues(request.getTypedKwargs());
+        if (!acceptKwargs && !kwargs.isEmpty()) {
+            throw new PluginException(PluginException.KIND_ARG_MISMATCH, name,
+                    "keyword arguments not supported by stream");
+        }
+        Object[] args = bind(info, Values.fromValues(request.getTypedArgs()), kwargs);
+        try {
+            return info.function.call(args);
+        } catch (Throwable t) {
+            throw PluginException.fromThrowable(name, t);
+        }
+    }
+
+    /** Binds positional and keyword arguments to parameters registered by FunPlugin.withParamNames. */
+    static Object[] bind(FunPlugin.FunctionInfo info, List<Object> args, Map<String, Object> kwargs)
+            throws PluginException {
+        if (info.variadic()) {
+            if (!kwargs.isEmpty()) {
+                throw argMismatch(info, "keyword arguments not accepted, parameter names not registered");
+            }
+            return args.toArray();
+        }
+
+        List<String> names = info.paramNames;
+        if (args.size() > names.size() || kwargs.isEmpty() && args.size() < names.size()) {
+            throw argMismatch(info, String.format("function expect %d arguments, but got %d",
+                    names.size(), args.size()));
+        }
+        Object[] bound = new Object[names.size()];
+        boolean[] set = new boolean[names.size()];
+        for (int i = 0; i < args.size(); i++) {
+            bound[i] = args.get(i);
+            set[i] = true;
+        }
+        for (Map.Entry<String, Object> entry : kwargs.entrySet()) {
+            int index = names.indexOf(entry.getKey());
+            if (index < 0) {
+                throw argMismatch(info, "unexpected keyword argument " + entry.getKey());
+            }
+            if (set[index]) {
+                throw argMismatch(info, "got multiple values for argument " + entry.getKey());
+            }
+            bound[index] = entry.getValue();
+            set[index] = true;
+        }
+        return bound; // missing arguments are null like zero values in fungo
+    }
+
+    private static PluginException argMismatch(FunPlugin.FunctionInfo info, String message) {
+        return new PluginException(PluginException.KIND_ARG_MISMATCH, info.name, message);
+    }
+
+    private static boolean next(CallRequest request, Iterator<?> iterator) throws PluginException {
+        try {
+            return iterator.hasNext();
+        } catch (Throwable t) {
+            throw PluginException.fromThrowable(request.getName(), t);
+        }
+    }
+
+    private static io.github.httprunner.funplugin.proto.Value encode(CallRequest request, Object value)
+            throws PluginException {
+        try {
+            return Values.toValue(value);
+        } catch (IllegalArgumentException e) {
+            throw new PluginException(PluginException.KIND_USER, request.getName(),
+                    "encode return value failed: " + e.getMessage());
+        }
+    }
+
+    /** Keeps oversized response to be fetched by FetchChunks if host accepts chunks. */
+    private CallResponse deferOversized(CallRequest request, CallResponse response) {
+        if (!request.getAcceptChunks() || response.getSerializedSize() <= maxSendSize) {
+            return response;
+        }
+        String chunkID = String.valueOf(lastChunkID.incrementAndGet());
+        chunks.put(chunkID, response.toByteArray());
+        // drop response not fetched in time, e.g. host call cancelled
+        chunkCleaner.schedule(() -> chunks.remove(chunkID), CHUNK_TIMEOUT_SECONDS, TimeUnit.SECONDS);
+        logger.fine("Call() response oversized, chunk id: " + chunkID);
+        return CallResponse.newBuilder().setChunkId(chunkID).build();
+    }
+
+    /** Replies in compressor specified by host, e.g. gzip. */
+    private void setCompression(StreamObserver<?> observer) {
+        if (!compression.isEmpty() && CompressorRegistry.getDefaultInstance().lookupCompressor(compression) != null) {
+            ((ServerCallStreamObserver<?>) observer).setCompression(compression);
+        }
+    }
+}
@@ -0,0 +1,140 @@
+package io.github.httprunner.funplugin;
+
+import java.io.IOException;
+import java.io.PrintStream;
+import java.net.InetSocketAddress;
+import java.util.Arrays;
+import java.util.Collections;
+import java.util.List;
+import java.util.Map;
+import java.util.concurrent.ConcurrentHashMap;
+
+import io.grpc.Server;
+import io.grpc.health.v1.HealthCheckResponse.ServingStatus;
+import io.grpc.netty.shaded.io.grpc.netty.NettyServerBuilder;
+import io.grpc.protobuf.services.HealthStatusManager;
+
+/**
+ * FunPlugin registers java functions and serves them to HttpRunner over gRPC,
+ * it implements the DebugTalk service and the go-plugin handshake like fungo and funppy.
+ *
+ * <pre>{@code
+ * FunPlugin.register("sum_two_int", args -> (Long) args[0] + (Long) args[1],
+ *         FunPlugin.withParamNames("a", "b"));
+ * FunPlugin.serve();
+ * }</pre>
+ */
+public final class FunPlugin {
+    // plugin protocol versions negotiated with host, keep consistent with fungo
+    static final int PROTOCOL_VERSION_JSON = 1; // values are encoded in JSON
+    static final int PROTOCOL_VERSION_TYPED = 2; // values are encoded in typed values
+
+    // environment variables specified by host, keep consistent with fungo
+    static final String MAGIC_COOKIE_KEY = "HttpRunnerPlus";
+    static final String MAGIC_COOKIE_VALUE = "debugtalk";
+    static final String MAX_SEND_SIZE_ENV_NAME = "HRP_PLUGIN_MAX_SEND_SIZE";
+    static final String MAX_RECV_SIZE_ENV_NAME = "HRP_PLUGIN_MAX_RECV_SIZE";
+    static final String COMPRESSION_ENV_NAME = "HRP_PLUGIN_COMPRESSION";
+    static final int DEFAULT_MAX_MESSAGE_SIZE = 4 << 20;
+
+    static final Map<String, FunctionInfo> functions = new ConcurrentHashMap<>();
+
+    private FunPlugin() {
+    }
+
+    /** FunctionOption specifies documentation or parameter names of registered function. */
+    @FunctionalInterface
+    public interface FunctionOption {
+        void apply(FunctionInfo info);
+    }
+
+    /** FunctionInfo is registered function with its options. */
+    public static final class FunctionInfo {
+        final String name;
+        final PluginFunction function;
+        String doc = "";
+        List<String> paramNames = Collections.emptyList();
+
+        FunctionInfo(String name, PluginFunction function) {
+            this.name = name;
+            this.function = function;
+        }
+
+        /** Functions accept any number of arguments unless parameter names are registered. */
+        boolean variadic() {
+            return paramNames.isEmpty();
+        }
+    }
+
+    /** WithDoc specifies documentation of function, it is returned by Describe. */
+    public static FunctionOption withDoc(String doc) {
+        return info -> info.doc = doc;
+    }
+
+    /**
+     * WithParamNames specifies parameter names of function, the number of arguments is checked
+     * and keyword arguments of CallKw are bound to parameters by names.
+     */
+    public static FunctionOption withParamNames(String... names) {
+        return info -> info.paramNames = Arrays.asList(names);
+    }
+
+    /** Register registers function with name, it overrides function registered with the same name. */
+    public static void register(String name, PluginFunction function, FunctionOption... options) {
+        FunctionInfo info = new FunctionInfo(name, function);
+        for (FunctionOption option : options) {
+            option.apply(info);
+        }
+        functions.put(name, info);
+    }
+
+    /** Serve starts plugin server and blocks until plugin process is killed by host. */
+    public static void serve() throws IOException, InterruptedException {
+        if (!MAGIC_COOKIE_VALUE.equals(System.getenv(MAGIC_COOKIE_KEY))) {
+            System.err.println("This binary is a plugin. These are not meant to be executed directly.\n"
+                    + "Please execute the program that consumes these plugins, which will\n"
+                    + "load any plugins automatically");
+            System.exit(1);
+        }
+        // typed values are required, java functions can not tell integers from floats in JSON
+        List<String> versions = Arrays.asList(System.getenv()
+                .getOrDefault("PLUGIN_PROTOCOL_VERSIONS", "").split(","));
+        if (!versions.contains(String.valueOf(PROTOCOL_VERSION_TYPED))) {
+            System.err.println("java plugin requires host built with funplugin v0.6.0 or later");
+            System.exit(1);
+        }
+
+        int maxRecvSize = envInt(MAX_RECV_SIZE_ENV_NAME, DEFAULT_MAX_MESSAGE_SIZE);
+        DebugTalkService service = new DebugTalkService(
+                envInt(MAX_SEND_SIZE_ENV_NAME, DEFAULT_MAX_MESSAGE_SIZE),
+                System.getenv().getOrDefault(COMPRESSION_ENV_NAME, ""));
+        // health service is probed by host to check if plugin is alive
+        HealthStatusManager health = new HealthStatusManager();
+        health.setStatus("plugin", ServingStatus.SERVING);
+
+        Server server = NettyServerBuilder.forAddress(new InetSocketAddress("127.0.0.1", 0))
+                .maxInboundMessageSize(maxRecvSize)
+                .addService(service)
+                .addService(health.getHealthService())
+                .build()
+                .start();
+        Runtime.getRuntime().addShutdownHook(new Thread(server::shutdownNow));
+
+        // stdout is reserved for handshake, go-plugin reads plugin address from the first line
+        PrintStream stdout = System.out;
+        System.setOut(System.err);
+        stdout.printf("1|%d|tcp|127.0.0.1:%d|grpc%n", PROTOCOL_VERSION_TYPED, server.getPort());
+        stdout.flush();
+
+        server.awaitTermination();
+    }
+
+    static int envInt(String name, int defaultValue) {
+        try {
+            int value = Integer.parseInt(System.getenv().getOrDefault(name, ""));
+            return value > 0 ? value : defaultValue;
+        } catch (NumberFormatException e) {
+            return defaultValue;
+        }
+    }
+}
@@ -0,0 +1,74 @@
+package io.github.httprunner.funplugin;
+
+import java.io.PrintWriter;
+import java.io.StringWriter;
+
+import com.google.protobuf.Any;
+import com.google.rpc.Code;
+import com.google.rpc.Status;
+
+import io.grpc.StatusRuntimeException;
+import io.grpc.protobuf.StatusProto;
+
+import io.github.httprunner.funplugin.proto.PluginError;
+
+/** PluginException is returned to host as PluginError attached to gRPC status details. */
+final class PluginException extends Exception {
+    // PluginError kinds, keep consistent with fungo.ErrorKind
+    static final String KIND_FUNC_NOT_FOUND = "function_not_found";
+    static final String KIND_ARG_MISMATCH = "argument_mismatch";
+    static final String KIND_USER = "user_error";
+    static final String KIND_PANIC = "panic";
+
+    final String kind;
+    final String funcName;
+    final String stack;
+
+    PluginException(String kind, String funcName, String message) {
+        this(kind, funcName, message, "");
+    }
+
+    PluginException(String kind, String funcName, String message, String stack) {
+        super(message);
+        this.kind = kind;
+        this.funcName = funcName;
+        this.stack = stack;
+    }
+
+    /** Wraps exception thrown by plugin function, errors like StackOverflowError are regarded as panic. */
+    static PluginException fromThrowable(String funcName, Throwable t) {
+        StringWriter stack = new StringWriter();
+        t.printStackTrace(new PrintWriter(stack));
+        String kind = t instanceof Exception ? KIND_USER : KIND_PANIC;
+        return new PluginException(kind, funcName, String.valueOf(t.getMessage()), stack.toString());
+    }
+
+    StatusRuntimeException toStatusException(boolean cancelled) {
+        Code code;
+        switch (kind) {
+            case KIND_FUNC_NOT_FOUND:
+                code = Code.NOT_FOUND;
+                break;
+            case KIND_ARG_MISMATCH:
+                code = Code.INVALID_ARGUMENT;
+                break;
+            case KIND_PANIC:
+                code = Code.INTERNAL;
+                break;
+            default:
+                // function failed because host cancelled the call or deadline exceeded
+                code = cancelled ? Code.DEADLINE_EXCEEDED : Code.UNKNOWN;
+        }
+        PluginError detail = PluginError.newBuilder()
+                .setKind(kind)
+                .setFuncName(funcName)
+                .setMessage(getMessage())
+                .setStack(stack)
+                .build();
+        return StatusProto.toStatusRuntimeException(Status.newBuilder()
+                .setCode(code.getNumber())
+                .setMessage(getMessage())
+                .addDetails(Any.pack(detail))
+                .build());
+    }
+}
@@ -0,0 +1,15 @@
+package io.github.httprunner.funplugin;
+
+/**
+ * PluginFunction is a function registered by {@link FunPlugin#register} and called by host.
+ *
+ * <p>Arguments are decoded from typed values: null, {@link Boolean}, {@link Long},
+ * {@link java.math.BigInteger}, {@link Double}, {@link java.math.BigDecimal}, {@link String},
+ * {@code byte[]}, {@link java.time.Instant}, {@link java.util.List} and {@link java.util.Map}.
+ * Return a {@link java.util.Iterator} or {@link java.util.stream.Stream} to push values to host
+ * {@code CallStream} one at a time. Exceptions are returned to host as user errors.
+ */
+@FunctionalInterface
+public interface PluginFunction {
+    Object call(Object... args) throws Exception;
+}
@@ -0,0 +1,144 @@
+package io.github.httprunner.funplugin;
+
+import java.lang.reflect.Array;
+import java.math.BigDecimal;
+import java.math.BigInteger;
+import java.time.Instant;
+import java.time.LocalDateTime;
+import java.time.OffsetDateTime;
+import java.time.ZoneOffset;
+import java.time.ZonedDateTime;
+import java.util.ArrayList;
+import java.util.Date;
+import java.util.LinkedHashMap;
+import java.util.List;
+import java.util.Map;
+
+import com.google.protobuf.ByteString;
+import com.google.protobuf.Timestamp;
+
+import io.github.httprunner.funplugin.proto.Empty;
+import io.github.httprunner.funplugin.proto.Value;
+import io.github.httprunner.funplugin.proto.ValueList;
+import io.github.httprunner.funplugin.proto.ValueMap;
+
+/** Values converts java values from and to typed values, keep consistent with fungo and funppy. */
+final class Values {
+    private static final BigInteger UINT64_MAX = BigInteger.ONE.shiftLeft(64).subtract(BigInteger.ONE);
+
+    private Values() {
+    }
+
+    /** Encodes java value to typed value, throws IllegalArgumentException if not supported. */
+    static Value toValue(Object value) {
+        Value.Builder builder = Value.newBuilder();
+        if (value == null) {
+            builder.setNullValue(Empty.getDefaultInstance());
+        } else if (value instanceof Boolean) {
+            builder.setBoolValue((Boolean) value);
+        } else if (value instanceof Byte || value instanceof Short
+                || value instanceof Integer || value instanceof Long) {
+            builder.setIntValue(((Number) value).longValue());
+        } else if (value instanceof BigInteger) {
+            BigInteger i = (BigInteger) value;
+            if (i.bitLength() < 64) {
+                builder.setIntValue(i.longValue());
+            } else if (i.signum() > 0 && i.compareTo(UINT64_MAX) <= 0) {
+                builder.setUintValue(i.longValue()); // unsigned bits of uint64
+            } else {
+                builder.setBigIntValue(i.toString());
+            }
+        } else if (value instanceof Float || value instanceof Double) {
+            builder.setFloatValue(((Number) value).doubleValue());
+        } else if (value instanceof BigDecimal) {
+            builder.setDecimalValue(((BigDecimal) value).toString());
+        } else if (value instanceof CharSequence || value instanceof Character) {
+            builder.setStringValue(value.toString());
+        } else if (value instanceof Enum) {
+            builder.setStringValue(((Enum<?>) value).name());
+        } else if (value instanceof byte[]) {
+            builder.setBytesValue(ByteString.copyFrom((byte[]) value));
+        } else if (value instanceof Instant) {
+            Instant t = (Instant) value;
+            builder.setTimeValue(Timestamp.newBuilder()
+                    .setSeconds(t.getEpochSecond()).setNanos(t.getNano()));
+        } else if (value instanceof Date) {
+            return toValue(((Date) value).toInstant());
+        } else if (value instanceof OffsetDateTime) {
+            return toValue(((OffsetDateTime) value).toInstant());
+        } else if (value instanceof ZonedDateTime) {
+            return toValue(((ZonedDateTime) value).toInstant());
+        } else if (value instanceof LocalDateTime) {
+            // local date time is regarded as UTC like naive datetime in funppy
+            return toValue(((LocalDateTime) value).toInstant(ZoneOffset.UTC));
+        } else if (value instanceof Iterable) {
+            ValueList.Builder list = ValueList.newBuilder();
+            for (Object item : (Iterable<?>) value) {
+                list.addValues(toValue(item));
+            }
+            builder.setListValue(list);
+        } else if (value.getClass().isArray()) {
+            ValueList.Builder list = ValueList.newBuilder();
+            for (int i = 0; i < Array.getLength(value); i++) {
+                list.addValues(toValue(Array.get(value, i)));
+            }
+            builder.setListValue(list);
+        } else if (value instanceof Map) {
+            ValueMap.Builder map = ValueMap.newBuilder();
+            for (Map.Entry<?, ?> entry : ((Map<?, ?>) value).entrySet()) {
+                map.putFields(String.valueOf(entry.getKey()), toValue(entry.getValue()));
+            }
+            builder.setMapValue(map);
+        } else {
+            throw new IllegalArgumentException("type " + value.getClass().getName() + " not supported");
+        }
+        return builder.build();
+    }
+
+    /** Decodes typed value to java value. */
+    static Object fromValue(Value value) {
+        switch (value.getKindCase()) {
+            case BOOL_VALUE:
+                return value.getBoolValue();
+            case INT_VALUE:
+                return value.getIntValue();
+            case UINT_VALUE:
+                return new BigInteger(Long.toUnsignedString(value.getUintValue()));
+            case FLOAT_VALUE:
+                return value.getFloatValue();
+            case STRING_VALUE:
+                return value.getStringValue();
+            case BYTES_VALUE:
+                return value.getBytesValue().toByteArray();
+            case TIME_VALUE:
+                Timestamp t = value.getTimeValue();
+                return Instant.ofEpochSecond(t.getSeconds(), t.getNanos());
+            case BIG_INT_VALUE:
+                return new BigInteger(value.getBigIntValue());
+            case DECIMAL_VALUE:
+                return new BigDecimal(value.getDecimalValue());
+            case LIST_VALUE:
+                return fromValues(value.getListValue());
+            case MAP_VALUE:
+                return fromValues(value.getMapValue());
+            default: // null value or not set
+                return null;
+        }
+    }
+
+    static List<Object> fromValues(ValueList values) {
+        List<Object> list = new ArrayList<>(values.getValuesCount());
+        for (Value item : values.getValuesList()) {
+            list.add(fromValue(item));
+        }
+        return list;
+    }
+
+    static Map<String, Object> fromValues(ValueMap values) {
+        Map<String, Object> map = new LinkedHashMap<>();
+        for (Map.Entry<String, Value> entry : values.getFieldsMap().entrySet()) {
+            map.put(entry.getKey(), fromValue(entry.getValue()));
+        }
+        return map;
+    }
+}
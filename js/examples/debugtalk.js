@@ -0,0 +1,50 @@
+// debugtalk functions run in embedded javascript engine, no node or funnode is required.
+// Functions exported by module.exports are plugin functions.
+
+function sum(...args) {
+  return args.reduce((result, arg) => result + arg, 0);
+}
+
+function concatenate(...args) {
+  return args.map(String).join("");
+}
+
+function sumTwoInt(a, b) {
+  return a + b;
+}
+// parameter names to bind keyword arguments, and doc shown in function signature
+sumTwoInt.params = ["a", "b"];
+sumTwoInt.doc = "Return the sum of two integers.";
+
+function* generateInts(n) {
+  for (let i = 0; i < n; i++) {
+    yield i;
+  }
+}
+
+async function greet(name, greeting = "Hello") {
+  return `${greeting}, ${name}!`;
+}
+
+function raiseError() {
+  throw new Error("something wrong");
+}
+
+function loop() {
+  for (;;) {}
+}
+
+module.exports = {
+  sum,
+  sum_ints: sum,
+  sum_two_int: sumTwoInt,
+  sum_two_string: (a, b) => a + b,
+  sum_strings: concatenate,
+  concatenate,
+  generate_ints: generateInts,
+  greet,
+  raise_error: raiseError,
+  loop,
+  setup_hook_example: (name) => `setup_hook_example: ${name}`,
+  teardown_hook_example: (name) => `teardown_hook_example: ${name}`,
+};
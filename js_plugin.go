@@ -0,0 +1,444 @@
+package funplugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/dop251/goja"
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+
+	"github.com/httprunner/funplugin/fungo"
+)
+
+var (
+	contextType   = reflect.TypeOf((*context.Context)(nil)).Elem()
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// embedsJS checks if plugin is .js file to be run in embedded javascript engine,
+// it is enabled by WithEmbeddedJS explicitly since funnode plugin is .js file as well.
+func (o *pluginOption) embedsJS(path string) bool {
+	return o.embeddedJS && filepath.Ext(path) == ".js"
+}
+
+// jsFunction is javascript function exported by plugin
+type jsFunction struct {
+	fn         reflect.Value // go function calling javascript function, see wrap
+	paramNames []string      // parameter names declared by params property of function
+	doc        string        // doc declared by doc property of function
+}
+
+// jsIterator is iterator returned by javascript function, e.g. generator
+type jsIterator struct {
+	object *goja.Object
+}
+
+// jsPlugin implements plugin running .js file in embedded javascript engine goja,
+// functions exported by module.exports, or top-level functions if nothing exported, are plugin functions.
+// Arguments and results are converted with the same rules as fungo.CallFunc.
+type jsPlugin struct {
+	path      string // plugin file path
+	option    *pluginOption
+	logger    hclog.Logger
+	mu        sync.Mutex // javascript runtime is not goroutine-safe
+	vm        *goja.Runtime
+	functions map[string]*jsFunction
+}
+
+func newJSPlugin(path string, option *pluginOption) (*jsPlugin, error) {
+	p := &jsPlugin{
+		path:      path,
+		option:    option,
+		logger:    logger.ResetNamed("js-plugin"),
+		vm:        goja.New(),
+		functions: make(map[string]*jsFunction),
+	}
+	if len(option.hostFunctions) > 0 {
+		p.logger.Warn("host functions are not supported by embedded javascript plugin")
+	}
+	if err := p.load(); err != nil {
+		p.logger.Error("load embedded javascript plugin failed", "path", path, "error", err)
+		return nil, err
+	}
+	p.logger.Info("load embedded javascript plugin success", "path", path)
+	return p, nil
+}
+
+// load runs plugin script as CommonJS module and collects its functions
+func (p *jsPlugin) load() error {
+	content, err := os.ReadFile(p.path)
+	if err != nil {
+		return errors.Wrap(err, "read javascript plugin failed")
+	}
+	program, err := goja.Compile(p.path, string(content), false)
+	if err != nil {
+		return errors.Wrap(err, "compile javascript plugin failed")
+	}
+
+	module := p.vm.NewObject()
+	exports := p.vm.NewObject()
+	if err := module.Set("exports", exports); err != nil {
+		return err
+	}
+	p.vm.Set("module", module)
+	p.vm.Set("exports", exports)
+	p.vm.Set("console", p.console())
+	if _, err := p.vm.RunProgram(program); err != nil {
+		return errors.Wrap(err, "run javascript plugin failed")
+	}
+
+	// functions exported by module.exports, or top-level functions if nothing exported
+	p.collect(module.Get("exports").ToObject(p.vm))
+	if len(p.functions) == 0 {
+		p.collect(p.vm.GlobalObject())
+	}
+	return nil
+}
+
+func (p *jsPlugin) collect(object *goja.Object) {
+	for _, name := range object.Keys() {
+		value := object.Get(name)
+		callable, ok := goja.AssertFunction(value)
+		if !ok {
+			continue
+		}
+		function := value.ToObject(p.vm)
+		f := &jsFunction{}
+		if params := function.Get("params"); params != nil && !goja.IsUndefined(params) {
+			p.vm.ExportTo(params, &f.paramNames)
+		}
+		if doc := function.Get("doc"); doc != nil && !goja.IsUndefined(doc) {
+			f.doc = doc.String()
+		}
+		numParams := len(f.paramNames)
+		if f.paramNames == nil {
+			numParams = int(function.Get("length").ToInteger())
+		}
+		f.fn = p.wrap(callable, numParams)
+		p.functions[name] = f
+	}
+}
+
+// wrap returns go function calling javascript function, which is declared as
+// func(ctx context.Context, arg0, ..., argN interface{}, args ...interface{}) (interface{}, error)
+// thus at least numParams arguments are required, and extra arguments are accepted like javascript.
+func (p *jsPlugin) wrap(callable goja.Callable, numParams int) reflect.Value {
+	in := []reflect.Type{contextType}
+	for i := 0; i < numParams; i++ {
+		in = append(in, interfaceType)
+	}
+	in = append(in, reflect.SliceOf(interfaceType))
+	fnType := reflect.FuncOf(in, []reflect.Type{interfaceType, errorType}, true)
+
+	return reflect.MakeFunc(fnType, func(values []reflect.Value) []reflect.Value {
+		ctx := values[0].Interface().(context.Context)
+		var args []interface{}
+		for _, value := range values[1 : len(values)-1] {
+			args = append(args, value.Interface())
+		}
+		args = append(args, values[len(values)-1].Interface().([]interface{})...)
+
+		var result interface{}
+		value, err := p.call(ctx, callable, goja.Undefined(), args...)
+		if err == nil {
+			result, err = p.export(value)
+		}
+		errValue := reflect.Zero(errorType)
+		if err != nil {
+			errValue = reflect.ValueOf(&err).Elem()
+		}
+		return []reflect.Value{reflect.ValueOf(&result).Elem(), errValue}
+	})
+}
+
+// call calls javascript function, which is interrupted if ctx is done
+func (p *jsPlugin) call(ctx context.Context, callable goja.Callable, this goja.Value, args ...interface{}) (goja.Value, error) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			p.vm.Interrupt(ctx.Err())
+		case <-done:
+		}
+	}()
+	defer func() {
+		close(done)
+		<-stopped
+		p.vm.ClearInterrupt()
+	}()
+
+	jsArgs := make([]goja.Value, len(args))
+	for i, arg := range args {
+		jsArgs[i] = p.vm.ToValue(arg)
+	}
+	value, err := callable(this, jsArgs...)
+	if err != nil {
+		return nil, p.jsError(ctx, err)
+	}
+	return value, nil
+}
+
+// export converts javascript value to go value, settled promise is resolved
+// and iterator is returned as *jsIterator to be consumed by caller.
+func (p *jsPlugin) export(value goja.Value) (interface{}, error) {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil, nil
+	}
+	if promise, ok := value.Export().(*goja.Promise); ok {
+		switch promise.State() {
+		case goja.PromiseStateFulfilled:
+			return p.export(promise.Result())
+		case goja.PromiseStateRejected:
+			return nil, &PluginError{Kind: ErrKindUser, Message: errorMessage(promise.Result())}
+		default:
+			return nil, errors.New("promise is pending, asynchronous operations are not supported")
+		}
+	}
+	if object, ok := value.(*goja.Object); ok {
+		_, isFunc := goja.AssertFunction(object.Get("next"))
+		if iterator := object.GetSymbol(goja.SymIterator); isFunc && iterator != nil && !goja.IsUndefined(iterator) {
+			return &jsIterator{object: object}, nil
+		}
+	}
+	return value.Export(), nil
+}
+
+// jsError converts javascript exception to *PluginError, or returns ctx error if interrupted
+func (p *jsPlugin) jsError(ctx context.Context, err error) error {
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	var exception *goja.Exception
+	if errors.As(err, &exception) {
+		return &PluginError{Kind: ErrKindUser, Message: errorMessage(exception.Value()), Stack: exception.String()}
+	}
+	return err
+}
+
+// errorMessage returns message of thrown javascript error, or the thrown value as string
+func errorMessage(value goja.Value) string {
+	if object, ok := value.(*goja.Object); ok {
+		if message := object.Get("message"); message != nil && !goja.IsUndefined(message) {
+			return message.String()
+		}
+	}
+	return value.String()
+}
+
+// next returns next value of iterator, io.EOF is returned when iterator is done
+func (p *jsPlugin) next(ctx context.Context, iterator *jsIterator) (interface{}, error) {
+	next, _ := goja.AssertFunction(iterator.object.Get("next"))
+	result, err := p.call(ctx, next, iterator.object)
+	if err != nil {
+		return nil, err
+	}
+	object := result.ToObject(p.vm)
+	if object.Get("done").ToBoolean() {
+		return nil, io.EOF
+	}
+	return p.export(object.Get("value"))
+}
+
+func (p *jsPlugin) Type() string {
+	return "js-plugin"
+}
+
+func (p *jsPlugin) Path() string {
+	return p.path
+}
+
+func (p *jsPlugin) Has(funcName string) bool {
+	_, ok := p.functions[funcName]
+	return ok
+}
+
+func (p *jsPlugin) Call(funcName string, args ...interface{}) (interface{}, error) {
+	return p.CallKwContext(context.Background(), funcName, args, nil)
+}
+
+func (p *jsPlugin) CallContext(ctx context.Context, funcName string, args ...interface{}) (interface{}, error) {
+	return p.CallKwContext(ctx, funcName, args, nil)
+}
+
+func (p *jsPlugin) CallKw(funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	return p.CallKwContext(context.Background(), funcName, args, kwargs)
+}
+
+// CallKwContext calls function with keyword arguments, which are bound to parameter names declared
+// by params property of function, e.g. sum_two_int.params = ["a", "b"]. Values yielded by iterator
+// are returned as list. The function is interrupted when ctx is done or call timeout is exceeded.
+func (p *jsPlugin) CallKwContext(ctx context.Context, funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	timeout := p.option.getCallTimeout(funcName)
+	if timeout <= 0 {
+		return p.invoke(ctx, funcName, args, kwargs)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := p.invoke(callCtx, funcName, args, kwargs)
+	if err == nil || ctx.Err() != nil || !isDeadlineExceeded(callCtx, err) {
+		return result, err
+	}
+	return nil, &CallTimeoutError{FuncName: funcName, Timeout: timeout}
+}
+
+func (p *jsPlugin) invoke(ctx context.Context, funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	f, ok := p.functions[funcName]
+	if !ok {
+		return nil, &PluginError{
+			Kind:     ErrKindFuncNotFound,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function %s not found", funcName),
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result, err := fungo.CallFuncKw(ctx, f.fn, f.paramNames, args, kwargs)
+	if err != nil {
+		return nil, p.pluginError(ctx, funcName, err)
+	}
+	iterator, ok := result.(*jsIterator)
+	if !ok {
+		return result, nil
+	}
+	values := []interface{}{}
+	for {
+		value, err := p.next(ctx, iterator)
+		if err == io.EOF {
+			return values, nil
+		} else if err != nil {
+			return nil, p.pluginError(ctx, funcName, err)
+		}
+		values = append(values, value)
+	}
+}
+
+// pluginError returns ctx error if function is interrupted, otherwise *PluginError
+func (p *jsPlugin) pluginError(ctx context.Context, funcName string, err error) error {
+	if ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+		return err
+	}
+	return fungo.AsPluginError(funcName, err)
+}
+
+// CallStream calls function and receives values yielded by iterator one at a time,
+// e.g. generator function, otherwise the stream yields the single result.
+func (p *jsPlugin) CallStream(ctx context.Context, funcName string, args ...interface{}) (fungo.Stream, error) {
+	f, ok := p.functions[funcName]
+	if !ok {
+		return nil, &PluginError{
+			Kind:     ErrKindFuncNotFound,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function %s not found", funcName),
+		}
+	}
+
+	p.mu.Lock()
+	result, err := fungo.CallFuncContext(ctx, f.fn, args...)
+	if err != nil {
+		p.mu.Unlock()
+		return nil, p.pluginError(ctx, funcName, err)
+	}
+	iterator, ok := result.(*jsIterator)
+	if !ok {
+		p.mu.Unlock()
+		return fungo.NewValueStream(result), nil
+	}
+	return &jsStream{plugin: p, ctx: ctx, funcName: funcName, iterator: iterator}, nil
+}
+
+// jsStream receives values from javascript iterator, plugin is locked until stream ends or is closed
+type jsStream struct {
+	plugin   *jsPlugin
+	ctx      context.Context
+	funcName string
+	iterator *jsIterator
+	done     bool
+	once     sync.Once
+}
+
+func (s *jsStream) Recv() (interface{}, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+	value, err := s.plugin.next(s.ctx, s.iterator)
+	if err == io.EOF {
+		s.finish()
+		return nil, io.EOF
+	} else if err != nil {
+		s.finish()
+		return nil, s.plugin.pluginError(s.ctx, s.funcName, err)
+	}
+	return value, nil
+}
+
+// Close calls return method of iterator if it is not done, e.g. to run finally block of generator
+func (s *jsStream) Close() error {
+	if !s.done {
+		if ret, ok := goja.AssertFunction(s.iterator.object.Get("return")); ok {
+			ret(s.iterator.object)
+		}
+	}
+	s.finish()
+	return nil
+}
+
+func (s *jsStream) finish() {
+	s.done = true
+	s.once.Do(s.plugin.mu.Unlock)
+}
+
+func (p *jsPlugin) Describe(funcName string) (*fungo.FuncSignature, error) {
+	f, ok := p.functions[funcName]
+	if !ok {
+		return nil, &PluginError{
+			Kind:     ErrKindFuncNotFound,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function %s not found", funcName),
+		}
+	}
+	return fungo.DescribeFunc(funcName, f.fn, f.paramNames, f.doc), nil
+}
+
+func (p *jsPlugin) Quit() error {
+	// no need to quit for embedded javascript plugin
+	return nil
+}
+
+func (p *jsPlugin) StartHeartbeat() {
+	// no heartbeat needed for in-process plugin
+}
+
+// console returns console object writing logs to plugin logger
+func (p *jsPlugin) console() map[string]interface{} {
+	log := func(level hclog.Level) func(args ...interface{}) {
+		return func(args ...interface{}) {
+			messages := make([]string, len(args))
+			for i, arg := range args {
+				messages[i] = fmt.Sprint(arg)
+			}
+			p.logger.Log(level, strings.Join(messages, " "))
+		}
+	}
+	return map[string]interface{}{
+		"debug": log(hclog.Debug),
+		"log":   log(hclog.Info),
+		"info":  log(hclog.Info),
+		"warn":  log(hclog.Warn),
+		"error": log(hclog.Error),
+	}
+}
@@ -0,0 +1,144 @@
+package funplugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSPlugin(t *testing.T) {
+	plugin, err := Init("js/examples/debugtalk.js",
+		WithEmbeddedJS(true), WithCallTimeout(100*time.Millisecond, "loop"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	if !assert.Equal(t, "js-plugin", plugin.Type()) {
+		t.Fail()
+	}
+	assertPlugin(t, plugin)
+	assertPluginStream(t, plugin)
+
+	// keyword arguments are bound by params property, and resolved promise is returned
+	v, err := plugin.CallKw("sum_two_int", nil, map[string]interface{}{"a": 1, "b": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+	v, err = plugin.Call("greet", "world")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "Hello, world!", v) {
+		t.Fail()
+	}
+	// values yielded by generator are returned as list
+	v, err = plugin.Call("generate_ints", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, []interface{}{int64(0), int64(1), int64(2)}, v) {
+		t.Fail()
+	}
+
+	signature, err := plugin.Describe("sum_two_int")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "a", signature.Params[0].Name) {
+		t.Fail()
+	}
+	if !assert.Equal(t, "Return the sum of two integers.", signature.Doc) {
+		t.Fail()
+	}
+	assertDescribeNotFound(t, plugin)
+
+	errorKinds := map[string]ErrorKind{
+		"raise_error": ErrKindUser,
+		"sum_two_int": ErrKindArgMismatch, // missing arguments
+		"not_exist":   ErrKindFuncNotFound,
+	}
+	for funcName, kind := range errorKinds {
+		_, err := plugin.Call(funcName)
+		var pluginErr *PluginError
+		if !assert.True(t, errors.As(err, &pluginErr), err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, kind, pluginErr.Kind, funcName) {
+			t.Fail()
+		}
+	}
+
+	// endless function is interrupted on timeout or when ctx is done
+	_, err = plugin.Call("loop")
+	var timeoutErr *CallTimeoutError
+	if !assert.True(t, errors.As(err, &timeoutErr), err) {
+		t.Fatal()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = plugin.CallContext(ctx, "loop")
+	if !assert.ErrorIs(t, err, context.DeadlineExceeded) {
+		t.Fail()
+	}
+	v, err = plugin.Call("sum_two_int", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+}
+
+func TestJSPluginTopLevelFunctions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debugtalk.js")
+	content := "function sum_two_int(a, b) { return a + b; }\nvar version = '1.0';\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	plugin, err := Init(path, WithEmbeddedJS(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	if !assert.True(t, plugin.Has("sum_two_int")) {
+		t.Fail()
+	}
+	if !assert.False(t, plugin.Has("version")) {
+		t.Fail()
+	}
+	v, err := plugin.Call("sum_two_int", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+}
+
+func TestEmbedsJS(t *testing.T) {
+	// embedded engine is chosen explicitly, no matter whether node is found
+	t.Setenv("PATH", "")
+	option := newPluginOption()
+	if !assert.False(t, option.embedsJS("debugtalk.js")) {
+		t.Fail()
+	}
+
+	option = newPluginOption(WithEmbeddedJS(true))
+	if !assert.True(t, option.embedsJS("debugtalk.js")) {
+		t.Fail()
+	}
+	if !assert.False(t, option.embedsJS("debugtalk.mjs")) {
+		t.Fail()
+	}
+}
@@ -0,0 +1,385 @@
+package funplugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/httprunner/funplugin/fungo"
+	"github.com/httprunner/funplugin/myexec"
+)
+
+// ConflictPolicy specifies how Manager routes a plain function name found in multiple plugins
+type ConflictPolicy string
+
+const (
+	ConflictError ConflictPolicy = "error" // return *FuncConflictError, default
+	ConflictFirst ConflictPolicy = "first" // route to the first loaded plugin
+	ConflictLast  ConflictPolicy = "last"  // route to the last loaded plugin
+)
+
+// pluginExts are the plugin file extensions loaded by Manager.LoadDir,
+// .go is excluded since source of hashicorp go plugin is placed along with its binary.
+var pluginExts = map[string]bool{
+	".bin":  true,
+	".py":   true,
+	".so":   true,
+	".jar":  true,
+	".java": true,
+	".js":   true,
+	".mjs":  true,
+	".sh":   true,
+	".rb":   true,
+	".pl":   true,
+	".wasm": true,
+	".star": true,
+}
+
+// Manager loads multiple plugins and routes function calls to them.
+// Each plugin is namespaced by its file name without extension, function can be
+// called with namespace, e.g. "debugtalk.sum", or with plain name if it is unique.
+type Manager struct {
+	mu             sync.RWMutex
+	plugins        map[string]IPlugin // key is namespace
+	namespaces     []string           // namespaces in load order
+	routes         sync.Map           // cached routes, key is plain function name, value is namespace
+	conflictPolicy ConflictPolicy
+	options        []Option // options to initialize each plugin
+}
+
+type ManagerOption func(*Manager)
+
+// WithConflictPolicy specifies how to route a plain function name found in multiple plugins
+func WithConflictPolicy(policy ConflictPolicy) ManagerOption {
+	return func(m *Manager) {
+		m.conflictPolicy = policy
+	}
+}
+
+// WithPluginOptions specifies options to initialize each plugin
+func WithPluginOptions(options ...Option) ManagerOption {
+	return func(m *Manager) {
+		m.options = append(m.options, options...)
+	}
+}
+
+// NewManager creates a plugin manager, plugins should be loaded by Load or LoadDir
+func NewManager(options ...ManagerOption) *Manager {
+	m := &Manager{
+		plugins:        make(map[string]IPlugin),
+		conflictPolicy: ConflictError,
+	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+// LoadDir loads all plugin files (.bin/.py/.so/.jar/.java/.js/.mjs/.sh/.rb/.pl/.wasm/.star) in dir, and sub directories
+// with plugin manifest, e.g. signer/plugin.yaml. Other sub directories and files starting with "." or "_" are ignored.
+// Interpreted go plugins (.go) are not loaded by LoadDir, load them by Load explicitly.
+func (m *Manager) LoadDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Wrap(err, "read plugin dir failed")
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+			continue
+		}
+		if entry.IsDir() {
+			if manifest := findManifest(filepath.Join(dir, name)); manifest != "" {
+				paths = append(paths, manifest)
+			}
+			continue
+		}
+		if pluginExts[filepath.Ext(name)] {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no plugin found in %s", dir)
+	}
+	return m.Load(paths...)
+}
+
+// Load loads plugins in parallel, plugins are loaded in the order of paths.
+// If any plugin failed to load, the others loaded in this call are quit.
+func (m *Manager) Load(paths ...string) error {
+	option := newPluginOption(m.options...)
+	initLogger(option)
+
+	// check namespaces before starting plugins
+	namespaces := make([]string, len(paths))
+	seen := make(map[string]bool)
+	m.mu.RLock()
+	for i, path := range paths {
+		ns := namespaceOf(path)
+		if _, ok := m.plugins[ns]; ok || seen[ns] {
+			m.mu.RUnlock()
+			return fmt.Errorf("duplicate plugin namespace %s: %s", ns, path)
+		}
+		seen[ns] = true
+		namespaces[i] = ns
+	}
+	m.mu.RUnlock()
+
+	// prepare python3 venv once for all python plugins
+	python3 := option.python3
+	for _, path := range paths {
+		if filepath.Ext(path) != ".py" || python3 != "" {
+			continue
+		}
+		var err error
+		python3, err = myexec.EnsurePython3Venv("", "funppy")
+		if err != nil {
+			return errors.Wrap(err, "miss python3, create python3 funppy venv failed")
+		}
+	}
+
+	// install funnode once for all node plugins
+	nodePath := option.nodePath
+	for _, path := range paths {
+		if ext := filepath.Ext(path); (ext != ".js" && ext != ".mjs") || option.embedsJS(path) || nodePath != "" {
+			continue
+		}
+		var err error
+		nodePath, err = myexec.EnsureNodeModules("", option.getNodePackage())
+		if err != nil {
+			return errors.Wrap(err, "install funnode node modules failed")
+		}
+	}
+
+	plugins := make([]IPlugin, len(paths))
+	errs := make([]error, len(paths))
+	var wg sync.WaitGroup
+	for i, path := range paths {
+		wg.Add(1)
+		go func(i int, path string) {
+			defer wg.Done()
+			option := newPluginOption(m.options...)
+			option.python3 = python3
+			option.nodePath = nodePath
+			if option.watch {
+				option.eventHandler = m.reloadHandler(option.eventHandler)
+			}
+			plugins[i], errs[i] = initPlugin(path, option)
+		}(i, path)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		for _, plugin := range plugins {
+			if plugin != nil {
+				plugin.Quit()
+			}
+		}
+		return errors.Wrapf(err, "load plugin %s failed", paths[i])
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, ns := range namespaces {
+		m.plugins[ns] = plugins[i]
+		m.namespaces = append(m.namespaces, ns)
+	}
+	m.clearRoutes() // routes may change with new plugins
+	logger.Info("load plugins success", "namespaces", namespaces)
+	return nil
+}
+
+// findManifest returns path of plugin manifest in dir, or empty if not found
+func findManifest(dir string) string {
+	for _, name := range []string{"plugin.yaml", "plugin.yml", "plugin.json"} {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// namespaceOf returns plugin file name without extension, or directory name of plugin manifest
+func namespaceOf(path string) string {
+	if isManifest(path) {
+		abs, err := filepath.Abs(path)
+		if err == nil {
+			path = abs
+		}
+		return filepath.Base(filepath.Dir(path))
+	}
+	name := filepath.Base(path)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// Namespaces returns namespaces of loaded plugins in load order
+func (m *Manager) Namespaces() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]string{}, m.namespaces...)
+}
+
+// Plugin returns loaded plugin by namespace
+func (m *Manager) Plugin(namespace string) (IPlugin, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	plugin, ok := m.plugins[namespace]
+	return plugin, ok
+}
+
+// resolve returns the plugin and function name in plugin to call
+func (m *Manager) resolve(funcName string) (IPlugin, string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// namespaced function name
+	if i := strings.Index(funcName, "."); i > 0 {
+		if plugin, ok := m.plugins[funcName[:i]]; ok {
+			name := funcName[i+1:]
+			if !plugin.Has(name) {
+				return nil, "", m.notFound(funcName)
+			}
+			return plugin, name, nil
+		}
+	}
+
+	// plain function name
+	if ns, ok := m.routes.Load(funcName); ok {
+		return m.plugins[ns.(string)], funcName, nil
+	}
+	var found []string
+	for _, ns := range m.namespaces {
+		if m.plugins[ns].Has(funcName) {
+			found = append(found, ns)
+		}
+	}
+
+	var ns string
+	switch {
+	case len(found) == 0:
+		return nil, "", m.notFound(funcName)
+	case len(found) == 1 || m.conflictPolicy == ConflictFirst:
+		ns = found[0]
+	case m.conflictPolicy == ConflictLast:
+		ns = found[len(found)-1]
+	default:
+		return nil, "", &FuncConflictError{FuncName: funcName, Namespaces: found}
+	}
+
+	m.routes.Store(funcName, ns)
+	return m.plugins[ns], funcName, nil
+}
+
+// reloadHandler wraps event handler of watched plugin, routes are cleared on reload
+// since functions of reloaded plugin may be added or removed.
+func (m *Manager) reloadHandler(handler func(PluginEvent)) func(PluginEvent) {
+	return func(event PluginEvent) {
+		if event.Type == PluginReloaded {
+			m.mu.Lock()
+			m.clearRoutes()
+			m.mu.Unlock()
+		}
+		if handler != nil {
+			handler(event)
+		}
+	}
+}
+
+func (m *Manager) clearRoutes() {
+	m.routes.Range(func(key, value interface{}) bool {
+		m.routes.Delete(key)
+		return true
+	})
+}
+
+func (m *Manager) notFound(funcName string) error {
+	return &PluginError{
+		Kind:     ErrKindFuncNotFound,
+		FuncName: funcName,
+		Message:  fmt.Sprintf("function %s not found in plugins %v", funcName, m.namespaces),
+	}
+}
+
+// Has checks if function can be routed to a plugin
+func (m *Manager) Has(funcName string) bool {
+	_, _, err := m.resolve(funcName)
+	return err == nil
+}
+
+// Call calls function with namespaced or plain function name
+func (m *Manager) Call(funcName string, args ...interface{}) (interface{}, error) {
+	return m.CallContext(context.Background(), funcName, args...)
+}
+
+// CallContext calls function with context
+func (m *Manager) CallContext(ctx context.Context, funcName string, args ...interface{}) (interface{}, error) {
+	plugin, name, err := m.resolve(funcName)
+	if err != nil {
+		return nil, err
+	}
+	return plugin.CallContext(ctx, name, args...)
+}
+
+// CallKw calls function with keyword arguments
+func (m *Manager) CallKw(funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	return m.CallKwContext(context.Background(), funcName, args, kwargs)
+}
+
+// CallKwContext calls function with keyword arguments and context
+func (m *Manager) CallKwContext(ctx context.Context, funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	plugin, name, err := m.resolve(funcName)
+	if err != nil {
+		return nil, err
+	}
+	return plugin.CallKwContext(ctx, name, args, kwargs)
+}
+
+// CallStream calls function and receives values as stream
+func (m *Manager) CallStream(ctx context.Context, funcName string, args ...interface{}) (fungo.Stream, error) {
+	plugin, name, err := m.resolve(funcName)
+	if err != nil {
+		return nil, err
+	}
+	return plugin.CallStream(ctx, name, args...)
+}
+
+// Describe gets function signature
+func (m *Manager) Describe(funcName string) (*fungo.FuncSignature, error) {
+	plugin, name, err := m.resolve(funcName)
+	if err != nil {
+		return nil, err
+	}
+	return plugin.Describe(name)
+}
+
+// Quit quits all loaded plugins
+func (m *Manager) Quit() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []string
+	for _, ns := range m.namespaces {
+		if err := m.plugins[ns].Quit(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", ns, err))
+		}
+	}
+	m.plugins = make(map[string]IPlugin)
+	m.namespaces = nil
+	m.clearRoutes()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("quit plugins failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package funplugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// prepareManagerPlugins copies hashicorp go plugin into dir as multiple team-owned plugins
+func prepareManagerPlugins(t *testing.T, names ...string) string {
+	buildHashicorpGoPlugin()
+	defer removeHashicorpGoPlugin()
+
+	content, err := os.ReadFile(pluginBinPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestManager(t *testing.T) {
+	dir := prepareManagerPlugins(t, "team_a.bin", "team_b.bin", "_ignored.bin", "README.md")
+
+	manager := NewManager()
+	if err := manager.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Quit()
+
+	if !assert.Equal(t, []string{"team_a", "team_b"}, manager.Namespaces()) {
+		t.Fail()
+	}
+
+	// call with namespace
+	v, err := manager.Call("team_b.sum_two_int", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+	pidA, _ := manager.Call("team_a.get_pid")
+	pidB, _ := manager.Call("team_b.get_pid")
+	if !assert.NotEqual(t, pidA, pidB) {
+		t.Fail()
+	}
+
+	// plain function name found in multiple plugins
+	_, err = manager.Call("sum_two_int", 1, 2)
+	var conflictErr *FuncConflictError
+	if !assert.True(t, errors.As(err, &conflictErr), err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, []string{"team_a", "team_b"}, conflictErr.Namespaces) {
+		t.Fail()
+	}
+
+	// function not found
+	for _, funcName := range []string{"not_exist", "team_a.not_exist"} {
+		_, err = manager.Call(funcName)
+		var pluginErr *PluginError
+		if !assert.True(t, errors.As(err, &pluginErr), err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, ErrKindFuncNotFound, pluginErr.Kind) {
+			t.Fail()
+		}
+	}
+	if !assert.False(t, manager.Has("not_exist")) {
+		t.Fail()
+	}
+
+	// duplicate namespace
+	err = manager.Load(filepath.Join(dir, "team_a.bin"))
+	if !assert.ErrorContains(t, err, "duplicate plugin namespace team_a") {
+		t.Fail()
+	}
+}
+
+func TestManagerConflictPolicy(t *testing.T) {
+	dir := prepareManagerPlugins(t, "team_a.bin", "team_b.bin")
+
+	for _, policy := range []ConflictPolicy{ConflictFirst, ConflictLast} {
+		manager := NewManager(WithConflictPolicy(policy))
+		err := manager.Load(filepath.Join(dir, "team_a.bin"), filepath.Join(dir, "team_b.bin"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectNs := "team_a"
+		if policy == ConflictLast {
+			expectNs = "team_b"
+		}
+		expectPid, err := manager.Call(expectNs + ".get_pid")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pid, err := manager.Call("get_pid")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !assert.Equal(t, expectPid, pid, policy) {
+			t.Fail()
+		}
+		manager.Quit()
+	}
+}
+
+func TestManagerManifest(t *testing.T) {
+	dir := prepareManagerPlugins(t, "team_a.bin")
+	for _, name := range []string{"team_b", "_ignored", "no_manifest"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeManifest(t, filepath.Join(dir, "team_b"), "plugin.yaml", "command: ../team_a.bin\n")
+	writeManifest(t, filepath.Join(dir, "_ignored"), "plugin.yaml", "command: ../team_a.bin\n")
+
+	manager := NewManager()
+	if err := manager.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Quit()
+
+	if !assert.Equal(t, []string{"team_a", "team_b"}, manager.Namespaces()) {
+		t.Fail()
+	}
+	v, err := manager.Call("team_b.sum_two_int", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+}
+
+func TestManagerReloadHandler(t *testing.T) {
+	manager := NewManager()
+	manager.routes.Store("sum_two_int", "team_a")
+
+	var events []PluginEvent
+	handler := manager.reloadHandler(func(event PluginEvent) {
+		events = append(events, event)
+	})
+
+	// routes are kept on other events
+	handler(PluginEvent{Type: PluginRestarted})
+	if _, ok := manager.routes.Load("sum_two_int"); !assert.True(t, ok) {
+		t.Fail()
+	}
+
+	// routes are cleared on reload, since functions may be added or removed
+	handler(PluginEvent{Type: PluginReloaded})
+	if _, ok := manager.routes.Load("sum_two_int"); !assert.False(t, ok) {
+		t.Fail()
+	}
+	if !assert.Len(t, events, 2) {
+		t.Fail()
+	}
+
+	// no handler specified by user
+	manager.reloadHandler(nil)(PluginEvent{Type: PluginReloaded})
+}
+
+func TestManagerLoadDirWithGoSource(t *testing.T) {
+	// source files of hashicorp go plugin are placed along with its binary
+	dir := prepareManagerPlugins(t, "debugtalk.bin")
+	for _, name := range []string{"debugtalk.go", "hashicorp.go"} {
+		content, err := os.ReadFile(filepath.Join("fungo", "examples", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager := NewManager()
+	if err := manager.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer manager.Quit()
+
+	if !assert.Equal(t, []string{"debugtalk"}, manager.Namespaces()) {
+		t.Fail()
+	}
+	plugin, _ := manager.Plugin("debugtalk")
+	if !assert.Equal(t, "hashicorp-grpc-go", plugin.Type()) {
+		t.Fail()
+	}
+}
@@ -0,0 +1,122 @@
+package funplugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/httprunner/funplugin/myexec"
+)
+
+// manifestNames are file names of plugin manifest, the manifest directory contains plugin files
+var manifestNames = map[string]bool{
+	"plugin.yaml": true,
+	"plugin.yml":  true,
+	"plugin.json": true,
+}
+
+// manifestProtocolStdio declares plugin speaking newline-delimited JSON-RPC over stdio, see stdioPlugin
+const manifestProtocolStdio = "stdio"
+
+// defaultManifestLanguage is the language label of manifest plugin if not specified
+const defaultManifestLanguage = "generic"
+
+// Manifest declares how to start an executable plugin which speaks DebugTalk protocol,
+// it is loaded from plugin.yaml or plugin.json. Environment variables in command, args, env
+// and dir are expanded, e.g. ${HOME}.
+//
+//	command: ./target/release/debugtalk
+//	args: ["--verbose"]
+//	env:
+//	  RUST_LOG: info
+//	dir: .
+//	language: rust
+//	protocol: grpc
+type Manifest struct {
+	Command  string            `json:"command" yaml:"command"`   // executable path relative to manifest directory, or name in $PATH
+	Args     []string          `json:"args" yaml:"args"`         // command arguments
+	Env      map[string]string `json:"env" yaml:"env"`           // environment variables added to plugin process
+	Dir      string            `json:"dir" yaml:"dir"`           // working directory relative to manifest directory, default is manifest directory
+	Language string            `json:"language" yaml:"language"` // language label shown in plugin type and logs, default is generic
+	Protocol string            `json:"protocol" yaml:"protocol"` // grpc, rpc or stdio, default is grpc
+
+	path string // manifest file path
+}
+
+func isManifest(path string) bool {
+	return manifestNames[filepath.Base(path)]
+}
+
+// LoadManifest loads and validates plugin manifest in YAML or JSON
+func LoadManifest(path string) (*Manifest, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read plugin manifest failed")
+	}
+
+	// relative paths in manifest are resolved against manifest directory
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "get plugin manifest absolute path failed")
+	}
+	manifest := &Manifest{path: absPath}
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(content, manifest)
+	} else {
+		err = yaml.Unmarshal(content, manifest)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse plugin manifest %s failed", path)
+	}
+
+	if manifest.Command == "" {
+		return nil, fmt.Errorf("invalid plugin manifest %s: command not specified", path)
+	}
+	switch rpcType(manifest.Protocol) {
+	case "":
+		manifest.Protocol = rpcTypeGRPC.String()
+	case rpcTypeGRPC, rpcTypeRPC, manifestProtocolStdio:
+	default:
+		return nil, fmt.Errorf("invalid plugin manifest %s: unsupported protocol %s, expect grpc, rpc or stdio",
+			path, manifest.Protocol)
+	}
+	if manifest.Language == "" {
+		manifest.Language = defaultManifestLanguage
+	}
+	return manifest, nil
+}
+
+// command returns plugin process command declared by manifest
+func (m *Manifest) command() *exec.Cmd {
+	baseDir := filepath.Dir(m.path)
+	name := os.ExpandEnv(m.Command)
+	// command with path separator is relative to manifest directory, otherwise it is looked up in $PATH
+	if strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		name = resolvePath(baseDir, name)
+	}
+	args := make([]string, len(m.Args))
+	for i, arg := range m.Args {
+		args[i] = os.ExpandEnv(arg)
+	}
+
+	cmd := myexec.Command(name, args...)
+	cmd.Dir = resolvePath(baseDir, os.ExpandEnv(m.Dir))
+	cmd.Env = os.Environ()
+	for key, value := range m.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, os.ExpandEnv(value)))
+	}
+	return cmd
+}
+
+func resolvePath(baseDir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
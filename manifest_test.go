@@ -0,0 +1,121 @@
+package funplugin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeManifest(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("FUNPLUGIN_MANIFEST_LEVEL", "debug")
+
+	path := writeManifest(t, dir, "plugin.yaml", `
+command: ./bin/debugtalk
+args: ["--level", "${FUNPLUGIN_MANIFEST_LEVEL}"]
+env:
+  PLUGIN_LEVEL: ${FUNPLUGIN_MANIFEST_LEVEL}
+dir: work
+language: rust
+`)
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "rust", manifest.Language) {
+		t.Fail()
+	}
+	if !assert.Equal(t, "grpc", manifest.Protocol) {
+		t.Fail()
+	}
+
+	cmd := manifest.command()
+	if !assert.Equal(t, filepath.Join(dir, "bin", "debugtalk"), cmd.Path) {
+		t.Fail()
+	}
+	if !assert.Equal(t, []string{filepath.Join(dir, "bin", "debugtalk"), "--level", "debug"}, cmd.Args) {
+		t.Fail()
+	}
+	if !assert.Equal(t, filepath.Join(dir, "work"), cmd.Dir) {
+		t.Fail()
+	}
+	if !assert.Contains(t, cmd.Env, "PLUGIN_LEVEL=debug") {
+		t.Fail()
+	}
+
+	path = writeManifest(t, dir, "plugin.json", `{"command": "sh", "args": ["debugtalk.sh"], "protocol": "rpc"}`)
+	manifest, err = LoadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "generic", manifest.Language) {
+		t.Fail()
+	}
+	if !assert.Equal(t, "rpc", manifest.Protocol) {
+		t.Fail()
+	}
+	// command without path separator is looked up in $PATH, working directory is manifest directory
+	cmd = manifest.command()
+	if !assert.Equal(t, "sh", cmd.Args[0]) {
+		t.Fail()
+	}
+	if !assert.Equal(t, dir, cmd.Dir) {
+		t.Fail()
+	}
+}
+
+func TestLoadManifestInvalid(t *testing.T) {
+	dir := t.TempDir()
+
+	path := writeManifest(t, dir, "plugin.yaml", "args: [--verbose]\n")
+	_, err := LoadManifest(path)
+	if !assert.ErrorContains(t, err, "command not specified") {
+		t.Fail()
+	}
+
+	path = writeManifest(t, dir, "plugin.yml", "command: ./debugtalk\nprotocol: http\n")
+	_, err = LoadManifest(path)
+	if !assert.ErrorContains(t, err, "unsupported protocol http") {
+		t.Fail()
+	}
+
+	path = writeManifest(t, dir, "plugin.json", "{invalid json")
+	_, err = LoadManifest(path)
+	if !assert.ErrorContains(t, err, "parse plugin manifest") {
+		t.Fail()
+	}
+}
+
+func TestManifestPlugin(t *testing.T) {
+	buildHashicorpGoPlugin()
+	defer removeHashicorpGoPlugin()
+
+	for _, protocol := range []string{"grpc", "rpc"} {
+		t.Run(protocol, func(t *testing.T) {
+			path := writeManifest(t, "fungo/examples", "plugin.yaml",
+				"command: ./debugtalk.bin\nlanguage: golang\nprotocol: "+protocol+"\n")
+			defer os.Remove(path)
+
+			plugin, err := Init(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer plugin.Quit()
+
+			if !assert.Equal(t, "hashicorp-"+protocol+"-golang", plugin.Type()) {
+				t.Fail()
+			}
+			assertPlugin(t, plugin)
+		})
+	}
+}
@@ -53,6 +53,20 @@ func EnsurePython3Venv(venv string, packages ...string) (python3 string, err err
 	return python3, nil
 }
 
+// LookupJava returns path of java executable, priority: $JAVA_HOME/bin/java > java in $PATH
+func LookupJava() (string, error) {
+	if javaHome := os.Getenv("JAVA_HOME"); javaHome != "" {
+		if java, err := exec.LookPath(filepath.Join(javaHome, "bin", "java")); err == nil {
+			return java, nil
+		}
+	}
+	java, err := exec.LookPath("java")
+	if err != nil {
+		return "", errors.Wrap(err, "java not found in JAVA_HOME or PATH")
+	}
+	return java, nil
+}
+
 func ExecPython3Command(cmdName string, args ...string) error {
 	args = append([]string{"-m", cmdName}, args...)
 	return RunCommand(python3Executable, args...)
@@ -0,0 +1,73 @@
+package myexec
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// EnsureNodeModules ensures node packages are installed in dir by npm, and returns its node_modules
+// path to be set as NODE_PATH of node plugins. dir should be directory path of target node modules,
+// default is $HOME/.hrp/node. packages can be package names, e.g. funnode@0.6.0, or local package directories.
+func EnsureNodeModules(dir string, packages ...string) (nodePath string, err error) {
+	// priority: specified > $HOME/.hrp/node
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", errors.Wrap(err, "get user home dir failed")
+		}
+		dir = filepath.Join(home, ".hrp", "node")
+	}
+	nodePath = filepath.Join(dir, "node_modules")
+
+	var missing []string
+	for _, pkg := range packages {
+		name, err := nodePackageName(pkg)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(filepath.Join(nodePath, name, "package.json")); err != nil {
+			missing = append(missing, pkg)
+		}
+	}
+	if len(missing) == 0 {
+		logger.Info("node packages are ready", "nodePath", nodePath)
+		return nodePath, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", errors.Wrap(err, "create node modules dir failed")
+	}
+	logger.Info("installing node packages", "packages", missing, "dir", dir)
+	// local packages are copied instead of linked, so that their dependencies are installed in dir
+	args := append([]string{"install", "--prefix", dir, "--install-links", "--no-audit", "--no-fund"}, missing...)
+	if err := RunCommand("npm", args...); err != nil {
+		return "", errors.Wrap(err, "npm install packages failed")
+	}
+	return nodePath, nil
+}
+
+// nodePackageName returns name of package in local directory, or package name without version
+func nodePackageName(pkg string) (string, error) {
+	if stat, err := os.Stat(pkg); err == nil && stat.IsDir() {
+		content, err := os.ReadFile(filepath.Join(pkg, "package.json"))
+		if err != nil {
+			return "", errors.Wrap(err, "read package.json failed")
+		}
+		var manifest struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(content, &manifest); err != nil || manifest.Name == "" {
+			return "", errors.Errorf("invalid package.json in %s", pkg)
+		}
+		return manifest.Name, nil
+	}
+	// version follows the last @, scoped package name starts with @, e.g. @scope/name@1.0.0
+	if i := strings.LastIndex(pkg, "@"); i > 0 {
+		return pkg[:i], nil
+	}
+	return pkg, nil
+}
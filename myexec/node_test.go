@@ -0,0 +1,62 @@
+package myexec
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodePackageName(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name": "funnode"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testData := map[string]string{
+		"funnode":              "funnode",
+		"funnode@0.6.0":        "funnode",
+		"@grpc/grpc-js":        "@grpc/grpc-js",
+		"@grpc/grpc-js@^1.9.0": "@grpc/grpc-js",
+		dir:                    "funnode",
+	}
+	for pkg, expected := range testData {
+		name, err := nodePackageName(pkg)
+		if !assert.NoError(t, err) || !assert.Equal(t, expected, name) {
+			t.Fail()
+		}
+	}
+}
+
+func TestEnsureNodeModules(t *testing.T) {
+	if _, err := exec.LookPath("npm"); err != nil {
+		t.Skip("npm not found")
+	}
+	// local package without dependencies is installed offline
+	pkg := filepath.Join(t.TempDir(), "localpkg")
+	if err := os.MkdirAll(pkg, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := `{"name": "localpkg", "version": "1.0.0"}`
+	if err := os.WriteFile(filepath.Join(pkg, "package.json"), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	nodePath, err := EnsureNodeModules(dir, pkg)
+	if !assert.NoError(t, err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, filepath.Join(dir, "node_modules"), nodePath) {
+		t.Fail()
+	}
+	if !assert.FileExists(t, filepath.Join(nodePath, "localpkg", "package.json")) {
+		t.Fail()
+	}
+
+	// installed packages are skipped
+	if _, err := EnsureNodeModules(dir, pkg); !assert.NoError(t, err) {
+		t.Fail()
+	}
+}
@@ -0,0 +1,425 @@
+package funplugin
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"go.starlark.net/lib/json"
+	"go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+
+	"github.com/httprunner/funplugin/fungo"
+)
+
+// starlarkFileOptions enables set and while loop, recursion is disabled
+// since it can not be bounded by execution steps.
+var starlarkFileOptions = &syntax.FileOptions{Set: true, While: true}
+
+// thread local keys of call ctx and host functions caller
+const (
+	starlarkCtxKey  = "ctx"
+	starlarkHostKey = "host"
+)
+
+// starlarkPlugin implements plugin running .star file in starlark interpreter in process,
+// top-level functions not starting with _ are plugin functions. Starlark has no access to
+// filesystem or network, and the module globals are frozen after the script is loaded,
+// thus each call runs in a new thread and calls are deterministic and goroutine-safe.
+type starlarkPlugin struct {
+	path      string // plugin file path
+	option    *pluginOption
+	logger    hclog.Logger
+	host      fungo.IFuncCaller // caller of host functions of this plugin, nil if not specified
+	functions map[string]*starlark.Function
+}
+
+func newStarlarkPlugin(path string, option *pluginOption) (*starlarkPlugin, error) {
+	p := &starlarkPlugin{
+		path:      path,
+		option:    option,
+		logger:    logger.ResetNamed("starlark-plugin"),
+		functions: make(map[string]*starlark.Function),
+	}
+	if len(option.hostFunctions) > 0 {
+		// host functions are called directly, and only visible to this plugin
+		p.host = fungo.NewHostCaller(option.hostFunctions)
+	}
+	if err := p.load(); err != nil {
+		p.logger.Error("load starlark plugin failed", "path", path, "error", err)
+		return nil, err
+	}
+	p.logger.Info("load starlark plugin success", "path", path)
+	return p, nil
+}
+
+// load executes plugin script and collects its top-level functions
+func (p *starlarkPlugin) load() error {
+	predeclared := starlark.StringDict{
+		"json":      json.Module,
+		"math":      math.Module,
+		"call_host": starlark.NewBuiltin("call_host", callHost),
+	}
+
+	thread := p.newThread(context.Background(), "load")
+	globals, err := starlark.ExecFileOptions(starlarkFileOptions, thread, p.path, nil, predeclared)
+	if err != nil {
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			return errors.Errorf("run starlark plugin failed: %s", evalErr.Backtrace())
+		}
+		return errors.Wrap(err, "run starlark plugin failed")
+	}
+	for name, value := range globals {
+		if fn, ok := value.(*starlark.Function); ok && !strings.HasPrefix(name, "_") {
+			p.functions[name] = fn
+		}
+	}
+	return nil
+}
+
+// newThread creates thread with execution steps limit, print is written to plugin logger
+func (p *starlarkPlugin) newThread(ctx context.Context, name string) *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: name,
+		Print: func(_ *starlark.Thread, msg string) {
+			p.logger.Info(msg)
+		},
+	}
+	thread.SetLocal(starlarkCtxKey, ctx)
+	thread.SetLocal(starlarkHostKey, p.host)
+	thread.SetMaxExecutionSteps(p.option.starlarkMaxSteps)
+	return thread
+}
+
+func (p *starlarkPlugin) Type() string {
+	return "starlark-plugin"
+}
+
+func (p *starlarkPlugin) Path() string {
+	return p.path
+}
+
+func (p *starlarkPlugin) Has(funcName string) bool {
+	_, ok := p.functions[funcName]
+	return ok
+}
+
+func (p *starlarkPlugin) Call(funcName string, args ...interface{}) (interface{}, error) {
+	return p.CallKwContext(context.Background(), funcName, args, nil)
+}
+
+func (p *starlarkPlugin) CallContext(ctx context.Context, funcName string, args ...interface{}) (interface{}, error) {
+	return p.CallKwContext(ctx, funcName, args, nil)
+}
+
+func (p *starlarkPlugin) CallKw(funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	return p.CallKwContext(context.Background(), funcName, args, kwargs)
+}
+
+// CallKwContext calls function with positional and keyword arguments, which are bound by starlark.
+// The function is cancelled when ctx is done or call timeout is exceeded.
+func (p *starlarkPlugin) CallKwContext(ctx context.Context, funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	timeout := p.option.getCallTimeout(funcName)
+	if timeout <= 0 {
+		return p.invoke(ctx, funcName, args, kwargs)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := p.invoke(callCtx, funcName, args, kwargs)
+	if err == nil || ctx.Err() != nil || !isDeadlineExceeded(callCtx, err) {
+		return result, err
+	}
+	return nil, &CallTimeoutError{FuncName: funcName, Timeout: timeout}
+}
+
+func (p *starlarkPlugin) invoke(ctx context.Context, funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	fn, ok := p.functions[funcName]
+	if !ok {
+		return nil, &PluginError{
+			Kind:     ErrKindFuncNotFound,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function %s not found", funcName),
+		}
+	}
+
+	starlarkArgs := make(starlark.Tuple, len(args))
+	for i, arg := range args {
+		value, err := toStarlark(arg)
+		if err != nil {
+			return nil, &PluginError{
+				Kind:     ErrKindArgMismatch,
+				FuncName: funcName,
+				Message:  fmt.Sprintf("function argument %d: %v", i, err),
+			}
+		}
+		starlarkArgs[i] = value
+	}
+	names := make([]string, 0, len(kwargs))
+	for name := range kwargs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	starlarkKwargs := make([]starlark.Tuple, len(names))
+	for i, name := range names {
+		value, err := toStarlark(kwargs[name])
+		if err != nil {
+			return nil, &PluginError{
+				Kind:     ErrKindArgMismatch,
+				FuncName: funcName,
+				Message:  fmt.Sprintf("function argument %s: %v", name, err),
+			}
+		}
+		starlarkKwargs[i] = starlark.Tuple{starlark.String(name), value}
+	}
+
+	thread := p.newThread(ctx, funcName)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			thread.Cancel(ctx.Err().Error())
+		case <-done:
+		}
+	}()
+
+	value, err := starlark.Call(thread, fn, starlarkArgs, starlarkKwargs)
+	if err != nil {
+		return nil, p.pluginError(ctx, thread, funcName, err)
+	}
+	result, err := fromStarlark(value)
+	if err != nil {
+		return nil, &PluginError{
+			Kind:     ErrKindUser,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function return value: %v", err),
+		}
+	}
+	return result, nil
+}
+
+// pluginError returns ctx error if function is cancelled, otherwise *PluginError
+func (p *starlarkPlugin) pluginError(ctx context.Context, thread *starlark.Thread, funcName string, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	var evalErr *starlark.EvalError
+	if !errors.As(err, &evalErr) {
+		return fungo.AsPluginError(funcName, err)
+	}
+	kind := ErrKindUser
+	if thread.ExecutionSteps() == 0 {
+		// arguments are bound before the first step is executed
+		kind = ErrKindArgMismatch
+	} else if maxSteps := p.option.starlarkMaxSteps; maxSteps > 0 && thread.ExecutionSteps() >= maxSteps {
+		kind = ErrKindStepLimit
+	}
+	return &PluginError{
+		Kind:     kind,
+		FuncName: funcName,
+		Message:  evalErr.Msg,
+		Stack:    evalErr.Backtrace(),
+	}
+}
+
+// CallStream calls function and the stream yields the single result
+func (p *starlarkPlugin) CallStream(ctx context.Context, funcName string, args ...interface{}) (fungo.Stream, error) {
+	result, err := p.CallKwContext(ctx, funcName, args, nil)
+	if err != nil {
+		return nil, err
+	}
+	return fungo.NewValueStream(result), nil
+}
+
+// Describe returns function signature with parameter names and doc string,
+// parameter types are not available since starlark is dynamically typed.
+func (p *starlarkPlugin) Describe(funcName string) (*fungo.FuncSignature, error) {
+	fn, ok := p.functions[funcName]
+	if !ok {
+		return nil, &PluginError{
+			Kind:     ErrKindFuncNotFound,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function %s not found", funcName),
+		}
+	}
+	sig := &fungo.FuncSignature{
+		Name:     funcName,
+		Params:   []fungo.FuncParam{},
+		Variadic: fn.HasVarargs(),
+		Returns:  []string{},
+		Doc:      fn.Doc(),
+	}
+	numParams := fn.NumParams()
+	if fn.HasKwargs() {
+		numParams-- // **kwargs is the last parameter
+	}
+	for i := 0; i < numParams; i++ {
+		name, _ := fn.Param(i)
+		sig.Params = append(sig.Params, fungo.FuncParam{
+			Name:     name,
+			Optional: fn.ParamDefault(i) != nil || (fn.HasVarargs() && i == numParams-1),
+		})
+	}
+	return sig, nil
+}
+
+func (p *starlarkPlugin) Quit() error {
+	// no need to quit for starlark plugin
+	return nil
+}
+
+func (p *starlarkPlugin) StartHeartbeat() {
+	// no heartbeat needed for in-process plugin
+}
+
+// callHost is starlark builtin call_host(name, *args) to call host function specified for the plugin
+func callHost(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if len(args) == 0 || len(kwargs) > 0 {
+		return nil, fmt.Errorf("%s: expect host function name and positional arguments", b.Name())
+	}
+	funcName, ok := starlark.AsString(args[0])
+	if !ok {
+		return nil, fmt.Errorf("%s: host function name must be string, got %s", b.Name(), args[0].Type())
+	}
+	hostArgs := make([]interface{}, len(args)-1)
+	for i, arg := range args[1:] {
+		value, err := fromStarlark(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument %d: %v", b.Name(), i, err)
+		}
+		hostArgs[i] = value
+	}
+
+	host, _ := thread.Local(starlarkHostKey).(fungo.IFuncCaller)
+	if host == nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), fungo.ErrHostNotConnected)
+	}
+	ctx, _ := thread.Local(starlarkCtxKey).(context.Context)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	result, err := host.CallContext(ctx, funcName, hostArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	return toStarlark(result)
+}
+
+// toStarlark converts go value to starlark value, integers are converted to int,
+// slices and arrays to list, and maps to dict.
+func toStarlark(value interface{}) (starlark.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return starlark.None, nil
+	case starlark.Value:
+		return v, nil
+	case []byte:
+		return starlark.Bytes(v), nil
+	case *big.Int:
+		return starlark.MakeBigInt(v), nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return starlark.Bool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return starlark.MakeInt64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return starlark.MakeUint64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return starlark.Float(rv.Float()), nil
+	case reflect.String:
+		return starlark.String(rv.String()), nil
+	case reflect.Slice, reflect.Array:
+		elems := make([]starlark.Value, rv.Len())
+		for i := range elems {
+			elem, err := toStarlark(rv.Index(i).Interface())
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			elems[i] = elem
+		}
+		return starlark.NewList(elems), nil
+	case reflect.Map:
+		dict := starlark.NewDict(rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := toStarlark(iter.Key().Interface())
+			if err != nil {
+				return nil, fmt.Errorf("key %v: %v", iter.Key(), err)
+			}
+			elem, err := toStarlark(iter.Value().Interface())
+			if err != nil {
+				return nil, fmt.Errorf("[%v]: %v", iter.Key(), err)
+			}
+			if err := dict.SetKey(key, elem); err != nil {
+				return nil, err
+			}
+		}
+		return dict, nil
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return starlark.None, nil
+		}
+		return toStarlark(rv.Elem().Interface())
+	}
+	return nil, fmt.Errorf("unsupported type %T", value)
+}
+
+// fromStarlark converts starlark value to go value, int is converted to int64 or *big.Int
+// if it overflows, list, tuple and set to []interface{}, and dict to map[string]interface{}.
+func fromStarlark(value starlark.Value) (interface{}, error) {
+	switch v := value.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.Int:
+		if i, ok := v.Int64(); ok {
+			return i, nil
+		}
+		return v.BigInt(), nil
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Bytes:
+		return []byte(v), nil
+	case *starlark.Dict:
+		result := make(map[string]interface{}, v.Len())
+		for _, item := range v.Items() {
+			key, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, fmt.Errorf("dict key must be string, got %s", item[0].Type())
+			}
+			elem, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, fmt.Errorf("[%s]: %v", key, err)
+			}
+			result[key] = elem
+		}
+		return result, nil
+	case *starlark.List, starlark.Tuple, *starlark.Set:
+		result := []interface{}{}
+		iter := v.(starlark.Iterable).Iterate()
+		defer iter.Done()
+		var elem starlark.Value
+		for i := 0; iter.Next(&elem); i++ {
+			item, err := fromStarlark(elem)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			result = append(result, item)
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", value.Type())
+}
@@ -0,0 +1,208 @@
+package funplugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/httprunner/funplugin/fungo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStarlarkPlugin(t *testing.T) {
+	plugin, err := Init("starlark/examples/debugtalk.star",
+		WithCallTimeout(100*time.Millisecond, "loop"),
+		WithHostFunctions(map[string]interface{}{
+			"get_variable": func(name string) string { return "value of " + name },
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	if !assert.Equal(t, "starlark-plugin", plugin.Type()) {
+		t.Fail()
+	}
+	if !assert.False(t, plugin.Has("_private_helper")) {
+		t.Fail()
+	}
+	assertPlugin(t, plugin)
+
+	// keyword arguments are bound by starlark, values are converted to go maps, lists, ints and strings
+	v, err := plugin.CallKw("greet", []interface{}{"world"}, map[string]interface{}{"greeting": "Hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "Hi, world!", v) {
+		t.Fail()
+	}
+	v, err = plugin.Call("sign", map[string]interface{}{"b": 2, "a": []int{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "a=[1]&b=2", v) {
+		t.Fail()
+	}
+	v, err = plugin.Call("generate_ints", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, []interface{}{int64(0), int64(1), int64(2)}, v) {
+		t.Fail()
+	}
+	v, err = plugin.Call("get_variable", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "value of token", v) {
+		t.Fail()
+	}
+
+	signature, err := plugin.Describe("greet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "greeting", signature.Params[1].Name) || !assert.True(t, signature.Params[1].Optional) {
+		t.Fail()
+	}
+	signature, err = plugin.Describe("sum_two_int")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "Return the sum of two integers.", signature.Doc) {
+		t.Fail()
+	}
+	assertDescribeNotFound(t, plugin)
+
+	errorCases := []struct {
+		funcName string
+		args     []interface{}
+		kind     ErrorKind
+	}{
+		{"raise_error", nil, ErrKindUser},
+		{"sum_two_int", []interface{}{1}, ErrKindArgMismatch},             // missing argument
+		{"sum_two_int", []interface{}{struct{}{}, 1}, ErrKindArgMismatch}, // unsupported type
+		{"_private_helper", nil, ErrKindFuncNotFound},
+	}
+	for _, c := range errorCases {
+		_, err := plugin.Call(c.funcName, c.args...)
+		var pluginErr *PluginError
+		if !assert.True(t, errors.As(err, &pluginErr), err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, c.kind, pluginErr.Kind, c.funcName) {
+			t.Fail()
+		}
+	}
+
+	// endless function is cancelled on timeout or when ctx is done
+	_, err = plugin.Call("loop")
+	var timeoutErr *CallTimeoutError
+	if !assert.True(t, errors.As(err, &timeoutErr), err) {
+		t.Fatal()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = plugin.CallContext(ctx, "loop")
+	if !assert.ErrorIs(t, err, context.DeadlineExceeded) {
+		t.Fail()
+	}
+}
+
+func TestStarlarkPluginMaxSteps(t *testing.T) {
+	plugin, err := Init("starlark/examples/debugtalk.star", WithStarlarkMaxSteps(10000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	v, err := plugin.Call("sum_two_int", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+
+	// steps are limited for each call, and following calls are not affected
+	_, err = plugin.Call("loop")
+	var pluginErr *PluginError
+	if !assert.True(t, errors.As(err, &pluginErr), err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, ErrKindStepLimit, pluginErr.Kind) {
+		t.Fail()
+	}
+	v, err = plugin.Call("sum_two_int", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+}
+
+func TestStarlarkPluginHostFunctions(t *testing.T) {
+	plugin, err := Init("starlark/examples/debugtalk.star",
+		WithHostFunctions(map[string]interface{}{
+			"get_variable": func(name string) string { return "value of " + name },
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+	other, err := Init("starlark/examples/debugtalk.star")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Quit()
+
+	v, err := plugin.Call("get_variable", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "value of token", v) {
+		t.Fail()
+	}
+	// host functions of other plugin are not visible
+	_, err = other.Call("get_variable", "token")
+	if !assert.ErrorContains(t, err, fungo.ErrHostNotConnected.Error()) {
+		t.Fail()
+	}
+}
+
+func TestStarlarkPluginSandbox(t *testing.T) {
+	dir := t.TempDir()
+	scripts := map[string]string{
+		"load.star": "load('debugtalk.star', 'sum')\n",
+		"open.star": "def read(path):\n    return open(path).read()\n",
+	}
+	for name, content := range scripts {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// load statement and filesystem access are rejected when loading
+		_, err := Init(path)
+		if !assert.Error(t, err, name) {
+			t.Fail()
+		}
+	}
+
+	// recursion is rejected when called
+	path := filepath.Join(dir, "recursion.star")
+	if err := os.WriteFile(path, []byte("def f(n):\n    return f(n - 1)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	plugin, err := Init(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = plugin.Call("f", 1)
+	if !assert.ErrorContains(t, err, "called recursively") {
+		t.Fail()
+	}
+}
@@ -0,0 +1,67 @@
+#!/usr/bin/env perl
+# debugtalk plugin speaking newline-delimited JSON-RPC 2.0 over stdio, only core modules are used.
+use strict;
+use warnings;
+use JSON::PP;
+
+$| = 1; # flush stdout after each response
+
+my $json = JSON::PP->new->canonical->allow_nonref;
+
+my %functions = (
+    sum            => sub { my $s = 0; $s += $_ for @_; $s },
+    sum_ints       => sub { my $s = 0; $s += $_ for @_; $s },
+    sum_two_int    => sub { $_[0] + $_[1] },
+    sum_two_string => sub { $_[0] . $_[1] },
+    sum_strings    => sub { join "", @_ },
+    concatenate    => sub { join "", @_ },
+    sleep          => sub { select(undef, undef, undef, $_[0]); "slept $_[0]s" },
+    raise_error    => sub { die "something wrong\n" },
+);
+
+# parameter names to bind keyword arguments
+my %params = (
+    sum_two_int    => [ "a", "b" ],
+    sum_two_string => [ "a", "b" ],
+);
+
+sub send_message {
+    print $json->encode({ jsonrpc => "2.0", @_ }), "\n";
+}
+
+while (my $line = <STDIN>) {
+    my $request = eval { $json->decode($line) };
+    if (!$request) {
+        send_message(id => undef, error => { code => -32700, message => "parse error" });
+        next;
+    }
+    my ($id, $method, $args) = @{$request}{qw(id method params)};
+    print STDERR "call $method\n";
+
+    if ($method eq "rpc.names") {
+        send_message(id => $id, result => [ sort(keys %functions), "generate_ints" ]);
+        next;
+    }
+    if ($method eq "generate_ints") {
+        # yield values of streaming function before its response
+        send_message(method => "rpc.yield", params => { id => $id, value => $_ }) for 0 .. $args->[0] - 1;
+        send_message(id => $id, result => undef);
+        next;
+    }
+
+    my $function = $functions{$method};
+    if (!$function) {
+        send_message(id => $id, error => { code => -32601, message => "function $method not found" });
+        next;
+    }
+    if (ref $args eq "HASH") {
+        $args = [ map { $args->{$_} } @{ $params{$method} || [] } ];
+    }
+    my $result = eval { $function->(@$args) };
+    if ($@) {
+        chomp(my $message = $@);
+        send_message(id => $id, error => { code => -32000, message => $message });
+        next;
+    }
+    send_message(id => $id, result => $result);
+}
@@ -0,0 +1,50 @@
+#!/usr/bin/env ruby
+# debugtalk plugin speaking newline-delimited JSON-RPC 2.0 over stdio, only standard library is used.
+require "json"
+
+$stdout.sync = true
+
+FUNCTIONS = {
+  "sum" => ->(*args) { args.sum },
+  "sum_ints" => ->(*args) { args.sum },
+  "sum_two_int" => ->(a, b) { a + b },
+  "sum_two_string" => ->(a, b) { a + b },
+  "sum_strings" => ->(*args) { args.join },
+  "concatenate" => ->(*args) { args.map(&:to_s).join },
+  "sleep" => ->(seconds) { sleep(seconds); "slept #{seconds}s" },
+  "raise_error" => ->() { raise "something wrong" },
+}.freeze
+
+def send_message(message)
+  puts JSON.generate({ "jsonrpc" => "2.0" }.merge(message))
+end
+
+$stdin.each_line do |line|
+  request = JSON.parse(line)
+  id, method, params = request.values_at("id", "method", "params")
+  warn "call #{method}"
+
+  case method
+  when "rpc.names"
+    send_message("id" => id, "result" => FUNCTIONS.keys + ["generate_ints"])
+  when "generate_ints"
+    # yield values of streaming function before its response
+    params[0].times { |i| send_message("method" => "rpc.yield", "params" => { "id" => id, "value" => i }) }
+    send_message("id" => id, "result" => nil)
+  else
+    function = FUNCTIONS[method]
+    if function.nil?
+      send_message("id" => id, "error" => { "code" => -32601, "message" => "function #{method} not found" })
+      next
+    end
+    begin
+      # keyword arguments are bound by parameter names of lambda
+      args = params.is_a?(Hash) ? function.parameters.map { |_, name| params[name.to_s] } : params
+      send_message("id" => id, "result" => function.call(*args))
+    rescue StandardError => e
+      send_message("id" => id, "error" => { "code" => -32000, "message" => e.message, "data" => e.backtrace.join("\n") })
+    end
+  end
+rescue JSON::ParserError
+  send_message("id" => nil, "error" => { "code" => -32700, "message" => "parse error" })
+end
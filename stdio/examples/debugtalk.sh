@@ -0,0 +1,67 @@
+#!/usr/bin/env bash
+# debugtalk plugin speaking newline-delimited JSON-RPC 2.0 over stdio, requires jq.
+# Each request is read from stdin as one line, and its response is written to stdout as one line,
+# logs should be written to stderr.
+
+respond() {
+  echo "{\"jsonrpc\":\"2.0\",\"id\":$1,\"result\":$2}"
+}
+
+respond_error() {
+  echo "{\"jsonrpc\":\"2.0\",\"id\":$1,\"error\":{\"code\":$2,\"message\":$(jq -Rn --arg m "$3" '$m')}}"
+}
+
+# yield sends value of streaming function before its response
+yield() {
+  echo "{\"jsonrpc\":\"2.0\",\"method\":\"rpc.yield\",\"params\":{\"id\":$1,\"value\":$2}}"
+}
+
+while IFS= read -r request; do
+  id=$(jq -c '.id' <<<"$request")
+  method=$(jq -r '.method' <<<"$request")
+  echo "call $method" >&2
+
+  case "$method" in
+  rpc.names)
+    respond "$id" '["sum","sum_ints","sum_two_int","sum_two_string","sum_strings","concatenate","sleep","generate_ints","raise_error"]'
+    ;;
+  rpc.describe)
+    case $(jq -r '.params[0]' <<<"$request") in
+    sum_two_int)
+      respond "$id" '{"name":"sum_two_int","params":[{"name":"a","type":"int"},{"name":"b","type":"int"}],"doc":"Return the sum of two integers."}'
+      ;;
+    *)
+      respond_error "$id" -32601 "function not described"
+      ;;
+    esac
+    ;;
+  sum | sum_ints)
+    respond "$id" "$(jq -c '.params | add // 0' <<<"$request")"
+    ;;
+  sum_two_int | sum_two_string)
+    # positional arguments are passed as array, keyword arguments as object
+    respond "$id" "$(jq -c '.params | if type == "object" then .a + .b else .[0] + .[1] end' <<<"$request")"
+    ;;
+  sum_strings | concatenate)
+    respond "$id" "$(jq -c '[.params[] | tostring] | join("")' <<<"$request")"
+    ;;
+  sleep)
+    seconds=$(jq -r '.params[0]' <<<"$request")
+    sleep "$seconds"
+    respond "$id" "\"slept ${seconds}s\""
+    ;;
+  generate_ints)
+    n=$(jq -r '.params[0]' <<<"$request")
+    for ((i = 0; i < n; i++)); do
+      yield "$id" "$i"
+    done
+    respond "$id" null
+    ;;
+  raise_error)
+    respond_error "$id" -32000 "something wrong"
+    ;;
+  *)
+    respond_error "$id" -32601 "function $method not found"
+    ;;
+  esac
+done
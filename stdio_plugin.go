@@ -0,0 +1,604 @@
+package funplugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+
+	"github.com/httprunner/funplugin/fungo"
+	"github.com/httprunner/funplugin/myexec"
+)
+
+// scriptInterpreters are interpreters of script plugins speaking JSON-RPC over stdio
+var scriptInterpreters = map[string]string{
+	".sh": "bash",
+	".rb": "ruby",
+	".pl": "perl",
+}
+
+// reserved methods of stdio plugin, following JSON-RPC convention of rpc. prefix
+const (
+	stdioMethodNames    = "rpc.names"    // list function names, optional
+	stdioMethodDescribe = "rpc.describe" // describe function signature, optional
+	stdioMethodYield    = "rpc.yield"    // notification of value yielded by streaming function
+)
+
+// JSON-RPC error codes, other codes are regarded as user errors
+const (
+	jsonrpcParseError     = -32700
+	jsonrpcInvalidRequest = -32600
+	jsonrpcMethodNotFound = -32601
+	jsonrpcInvalidParams  = -32602
+)
+
+// stdioQuitTimeout is the time to wait for plugin process to exit after its stdin is closed
+const stdioQuitTimeout = time.Second
+
+// stdioProbeTimeout is the default time to wait for the answer of rpc.names when plugin is initialized
+var stdioProbeTimeout = 10 * time.Second
+
+type stdioRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      uint64      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// stdioMessage is response or notification written by plugin
+type stdioMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *stdioError     `json:"error"`
+}
+
+type stdioError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data"`
+}
+
+// stdioYield is params of rpc.yield notification
+type stdioYield struct {
+	ID    json.RawMessage `json:"id"`
+	Value json.RawMessage `json:"value"`
+}
+
+// stdioProcess is a running plugin process, lines written to its stdout are sent to lines
+type stdioProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	lines  chan []byte
+	killed chan struct{} // closed when process is killed
+	exited chan struct{} // closed when process exits
+	once   sync.Once
+}
+
+func (proc *stdioProcess) read(stdout io.Reader) {
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			select {
+			case proc.lines <- line:
+			case <-proc.killed:
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	close(proc.lines)
+	proc.cmd.Wait()
+	close(proc.exited)
+}
+
+// kill kills plugin process and its children, which may hold stdout open
+func (proc *stdioProcess) kill() {
+	proc.once.Do(func() { close(proc.killed) })
+	proc.stdin.Close()
+	if err := myexec.KillProcessesByGpid(proc.cmd); err != nil {
+		proc.cmd.Process.Kill()
+	}
+	select {
+	case <-proc.exited:
+	case <-time.After(stdioQuitTimeout):
+	}
+}
+
+// quit closes stdin of plugin process and waits for it to exit, or kills it on timeout
+func (proc *stdioProcess) quit() {
+	proc.stdin.Close()
+	select {
+	case <-proc.exited:
+	case <-time.After(stdioQuitTimeout):
+		proc.kill()
+	}
+}
+
+// stdioPlugin implements plugin which reads newline-delimited JSON-RPC 2.0 requests on stdin
+// and writes responses on stdout, e.g. bash, ruby or perl scripts without any SDK.
+// Requests are sent one by one to a single plugin process, which is started again on next call
+// if it exited or was killed after call timeout.
+type stdioPlugin struct {
+	path        string // plugin file path
+	option      *pluginOption
+	interpreter string // interpreter of script plugin, empty for manifest plugin
+	logger      hclog.Logger
+
+	mu     sync.Mutex    // serializes requests since plugin handles them sequentially
+	proc   *stdioProcess // nil if not started
+	nextID uint64
+	names  map[string]bool // function names, nil if plugin does not implement rpc.names
+}
+
+func newStdioPlugin(path string, option *pluginOption) (*stdioPlugin, error) {
+	p := &stdioPlugin{
+		path:   path,
+		option: option,
+		logger: logger.ResetNamed(fmt.Sprintf("stdio-%s", option.langType)),
+	}
+	if option.manifest == nil {
+		interpreter, err := exec.LookPath(scriptInterpreters[filepath.Ext(path)])
+		if err != nil {
+			p.logger.Error("interpreter of script plugin not found", "path", path, "error", err)
+			return nil, errors.Wrap(err, "interpreter of script plugin not found")
+		}
+		p.interpreter = interpreter
+	}
+	if len(option.hostFunctions) > 0 {
+		p.logger.Warn("host functions are not supported by stdio plugin")
+	}
+
+	// plugin which never answers rpc.names is killed after call timeout
+	timeout := option.getCallTimeout(stdioMethodNames)
+	if timeout <= 0 {
+		timeout = stdioProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	names, err := p.invoke(ctx, stdioMethodNames, []interface{}{})
+	var pluginErr *PluginError
+	if errors.As(err, &pluginErr) && pluginErr.Kind == ErrKindFuncNotFound {
+		p.logger.Warn("plugin does not implement rpc.names, assume it has all functions")
+	} else if err != nil {
+		p.logger.Error("load stdio plugin failed", "path", path, "error", err)
+		p.stop()
+		return nil, errors.Wrapf(err, "call %s failed", stdioMethodNames)
+	} else {
+		list, ok := names.([]interface{})
+		if !ok {
+			p.stop()
+			return nil, fmt.Errorf("invalid result of rpc.names: %v", names)
+		}
+		p.names = make(map[string]bool, len(list))
+		for _, name := range list {
+			p.names[fmt.Sprint(name)] = true
+		}
+	}
+
+	p.logger.Info("load stdio plugin success", "path", path)
+	return p, nil
+}
+
+func (p *stdioPlugin) Type() string {
+	return fmt.Sprintf("stdio-%s", p.option.langType)
+}
+
+func (p *stdioPlugin) Path() string {
+	return p.path
+}
+
+func (p *stdioPlugin) Has(funcName string) bool {
+	if p.names == nil {
+		return true
+	}
+	return p.names[funcName]
+}
+
+func (p *stdioPlugin) Call(funcName string, args ...interface{}) (interface{}, error) {
+	return p.CallKwContext(context.Background(), funcName, args, nil)
+}
+
+func (p *stdioPlugin) CallContext(ctx context.Context, funcName string, args ...interface{}) (interface{}, error) {
+	return p.CallKwContext(ctx, funcName, args, nil)
+}
+
+func (p *stdioPlugin) CallKw(funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	return p.CallKwContext(context.Background(), funcName, args, kwargs)
+}
+
+// CallKwContext calls function with arguments as JSON-RPC params, positional arguments are sent as
+// array and keyword arguments as object, thus they can not be passed together.
+func (p *stdioPlugin) CallKwContext(ctx context.Context, funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	params, err := jsonParams(funcName, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := p.option.getCallTimeout(funcName)
+	if timeout <= 0 {
+		return p.invoke(ctx, funcName, params)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := p.invoke(callCtx, funcName, params)
+	if err == nil || ctx.Err() != nil || !isDeadlineExceeded(callCtx, err) {
+		return result, err
+	}
+	return nil, &CallTimeoutError{FuncName: funcName, Timeout: timeout}
+}
+
+// CallStream calls function and receives values yielded by rpc.yield notifications,
+// the stream yields the single result if function does not yield any value.
+// Plugin is locked until the stream ends, is closed or ctx is done.
+func (p *stdioPlugin) CallStream(ctx context.Context, funcName string, args ...interface{}) (fungo.Stream, error) {
+	params, err := jsonParams(funcName, args, nil)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	id, err := p.send(funcName, params)
+	if err != nil {
+		p.mu.Unlock()
+		return nil, err
+	}
+	stream := &stdioStream{plugin: p, ctx: ctx, funcName: funcName, id: id, finished: make(chan struct{})}
+	if ctx.Done() != nil {
+		go stream.watch()
+	}
+	return stream, nil
+}
+
+// Describe gets function signature by rpc.describe,
+// only function name is available if plugin does not implement it.
+func (p *stdioPlugin) Describe(funcName string) (*fungo.FuncSignature, error) {
+	if !p.Has(funcName) {
+		return nil, &PluginError{
+			Kind:     ErrKindFuncNotFound,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function %s not found", funcName),
+		}
+	}
+	result, err := p.invoke(context.Background(), stdioMethodDescribe, []interface{}{funcName})
+	var pluginErr *PluginError
+	if errors.As(err, &pluginErr) && pluginErr.Kind == ErrKindFuncNotFound && pluginErr.FuncName == stdioMethodDescribe {
+		return &fungo.FuncSignature{Name: funcName, Variadic: true}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal function signature failed")
+	}
+	signature := &fungo.FuncSignature{}
+	if err := json.Unmarshal(data, signature); err != nil {
+		return nil, errors.Wrapf(err, "invalid result of rpc.describe: %s", data)
+	}
+	if signature.Name == "" {
+		signature.Name = funcName
+	}
+	return signature, nil
+}
+
+func (p *stdioPlugin) Quit() error {
+	p.logger.Info("quit stdio plugin process")
+	p.stop()
+	return fungo.CloseLogFile()
+}
+
+// stop quits plugin process gracefully
+func (p *stdioPlugin) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.proc != nil {
+		p.proc.quit()
+		p.proc = nil
+	}
+}
+
+func (p *stdioPlugin) StartHeartbeat() {
+	// plugin process is started again on next call if it exited
+}
+
+// command returns plugin process command
+func (p *stdioPlugin) command() *exec.Cmd {
+	if p.option.manifest != nil {
+		return p.option.manifest.command()
+	}
+	return myexec.Command(p.interpreter, p.path)
+}
+
+// start starts plugin process, p.mu should be held
+func (p *stdioPlugin) start() error {
+	cmd := p.command()
+	cmd.Stderr = p.logger.StandardWriter(&hclog.StandardLoggerOptions{InferLevels: true})
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return errors.Wrap(err, "create stdin pipe failed")
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return errors.Wrap(err, "create stdout pipe failed")
+	}
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "start plugin process failed")
+	}
+
+	proc := &stdioProcess{
+		cmd:    cmd,
+		stdin:  stdin,
+		lines:  make(chan []byte, 16),
+		killed: make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go proc.read(stdout)
+	p.proc = proc
+	p.logger.Info("plugin process started", "path", p.path, "pid", cmd.Process.Pid)
+	return nil
+}
+
+// kill kills plugin process, it is started again on next call, p.mu should be held
+func (p *stdioPlugin) kill() {
+	if p.proc == nil {
+		return
+	}
+	p.proc.kill()
+	p.proc = nil
+}
+
+// invoke sends request and waits for its result, values yielded by streaming function are returned as list
+func (p *stdioPlugin) invoke(ctx context.Context, method string, params interface{}) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id, err := p.send(method, params)
+	if err != nil {
+		return nil, err
+	}
+	var yielded []interface{}
+	for {
+		value, final, err := p.receive(ctx, method, id)
+		if err != nil {
+			return nil, err
+		}
+		if !final {
+			yielded = append(yielded, value)
+			continue
+		}
+		if value == nil && yielded != nil {
+			return yielded, nil
+		}
+		return value, nil
+	}
+}
+
+// send starts plugin process if not started and writes request to its stdin, p.mu should be held
+func (p *stdioPlugin) send(method string, params interface{}) (uint64, error) {
+	if p.proc != nil {
+		select {
+		case <-p.proc.exited:
+			p.logger.Warn("plugin process exited, starting again...", "pid", p.proc.cmd.Process.Pid)
+			p.proc = nil
+		default:
+		}
+	}
+	if p.proc == nil {
+		if err := p.start(); err != nil {
+			return 0, &PluginError{Kind: ErrKindTransportFailure, FuncName: method, Message: err.Error()}
+		}
+	}
+
+	p.nextID++
+	data, err := json.Marshal(&stdioRequest{JSONRPC: "2.0", ID: p.nextID, Method: method, Params: params})
+	if err != nil {
+		return 0, &PluginError{Kind: ErrKindArgMismatch, FuncName: method, Message: err.Error()}
+	}
+	p.logger.Debug("send request", "request", string(data))
+	if _, err := p.proc.stdin.Write(append(data, '\n')); err != nil {
+		p.kill()
+		return 0, &PluginError{Kind: ErrKindTransportFailure, FuncName: method, Message: err.Error()}
+	}
+	return p.nextID, nil
+}
+
+// receive reads next message of request id, final is false if value is yielded by rpc.yield notification.
+// Plugin process is killed if ctx is done, since it can not be cancelled. p.mu should be held.
+func (p *stdioPlugin) receive(ctx context.Context, method string, id uint64) (value interface{}, final bool, err error) {
+	for {
+		var line []byte
+		var ok bool
+		select {
+		case <-ctx.Done():
+			p.logger.Error("call function cancelled, killing plugin process...",
+				"funcName", method, "error", ctx.Err())
+			p.kill()
+			return nil, false, ctx.Err()
+		case line, ok = <-p.proc.lines:
+		}
+		if !ok {
+			return nil, false, &PluginError{
+				Kind:     ErrKindTransportFailure,
+				FuncName: method,
+				Message:  "plugin process exited before response",
+			}
+		}
+
+		var msg stdioMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			p.logger.Info("ignore non JSON-RPC output", "line", string(line))
+			continue
+		}
+		if msg.Method == stdioMethodYield {
+			var yield stdioYield
+			if err := json.Unmarshal(msg.Params, &yield); err != nil || !matchID(yield.ID, id) {
+				continue
+			}
+			value, err := decodeJSON(yield.Value)
+			return value, false, err
+		}
+		if !matchID(msg.ID, id) {
+			p.logger.Warn("ignore message of unknown request", "message", string(line))
+			continue
+		}
+		p.logger.Debug("receive response", "response", string(line))
+		if msg.Error != nil {
+			return nil, true, msg.Error.pluginError(method)
+		}
+		value, err := decodeJSON(msg.Result)
+		return value, true, err
+	}
+}
+
+func (e *stdioError) pluginError(funcName string) *PluginError {
+	pluginErr := &PluginError{Kind: ErrKindUser, FuncName: funcName, Message: e.Message}
+	switch e.Code {
+	case jsonrpcMethodNotFound:
+		pluginErr.Kind = ErrKindFuncNotFound
+	case jsonrpcInvalidParams:
+		pluginErr.Kind = ErrKindArgMismatch
+	case jsonrpcParseError, jsonrpcInvalidRequest:
+		pluginErr.Kind = ErrKindTransportFailure
+	}
+	if e.Data != nil {
+		pluginErr.Stack = fmt.Sprint(e.Data)
+	}
+	return pluginErr
+}
+
+// stdioStream receives values of streaming function, plugin is locked until stream ends or is closed
+type stdioStream struct {
+	plugin   *stdioPlugin
+	ctx      context.Context
+	funcName string
+	id       uint64
+	mu       sync.Mutex // serializes Recv and Close, which may be called by watch
+	yielded  bool       // whether any value is yielded
+	done     bool
+	finished chan struct{} // closed when stream ends or is closed
+	once     sync.Once
+}
+
+// watch closes the stream once ctx is done, thus plugin is unlocked even if the stream is abandoned
+func (s *stdioStream) watch() {
+	select {
+	case <-s.ctx.Done():
+		s.Close()
+	case <-s.finished:
+	}
+}
+
+func (s *stdioStream) Recv() (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return nil, io.EOF
+	}
+	value, final, err := s.plugin.receive(s.ctx, s.funcName, s.id)
+	if err != nil {
+		s.finish()
+		return nil, err
+	}
+	if !final {
+		s.yielded = true
+		return value, nil
+	}
+	s.finish()
+	if s.yielded {
+		return nil, io.EOF
+	}
+	return value, nil
+}
+
+// Close kills plugin process if function is still running
+func (s *stdioStream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.done {
+		s.plugin.kill()
+	}
+	s.finish()
+	return nil
+}
+
+func (s *stdioStream) finish() {
+	s.done = true
+	s.once.Do(func() {
+		close(s.finished)
+		s.plugin.mu.Unlock()
+	})
+}
+
+// jsonParams returns positional arguments as JSON array, or keyword arguments as JSON object
+func jsonParams(funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	if len(kwargs) == 0 {
+		if args == nil {
+			return []interface{}{}, nil
+		}
+		return args, nil
+	}
+	if len(args) > 0 {
+		return nil, &PluginError{
+			Kind:     ErrKindArgMismatch,
+			FuncName: funcName,
+			Message:  "positional and keyword arguments can not be passed together in one call",
+		}
+	}
+	return kwargs, nil
+}
+
+// matchID checks if JSON-RPC id is the request id, string id is also accepted for script plugins
+func matchID(raw json.RawMessage, id uint64) bool {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s == strconv.FormatUint(id, 10)
+	}
+	return string(raw) == strconv.FormatUint(id, 10)
+}
+
+// decodeJSON decodes JSON value, integers are decoded as int64 and other numbers as float64
+func decodeJSON(data json.RawMessage) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return nil, errors.Wrap(err, "decode JSON-RPC value failed")
+	}
+	return convertJSONNumbers(v), nil
+}
+
+func convertJSONNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = convertJSONNumbers(item)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = convertJSONNumbers(item)
+		}
+	}
+	return v
+}
@@ -0,0 +1,224 @@
+package funplugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func skipIfMissing(t *testing.T, commands ...string) {
+	for _, command := range commands {
+		if _, err := exec.LookPath(command); err != nil {
+			t.Skipf("%s not found", command)
+		}
+	}
+}
+
+func TestStdioShellPlugin(t *testing.T) {
+	skipIfMissing(t, "bash", "jq")
+
+	plugin, err := Init("stdio/examples/debugtalk.sh", WithCallTimeout(time.Second, "sleep"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	if !assert.Equal(t, "stdio-sh", plugin.Type()) {
+		t.Fail()
+	}
+	if !assert.False(t, plugin.Has("not_exist")) {
+		t.Fail()
+	}
+	assertPlugin(t, plugin)
+	assertPluginStream(t, plugin)
+	assertStdioPlugin(t, plugin)
+
+	// keyword arguments are sent as object
+	v, err := plugin.CallKw("sum_two_int", nil, map[string]interface{}{"a": 1, "b": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+	_, err = plugin.CallKw("sum_two_int", []interface{}{1}, map[string]interface{}{"b": 2})
+	var pluginErr *PluginError
+	if !assert.True(t, errors.As(err, &pluginErr), err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, ErrKindArgMismatch, pluginErr.Kind) {
+		t.Fail()
+	}
+
+	// signature from rpc.describe, or only name if not described
+	signature, err := plugin.Describe("sum_two_int")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, []string{"a", "b"}, []string{signature.Params[0].Name, signature.Params[1].Name}) {
+		t.Fail()
+	}
+	if !assert.Equal(t, "Return the sum of two integers.", signature.Doc) {
+		t.Fail()
+	}
+	signature, err = plugin.Describe("concatenate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "concatenate", signature.Name) {
+		t.Fail()
+	}
+	assertDescribeNotFound(t, plugin)
+
+	// hung plugin process is killed on timeout, and started again on next call
+	_, err = plugin.Call("sleep", 3)
+	var timeoutErr *CallTimeoutError
+	if !assert.True(t, errors.As(err, &timeoutErr), err) {
+		t.Fatal()
+	}
+	v, err = plugin.Call("sleep", 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, "slept 0.1s", v) {
+		t.Fail()
+	}
+
+	// plugin is unlocked when ctx of abandoned stream is done
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := plugin.CallStream(ctx, "sleep", 3); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v, err = plugin.Call("sum_two_int", 1, 2)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("call blocked by abandoned stream")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+}
+
+func TestStdioPerlPlugin(t *testing.T) {
+	skipIfMissing(t, "perl")
+
+	plugin, err := Init("stdio/examples/debugtalk.pl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	if !assert.Equal(t, "stdio-pl", plugin.Type()) {
+		t.Fail()
+	}
+	assertPlugin(t, plugin)
+	assertPluginStream(t, plugin)
+	assertStdioPlugin(t, plugin)
+}
+
+func TestStdioRubyPlugin(t *testing.T) {
+	skipIfMissing(t, "ruby")
+
+	plugin, err := Init("stdio/examples/debugtalk.rb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	assertPlugin(t, plugin)
+	assertPluginStream(t, plugin)
+	assertStdioPlugin(t, plugin)
+}
+
+func TestStdioManifestPlugin(t *testing.T) {
+	skipIfMissing(t, "perl")
+
+	dir := t.TempDir()
+	script, err := filepath.Abs("stdio/examples/debugtalk.pl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeManifest(t, dir, "plugin.yaml",
+		"command: perl\nargs: ["+script+"]\nlanguage: perl\nprotocol: stdio\n")
+
+	plugin, err := Init(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	if !assert.Equal(t, "stdio-perl", plugin.Type()) {
+		t.Fail()
+	}
+	assertPlugin(t, plugin)
+}
+
+func assertStdioPlugin(t *testing.T, plugin IPlugin) {
+	errorKinds := map[string]ErrorKind{
+		"raise_error": ErrKindUser,
+		"not_exist":   ErrKindFuncNotFound,
+	}
+	for funcName, kind := range errorKinds {
+		_, err := plugin.Call(funcName)
+		var pluginErr *PluginError
+		if !assert.True(t, errors.As(err, &pluginErr), err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, kind, pluginErr.Kind, funcName) {
+			t.Fail()
+		}
+	}
+
+	// plugin process is killed when call is cancelled, and started again on next call
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := plugin.CallContext(ctx, "sleep", 3)
+	if !assert.ErrorIs(t, err, context.DeadlineExceeded) {
+		t.Fail()
+	}
+	v, err := plugin.Call("sum_two_int", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+}
+
+func TestStdioPluginProbeTimeout(t *testing.T) {
+	skipIfMissing(t, "bash")
+
+	// script which never answers unknown methods
+	path := filepath.Join(t.TempDir(), "debugtalk.sh")
+	if err := os.WriteFile(path, []byte("while read -r line; do :; done\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := stdioProbeTimeout
+	stdioProbeTimeout = 200 * time.Millisecond
+	defer func() { stdioProbeTimeout = timeout }()
+
+	start := time.Now()
+	_, err := Init(path)
+	if !assert.ErrorIs(t, err, context.DeadlineExceeded) {
+		t.Fail()
+	}
+	if !assert.Less(t, time.Since(start), 2*time.Second) {
+		t.Fail()
+	}
+}
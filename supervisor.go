@@ -0,0 +1,247 @@
+package funplugin
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultHealthCheckInterval = 15 * time.Second
+	defaultHealthCheckTimeout  = 5 * time.Second
+)
+
+// RestartPolicy specifies how the supervisor restarts unhealthy hashicorp plugin processes,
+// zero value fields are set to defaults.
+type RestartPolicy struct {
+	MaxRestarts     int           // max restarts of each process, 0 means unlimited
+	Backoff         time.Duration // delay before restart, doubled on each consecutive crash, default 1s
+	MaxBackoff      time.Duration // max delay before restart, default 1m
+	CrashLoopWindow time.Duration // process exits within the window after start is regarded as a crash, default 30s
+	CrashLoopLimit  int           // give up after consecutive crashes, default 5, negative disables crash loop detection
+}
+
+func (r RestartPolicy) withDefaults() RestartPolicy {
+	if r.Backoff <= 0 {
+		r.Backoff = time.Second
+	}
+	if r.MaxBackoff <= 0 {
+		r.MaxBackoff = time.Minute
+	}
+	if r.CrashLoopWindow <= 0 {
+		r.CrashLoopWindow = 30 * time.Second
+	}
+	if r.CrashLoopLimit == 0 {
+		r.CrashLoopLimit = 5
+	}
+	return r
+}
+
+// backoff returns the delay before restart after consecutive crashes
+func (r RestartPolicy) backoff(crashes int) time.Duration {
+	delay := r.Backoff
+	for i := 1; i < crashes && delay < r.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.MaxBackoff {
+		delay = r.MaxBackoff
+	}
+	return delay
+}
+
+// PluginEventType is the type of PluginEvent
+type PluginEventType string
+
+const (
+	PluginExited    PluginEventType = "exited"    // process exited or failed health probe
+	PluginRestarted PluginEventType = "restarted" // process restarted by supervisor
+	PluginGaveUp    PluginEventType = "gave_up"   // supervisor gave up restarting process
+	PluginReloaded  PluginEventType = "reloaded"  // plugin reloaded after file changed, see WithWatch
+)
+
+// PluginEvent is emitted by the supervisor of hashicorp plugin processes
+type PluginEvent struct {
+	Type     PluginEventType
+	Path     string // plugin file path
+	Pid      int    // pid of exited process, or new process if restarted
+	Restarts int    // restarts of the process
+	Error    error  // health probe error if exited, reason if gave up, reload error if reloaded
+}
+
+// slotState is the restart state of a process slot in plugin process pool
+type slotState struct {
+	restarts  int       // number of restarts
+	crashes   int       // number of consecutive crashes
+	lastStart time.Time // last time the process is started
+}
+
+// supervisor probes health of hashicorp plugin processes periodically,
+// and restarts unhealthy ones following the restart policy.
+type supervisor struct {
+	plugin   *hashicorpPlugin
+	logger   hclog.Logger
+	interval time.Duration
+	timeout  time.Duration
+	policy   RestartPolicy
+	slots    []slotState
+	quit     chan struct{}
+}
+
+func newSupervisor(p *hashicorpPlugin, quit chan struct{}) *supervisor {
+	s := &supervisor{
+		plugin:   p,
+		logger:   p.logger.Named("supervisor"),
+		interval: p.option.healthCheckInterval,
+		timeout:  p.option.healthCheckTimeout,
+		policy:   p.option.restartPolicy.withDefaults(),
+		slots:    make([]slotState, len(p.processes)),
+		quit:     quit,
+	}
+	if s.interval <= 0 {
+		s.interval = defaultHealthCheckInterval
+	}
+	if s.timeout <= 0 {
+		s.timeout = defaultHealthCheckTimeout
+	}
+	now := time.Now()
+	for i := range s.slots {
+		s.slots[i].lastStart = now
+	}
+	return s
+}
+
+// run checks plugin processes until quit is closed
+func (s *supervisor) run() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.quit:
+			s.logger.Info("supervisor stopped")
+			return
+		case <-ticker.C:
+			s.check()
+		}
+	}
+}
+
+func (s *supervisor) check() {
+	s.logger.Debug("check plugin processes health")
+	for i := range s.slots {
+		proc := s.plugin.process(i)
+		if atomic.LoadInt32(&proc.gaveUp) == 1 {
+			continue
+		}
+		err := s.probe(proc)
+		if err == nil {
+			continue
+		}
+		if s.stopped() {
+			return
+		}
+
+		s.logger.Error("plugin process unhealthy", "pid", proc.pid, "error", err)
+		s.emit(PluginEvent{
+			Type:     PluginExited,
+			Pid:      proc.pid,
+			Restarts: s.slots[i].restarts,
+			Error:    err,
+		})
+		s.restart(i, proc)
+	}
+}
+
+// probe checks if plugin process is alive and its health service is serving
+func (s *supervisor) probe(proc *pluginProcess) error {
+	if proc.client.Exited() {
+		return errors.New("plugin process exited")
+	}
+	rpcClient, err := proc.client.Client()
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- rpcClient.Ping()
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(s.timeout):
+		return fmt.Errorf("health probe timeout after %v", s.timeout)
+	case <-s.quit:
+		return nil
+	}
+}
+
+func (s *supervisor) restart(i int, proc *pluginProcess) {
+	slot := &s.slots[i]
+	if time.Since(slot.lastStart) < s.policy.CrashLoopWindow {
+		slot.crashes++
+	} else {
+		slot.crashes = 0
+	}
+
+	var reason error
+	if s.policy.MaxRestarts > 0 && slot.restarts >= s.policy.MaxRestarts {
+		reason = fmt.Errorf("restarted %d times, reached max restarts", slot.restarts)
+	} else if s.policy.CrashLoopLimit > 0 && slot.crashes >= s.policy.CrashLoopLimit {
+		reason = fmt.Errorf("crashed %d times within %v after start, crash loop detected",
+			slot.crashes, s.policy.CrashLoopWindow)
+	}
+	if reason != nil {
+		s.logger.Error("give up restarting plugin process", "pid", proc.pid, "reason", reason)
+		// calls are no longer routed to the process
+		atomic.StoreInt32(&proc.gaveUp, 1)
+		s.emit(PluginEvent{
+			Type:     PluginGaveUp,
+			Pid:      proc.pid,
+			Restarts: slot.restarts,
+			Error:    reason,
+		})
+		return
+	}
+
+	delay := s.policy.backoff(slot.crashes)
+	s.logger.Info("restarting plugin process...", "pid", proc.pid, "delay", delay)
+	select {
+	case <-time.After(delay):
+	case <-s.quit:
+		return
+	}
+
+	slot.restarts++
+	slot.lastStart = time.Now()
+	newProc, err := s.plugin.restartProcess(proc, false)
+	if err != nil {
+		// the exited process is kept, it will be restarted in next check
+		s.logger.Error("restart plugin process failed", "error", err)
+		return
+	}
+	if newProc == nil {
+		return // replaced by another restart or plugin quit
+	}
+	s.emit(PluginEvent{
+		Type:     PluginRestarted,
+		Pid:      newProc.pid,
+		Restarts: slot.restarts,
+	})
+}
+
+func (s *supervisor) emit(event PluginEvent) {
+	s.plugin.emit(event)
+}
+
+func (s *supervisor) stopped() bool {
+	select {
+	case <-s.quit:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,33 @@
+package funplugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartPolicyBackoff(t *testing.T) {
+	policy := RestartPolicy{}.withDefaults()
+	if !assert.Equal(t, 5, policy.CrashLoopLimit) {
+		t.Fail()
+	}
+
+	policy = RestartPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}.withDefaults()
+	testData := []struct {
+		crashes int
+		expect  time.Duration
+	}{
+		{0, time.Second},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 5 * time.Second},
+		{100, 5 * time.Second},
+	}
+	for _, td := range testData {
+		if !assert.Equal(t, td.expect, policy.backoff(td.crashes), td.crashes) {
+			t.Fail()
+		}
+	}
+}
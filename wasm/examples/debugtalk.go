@@ -0,0 +1,141 @@
+//go:build wasip1
+
+// debugtalk plugin built as WebAssembly reactor module, go 1.24 or later is required:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o debugtalk.wasm debugtalk.go
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+func main() {}
+
+// buffers keeps memory allocated for host alive until it is deallocated by host
+var buffers = map[uint32][]byte{}
+
+//go:wasmexport alloc
+func alloc(size uint32) uint32 {
+	buf := make([]byte, size+1)
+	ptr := uint32(uintptr(unsafe.Pointer(&buf[0])))
+	buffers[ptr] = buf
+	return ptr
+}
+
+//go:wasmexport dealloc
+func dealloc(ptr, size uint32) {
+	delete(buffers, ptr)
+}
+
+// call decodes JSON arguments at ptr, calls fn and returns pointer and size of its JSON result,
+// keyword arguments are bound by params.
+func call(ptr, size uint32, params []string, fn func(args []interface{}) (interface{}, error)) uint64 {
+	var args []interface{}
+	var kwargs map[string]interface{}
+	data := unsafe.Slice((*byte)(unsafe.Pointer(uintptr(ptr))), size)
+	if err := json.Unmarshal(data, &kwargs); err == nil {
+		for _, name := range params {
+			args = append(args, kwargs[name])
+		}
+	} else if err := json.Unmarshal(data, &args); err != nil {
+		return output(map[string]interface{}{"error": err.Error()})
+	}
+
+	result, err := fn(args)
+	if err != nil {
+		return output(map[string]interface{}{"error": err.Error()})
+	}
+	return output(map[string]interface{}{"result": result})
+}
+
+func output(v interface{}) uint64 {
+	data, _ := json.Marshal(v)
+	ptr := alloc(uint32(len(data)))
+	copy(buffers[ptr], data)
+	return uint64(ptr)<<32 | uint64(len(data))
+}
+
+func sum(args []interface{}) (interface{}, error) {
+	var s float64
+	for _, arg := range args {
+		s += arg.(float64)
+	}
+	return s, nil
+}
+
+func concatenate(args []interface{}) (interface{}, error) {
+	var b strings.Builder
+	for _, arg := range args {
+		b.WriteString(fmt.Sprint(arg))
+	}
+	return b.String(), nil
+}
+
+//go:wasmexport sum
+func exportSum(ptr, size uint32) uint64 {
+	return call(ptr, size, nil, sum)
+}
+
+//go:wasmexport sum_ints
+func exportSumInts(ptr, size uint32) uint64 {
+	return call(ptr, size, nil, sum)
+}
+
+//go:wasmexport sum_two_int
+func exportSumTwoInt(ptr, size uint32) uint64 {
+	return call(ptr, size, []string{"a", "b"}, func(args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("function expect 2 arguments, but got %d", len(args))
+		}
+		return int(args[0].(float64)) + int(args[1].(float64)), nil
+	})
+}
+
+//go:wasmexport sum_two_string
+func exportSumTwoString(ptr, size uint32) uint64 {
+	return call(ptr, size, []string{"a", "b"}, func(args []interface{}) (interface{}, error) {
+		return args[0].(string) + args[1].(string), nil
+	})
+}
+
+//go:wasmexport sum_strings
+func exportSumStrings(ptr, size uint32) uint64 {
+	return call(ptr, size, nil, concatenate)
+}
+
+//go:wasmexport concatenate
+func exportConcatenate(ptr, size uint32) uint64 {
+	return call(ptr, size, nil, concatenate)
+}
+
+//go:wasmexport raise_error
+func exportRaiseError(ptr, size uint32) uint64 {
+	return call(ptr, size, nil, func(args []interface{}) (interface{}, error) {
+		return nil, errors.New("something wrong")
+	})
+}
+
+// hold allocates memory of n MB, to be limited by host
+var hold [][]byte
+
+//go:wasmexport alloc_mb
+func exportAllocMB(ptr, size uint32) uint64 {
+	return call(ptr, size, []string{"n"}, func(args []interface{}) (interface{}, error) {
+		for i := 0; i < int(args[0].(float64)); i++ {
+			hold = append(hold, make([]byte, 1<<20))
+		}
+		return len(hold), nil
+	})
+}
+
+//go:wasmexport loop
+func exportLoop(ptr, size uint32) uint64 {
+	return call(ptr, size, nil, func(args []interface{}) (interface{}, error) {
+		for i := 0; ; i++ {
+		}
+	})
+}
@@ -0,0 +1,330 @@
+package funplugin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/httprunner/funplugin/fungo"
+)
+
+// defaultWasmMemoryLimit is the default memory limit of each wasm module
+const defaultWasmMemoryLimit = 64 << 20 // 64MB
+
+// wasmPageSize is the size of wasm memory page
+const wasmPageSize = 64 << 10 // 64KB
+
+// functions exported by wasm module to transfer arguments and results in its memory
+const (
+	wasmFuncAlloc   = "alloc"   // alloc(size i32) -> ptr i32, required
+	wasmFuncDealloc = "dealloc" // dealloc(ptr i32, size i32), optional
+)
+
+// wasmResult is the JSON result written by wasm function
+type wasmResult struct {
+	Result json.RawMessage `json:"result"`
+	Error  *string         `json:"error"`
+}
+
+// wasmPlugin implements in-process WebAssembly plugin running by wazero, each plugin runs in its own
+// runtime without filesystem access, and its memory is limited by WithWasmMemoryLimit.
+//
+// Plugin functions are exported as func(ptr, size i32) i64 with JSON-in/JSON-out calling convention.
+// Arguments are written in memory allocated by exported alloc function as JSON array, or JSON object
+// for keyword arguments. Function returns pointer and size of its JSON result packed in i64 as
+// ptr<<32|size, which is {"result": value} or {"error": "message"}. Both buffers are released by
+// exported dealloc function if available.
+type wasmPlugin struct {
+	path     string // plugin file path
+	option   *pluginOption
+	logger   hclog.Logger
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	names    map[string]bool // exported plugin function names
+
+	mu     sync.Mutex // module instance does not support concurrent calls
+	module api.Module // nil if not instantiated or closed after trap
+}
+
+func newWasmPlugin(path string, option *pluginOption) (*wasmPlugin, error) {
+	pluginLogger := logger.ResetNamed("wasm-plugin")
+	binary, err := os.ReadFile(path)
+	if err != nil {
+		pluginLogger.Error("read wasm plugin failed", "path", path, "error", err)
+		return nil, errors.Wrap(err, "read wasm plugin failed")
+	}
+	if len(option.hostFunctions) > 0 {
+		pluginLogger.Warn("host functions are not supported by wasm plugin")
+	}
+
+	ctx := context.Background()
+	memoryLimit := option.wasmMemoryLimit
+	if memoryLimit <= 0 {
+		memoryLimit = defaultWasmMemoryLimit
+	}
+	config := wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32((memoryLimit + wasmPageSize - 1) / wasmPageSize)).
+		WithCloseOnContextDone(true) // interrupt function call when context is done
+	p := &wasmPlugin{
+		path:    path,
+		option:  option,
+		logger:  pluginLogger,
+		runtime: wazero.NewRuntimeWithConfig(ctx, config),
+		names:   make(map[string]bool),
+	}
+
+	if err := p.compile(ctx, binary); err != nil {
+		p.logger.Error("load wasm plugin failed", "path", path, "error", err)
+		p.runtime.Close(ctx)
+		return nil, err
+	}
+	p.logger.Info("load wasm plugin success", "path", path, "memoryLimit", memoryLimit)
+	return p, nil
+}
+
+// compile compiles wasm module and instantiates it once to check if it works
+func (p *wasmPlugin) compile(ctx context.Context, binary []byte) error {
+	// WASI without filesystem for modules built by TinyGo, Rust or go wasip1
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, p.runtime); err != nil {
+		return errors.Wrap(err, "instantiate wasi failed")
+	}
+	// abort function imported by AssemblyScript modules
+	_, err := p.runtime.NewHostModuleBuilder("env").
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, mod api.Module, message, fileName, line, column uint32) {
+			panic(fmt.Sprintf("abort at line %d column %d", line, column))
+		}).
+		Export("abort").
+		Instantiate(ctx)
+	if err != nil {
+		return errors.Wrap(err, "instantiate env module failed")
+	}
+
+	p.compiled, err = p.runtime.CompileModule(ctx, binary)
+	if err != nil {
+		return errors.Wrap(err, "compile wasm module failed")
+	}
+	functions := p.compiled.ExportedFunctions()
+	if alloc, ok := functions[wasmFuncAlloc]; !ok ||
+		!matchTypes(alloc.ParamTypes(), api.ValueTypeI32) || !matchTypes(alloc.ResultTypes(), api.ValueTypeI32) {
+		return fmt.Errorf("wasm module should export function %s(size i32) i32", wasmFuncAlloc)
+	}
+	for name, function := range functions {
+		if name == wasmFuncAlloc || name == wasmFuncDealloc {
+			continue
+		}
+		if matchTypes(function.ParamTypes(), api.ValueTypeI32, api.ValueTypeI32) &&
+			matchTypes(function.ResultTypes(), api.ValueTypeI64) {
+			p.names[name] = true
+		}
+	}
+
+	_, err = p.instance(ctx)
+	return err
+}
+
+// instance returns module instance, module is instantiated if not instantiated or closed, p.mu should be held
+func (p *wasmPlugin) instance(ctx context.Context) (api.Module, error) {
+	if p.module != nil && !p.module.IsClosed() {
+		return p.module, nil
+	}
+	output := p.logger.StandardWriter(&hclog.StandardLoggerOptions{InferLevels: true})
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize"). // initialize reactor module, e.g. go wasip1 c-shared
+		WithStdout(output).
+		WithStderr(output).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithSysNanosleep().
+		WithRandSource(rand.Reader)
+	module, err := p.runtime.InstantiateModule(ctx, p.compiled, config)
+	if err != nil {
+		return nil, errors.Wrap(err, "instantiate wasm module failed")
+	}
+	p.module = module
+	return module, nil
+}
+
+func (p *wasmPlugin) Type() string {
+	return "wasm-plugin"
+}
+
+func (p *wasmPlugin) Path() string {
+	return p.path
+}
+
+func (p *wasmPlugin) Has(funcName string) bool {
+	return p.names[funcName]
+}
+
+func (p *wasmPlugin) Call(funcName string, args ...interface{}) (interface{}, error) {
+	return p.CallKwContext(context.Background(), funcName, args, nil)
+}
+
+func (p *wasmPlugin) CallContext(ctx context.Context, funcName string, args ...interface{}) (interface{}, error) {
+	return p.CallKwContext(ctx, funcName, args, nil)
+}
+
+func (p *wasmPlugin) CallKw(funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	return p.CallKwContext(context.Background(), funcName, args, kwargs)
+}
+
+// CallKwContext calls function with arguments as JSON array, or keyword arguments as JSON object,
+// the call is interrupted when ctx is done or call timeout is exceeded.
+func (p *wasmPlugin) CallKwContext(ctx context.Context, funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	if !p.Has(funcName) {
+		return nil, &PluginError{
+			Kind:     ErrKindFuncNotFound,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function %s not found", funcName),
+		}
+	}
+	params, err := jsonParams(funcName, args, kwargs)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := p.option.getCallTimeout(funcName)
+	if timeout <= 0 {
+		return p.invoke(ctx, funcName, params)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := p.invoke(callCtx, funcName, params)
+	if err == nil || ctx.Err() != nil || !isDeadlineExceeded(callCtx, err) {
+		return result, err
+	}
+	return nil, &CallTimeoutError{FuncName: funcName, Timeout: timeout}
+}
+
+// CallStream calls function and returns its result as stream, since wasm function can not yield values
+func (p *wasmPlugin) CallStream(ctx context.Context, funcName string, args ...interface{}) (fungo.Stream, error) {
+	result, err := p.CallContext(ctx, funcName, args...)
+	if err != nil {
+		return nil, err
+	}
+	return fungo.NewValueStream(result), nil
+}
+
+// Describe returns function name only, since parameters are not available in wasm module
+func (p *wasmPlugin) Describe(funcName string) (*fungo.FuncSignature, error) {
+	if !p.Has(funcName) {
+		return nil, &PluginError{
+			Kind:     ErrKindFuncNotFound,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function %s not found", funcName),
+		}
+	}
+	return &fungo.FuncSignature{Name: funcName, Variadic: true}, nil
+}
+
+func (p *wasmPlugin) Quit() error {
+	p.logger.Info("quit wasm plugin")
+	if err := p.runtime.Close(context.Background()); err != nil {
+		p.logger.Error("close wasm runtime failed", "error", err)
+	}
+	return fungo.CloseLogFile()
+}
+
+func (p *wasmPlugin) StartHeartbeat() {
+	// no heartbeat needed for in-process plugin
+}
+
+// invoke calls wasm function, module is instantiated again on next call if it traps or is interrupted
+func (p *wasmPlugin) invoke(ctx context.Context, funcName string, params interface{}) (interface{}, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, &PluginError{Kind: ErrKindArgMismatch, FuncName: funcName, Message: err.Error()}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	module, err := p.instance(ctx)
+	if err != nil {
+		return nil, &PluginError{Kind: ErrKindTransportFailure, FuncName: funcName, Message: err.Error()}
+	}
+	output, err := p.callModule(ctx, module, funcName, data)
+	if err != nil {
+		p.logger.Error("call wasm function failed, module will be instantiated again",
+			"funcName", funcName, "error", err)
+		module.Close(context.Background())
+		p.module = nil
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, &PluginError{Kind: ErrKindPanic, FuncName: funcName, Message: err.Error()}
+	}
+
+	var result wasmResult
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, &PluginError{
+			Kind:     ErrKindTransportFailure,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("invalid result of wasm function: %s", output),
+		}
+	}
+	if result.Error != nil {
+		return nil, &PluginError{Kind: ErrKindUser, FuncName: funcName, Message: *result.Error}
+	}
+	return decodeJSON(result.Result)
+}
+
+// callModule writes arguments in module memory, calls function and reads its result
+func (p *wasmPlugin) callModule(ctx context.Context, module api.Module, funcName string, data []byte) ([]byte, error) {
+	results, err := module.ExportedFunction(wasmFuncAlloc).Call(ctx, uint64(len(data)))
+	if err != nil {
+		return nil, errors.Wrap(err, "alloc memory for arguments failed")
+	}
+	ptr := uint32(results[0])
+	if !module.Memory().Write(ptr, data) {
+		return nil, fmt.Errorf("write arguments out of memory range, ptr: %d, size: %d", ptr, len(data))
+	}
+
+	results, err = module.ExportedFunction(funcName).Call(ctx, uint64(ptr), uint64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	p.dealloc(ctx, module, ptr, uint32(len(data)))
+
+	resultPtr, resultSize := uint32(results[0]>>32), uint32(results[0])
+	view, ok := module.Memory().Read(resultPtr, resultSize)
+	if !ok {
+		return nil, fmt.Errorf("read result out of memory range, ptr: %d, size: %d", resultPtr, resultSize)
+	}
+	output := make([]byte, len(view))
+	copy(output, view)
+	p.dealloc(ctx, module, resultPtr, resultSize)
+	return output, nil
+}
+
+func (p *wasmPlugin) dealloc(ctx context.Context, module api.Module, ptr, size uint32) {
+	dealloc := module.ExportedFunction(wasmFuncDealloc)
+	if dealloc == nil {
+		return
+	}
+	if _, err := dealloc.Call(ctx, uint64(ptr), uint64(size)); err != nil {
+		p.logger.Warn("dealloc memory failed", "ptr", ptr, "size", size, "error", err)
+	}
+}
+
+func matchTypes(types []api.ValueType, expect ...api.ValueType) bool {
+	if len(types) != len(expect) {
+		return false
+	}
+	for i := range types {
+		if types[i] != expect[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package funplugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// buildWasmPlugin builds wasm example with go wasip1, test is skipped if go toolchain does not support it
+func buildWasmPlugin(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "debugtalk.wasm")
+	cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", path, "wasm/examples/debugtalk.go")
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("build wasm plugin failed, go 1.24 or later is required: %v\n%s", err, output)
+	}
+	return path
+}
+
+func TestWasmPlugin(t *testing.T) {
+	path := buildWasmPlugin(t)
+
+	plugin, err := Init(path, WithCallTimeout(500*time.Millisecond, "loop"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	if !assert.Equal(t, "wasm-plugin", plugin.Type()) {
+		t.Fail()
+	}
+	if !assert.False(t, plugin.Has("alloc")) {
+		t.Fail()
+	}
+	assertPlugin(t, plugin)
+	assertDescribeNotFound(t, plugin)
+
+	v, err := plugin.CallKw("sum_two_int", nil, map[string]interface{}{"a": 1, "b": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+
+	errorKinds := map[string]ErrorKind{
+		"raise_error": ErrKindUser,
+		"not_exist":   ErrKindFuncNotFound,
+	}
+	for funcName, kind := range errorKinds {
+		_, err := plugin.Call(funcName)
+		var pluginErr *PluginError
+		if !assert.True(t, errors.As(err, &pluginErr), err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, kind, pluginErr.Kind, funcName) {
+			t.Fail()
+		}
+	}
+
+	// endless function is interrupted on timeout, and module is instantiated again on next call
+	_, err = plugin.Call("loop")
+	var timeoutErr *CallTimeoutError
+	if !assert.True(t, errors.As(err, &timeoutErr), err) {
+		t.Fatal()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = plugin.CallContext(ctx, "loop")
+	if !assert.ErrorIs(t, err, context.DeadlineExceeded) {
+		t.Fail()
+	}
+	v, err = plugin.Call("sum_two_int", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 3, v) {
+		t.Fail()
+	}
+}
+
+func TestWasmPluginMemoryLimit(t *testing.T) {
+	path := buildWasmPlugin(t)
+
+	plugin, err := Init(path, WithWasmMemoryLimit(32<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	v, err := plugin.Call("alloc_mb", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 1, v) {
+		t.Fail()
+	}
+
+	// memory growth beyond limit fails, and module is instantiated again on next call
+	_, err = plugin.Call("alloc_mb", 64)
+	var pluginErr *PluginError
+	if !assert.True(t, errors.As(err, &pluginErr), err) {
+		t.Fatal()
+	}
+	if !assert.Equal(t, ErrKindPanic, pluginErr.Kind) {
+		t.Fail()
+	}
+	v, err = plugin.Call("alloc_mb", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !assert.EqualValues(t, 1, v) {
+		t.Fail()
+	}
+}
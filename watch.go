@@ -0,0 +1,107 @@
+package funplugin
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	watchInterval = time.Second      // interval to check plugin file changes
+	drainTimeout  = 30 * time.Second // max time to wait for running calls on old processes
+)
+
+// watch checks plugin file changes periodically until quit, and reloads plugin if changed
+func (p *hashicorpPlugin) watch() {
+	lastStat, err := os.Stat(p.path)
+	if err != nil {
+		p.logger.Error("watch plugin file failed", "path", p.path, "error", err)
+		return
+	}
+	p.logger.Info("watch plugin file", "path", p.path)
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-ticker.C:
+		}
+
+		stat, err := os.Stat(p.path)
+		if err != nil {
+			continue // file may be replaced at the moment
+		}
+		if stat.ModTime().Equal(lastStat.ModTime()) && stat.Size() == lastStat.Size() {
+			continue
+		}
+		lastStat = stat
+
+		p.logger.Info("plugin file changed, reloading...", "path", p.path)
+		err = p.reload()
+		if err != nil {
+			p.logger.Error("reload plugin failed, keep running old processes", "error", err)
+		}
+		p.emit(PluginEvent{Type: PluginReloaded, Error: err})
+	}
+}
+
+// reload starts new processes and checks they work by calling GetNames,
+// then switches calls to new processes and drains old processes.
+func (p *hashicorpPlugin) reload() error {
+	processes, err := p.startProcesses()
+	if err != nil {
+		return err
+	}
+	for _, proc := range processes {
+		if _, err = proc.funcCaller.GetNames(); err != nil {
+			break
+		}
+	}
+
+	p.mu.Lock()
+	select {
+	case <-p.quit:
+		err = errors.New("plugin has quit")
+	default:
+	}
+	if err != nil {
+		p.mu.Unlock()
+		for _, proc := range processes {
+			proc.client.Kill()
+		}
+		return err
+	}
+	oldProcesses := p.processes
+	p.processes = processes
+	p.clearCachedFunctions()
+	p.mu.Unlock()
+
+	p.logger.Info("plugin reloaded", "path", p.path, "processes", len(processes))
+	go p.drain(oldProcesses)
+	return nil
+}
+
+// drain kills old processes after their running calls finish
+func (p *hashicorpPlugin) drain(processes []*pluginProcess) {
+	deadline := time.After(drainTimeout)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for _, proc := range processes {
+	wait:
+		for atomic.LoadInt64(&proc.inflight) > 0 {
+			select {
+			case <-ticker.C:
+			case <-deadline:
+				p.logger.Warn("drain old plugin process timeout, kill it", "pid", proc.pid)
+				break wait
+			case <-p.quit:
+				break wait
+			}
+		}
+		proc.client.Kill()
+	}
+}
@@ -0,0 +1,253 @@
+package funplugin
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/pkg/errors"
+	"github.com/traefik/yaegi/interp"
+	"github.com/traefik/yaegi/stdlib"
+
+	"github.com/httprunner/funplugin/fungo"
+)
+
+// yaegiFunction is exported function of interpreted go plugin
+type yaegiFunction struct {
+	fn         reflect.Value
+	paramNames []string // parameter names in source, context.Context parameter is excluded
+	doc        string   // doc comment in source
+}
+
+// yaegiPlugin implements plugin interpreting go source file by yaegi in process,
+// no go build is needed and it does not depend on the go version of host like go plugin.
+// Exported functions are plugin functions, only standard library and fungo can be imported.
+type yaegiPlugin struct {
+	path      string // plugin file path
+	option    *pluginOption
+	functions map[string]*yaegiFunction
+	logger    hclog.Logger
+	host      fungo.IFuncCaller // caller of host functions of this plugin, nil if not specified
+}
+
+func newYaegiPlugin(path string, option *pluginOption) (*yaegiPlugin, error) {
+	pluginLogger := logger.ResetNamed("yaegi-plugin")
+
+	p := &yaegiPlugin{
+		path:      path,
+		option:    option,
+		functions: make(map[string]*yaegiFunction),
+		logger:    pluginLogger,
+	}
+	if len(option.hostFunctions) > 0 {
+		// host functions are called directly, and only visible to this plugin
+		p.host = fungo.NewHostCaller(option.hostFunctions)
+	}
+	if err := p.load(); err != nil {
+		pluginLogger.Error("load yaegi plugin failed", "path", path, "error", err)
+		return nil, err
+	}
+	pluginLogger.Info("load yaegi plugin success", "path", path)
+	return p, nil
+}
+
+// load interprets go source file and collects its exported functions
+func (p *yaegiPlugin) load() error {
+	file, err := parser.ParseFile(token.NewFileSet(), p.path, nil, parser.ParseComments)
+	if err != nil {
+		return errors.Wrap(err, "parse go plugin failed")
+	}
+
+	output := p.logger.StandardWriter(&hclog.StandardLoggerOptions{InferLevels: true})
+	i := interp.New(interp.Options{
+		Stdout: output,
+		Stderr: output,
+		Env:    os.Environ(),
+	})
+	if err := i.Use(stdlib.Symbols); err != nil {
+		return errors.Wrap(err, "load standard library symbols failed")
+	}
+	if err := i.Use(p.fungoSymbols()); err != nil {
+		return errors.Wrap(err, "load fungo symbols failed")
+	}
+	if _, err := i.EvalPath(p.path); err != nil {
+		return errors.Wrap(err, "interpret go plugin failed")
+	}
+
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Recv != nil || !funcDecl.Name.IsExported() {
+			continue
+		}
+		name := funcDecl.Name.Name
+		fn, err := i.Eval(fmt.Sprintf("%s.%s", file.Name.Name, name))
+		if err != nil {
+			return errors.Wrapf(err, "get function %s failed", name)
+		}
+
+		var paramNames []string
+		for _, field := range funcDecl.Type.Params.List {
+			for _, ident := range field.Names {
+				paramNames = append(paramNames, ident.Name)
+			}
+		}
+		if fn.Type().NumIn() > 0 && fn.Type().In(0) == contextType && len(paramNames) > 0 {
+			paramNames = paramNames[1:]
+		}
+		p.functions[name] = &yaegiFunction{
+			fn:         fn,
+			paramNames: paramNames,
+			doc:        strings.TrimSpace(funcDecl.Doc.Text()),
+		}
+	}
+	return nil
+}
+
+// fungoSymbols returns fungo symbols which can be imported by interpreted go plugin,
+// CallHost and CallHostContext call host functions of this plugin.
+func (p *yaegiPlugin) fungoSymbols() interp.Exports {
+	callHostContext := func(ctx context.Context, funcName string, args ...interface{}) (interface{}, error) {
+		if p.host == nil {
+			return nil, fungo.ErrHostNotConnected
+		}
+		return p.host.CallContext(ctx, funcName, args...)
+	}
+	callHost := func(funcName string, args ...interface{}) (interface{}, error) {
+		return callHostContext(context.Background(), funcName, args...)
+	}
+	return interp.Exports{
+		"github.com/httprunner/funplugin/fungo/fungo": {
+			"CallHost":            reflect.ValueOf(callHost),
+			"CallHostContext":     reflect.ValueOf(callHostContext),
+			"ErrHostNotConnected": reflect.ValueOf(&fungo.ErrHostNotConnected).Elem(),
+			"Unpack":              reflect.ValueOf(fungo.Unpack),
+			"Logger":              reflect.ValueOf(&fungo.Logger).Elem(),
+			"PluginError":         reflect.ValueOf((*fungo.PluginError)(nil)),
+		},
+	}
+}
+
+func (p *yaegiPlugin) Type() string {
+	return "yaegi-plugin"
+}
+
+func (p *yaegiPlugin) Path() string {
+	return p.path
+}
+
+func (p *yaegiPlugin) Has(funcName string) bool {
+	p.logger.Debug("check if plugin has function", "funcName", funcName)
+	_, ok := p.functions[funcName]
+	return ok
+}
+
+func (p *yaegiPlugin) Call(funcName string, args ...interface{}) (interface{}, error) {
+	return p.CallContext(context.Background(), funcName, args...)
+}
+
+func (p *yaegiPlugin) CallContext(ctx context.Context, funcName string, args ...interface{}) (interface{}, error) {
+	return p.CallKwContext(ctx, funcName, args, nil)
+}
+
+func (p *yaegiPlugin) CallKw(funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	return p.CallKwContext(context.Background(), funcName, args, kwargs)
+}
+
+// CallKwContext calls function with keyword arguments, which are bound by parameter names in source
+// or decoded into struct parameter. The call returns when ctx is done or call timeout is exceeded.
+func (p *yaegiPlugin) CallKwContext(ctx context.Context, funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	timeout := p.option.getCallTimeout(funcName)
+	if timeout <= 0 {
+		return p.invoke(ctx, funcName, args, kwargs)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := p.invoke(callCtx, funcName, args, kwargs)
+	if err == nil || ctx.Err() != nil || !isDeadlineExceeded(callCtx, err) {
+		return result, err
+	}
+	return nil, &CallTimeoutError{FuncName: funcName, Timeout: timeout}
+}
+
+// invoke calls function and returns when ctx is done, since interpreted function can not be
+// interrupted, function which does not respect ctx keeps running until it returns.
+func (p *yaegiPlugin) invoke(ctx context.Context, funcName string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	f, ok := p.functions[funcName]
+	if !ok {
+		return nil, &PluginError{
+			Kind:     ErrKindFuncNotFound,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function %s not found", funcName),
+		}
+	}
+	if ctx.Done() == nil {
+		return p.call(ctx, funcName, f, args, kwargs)
+	}
+
+	type callResult struct {
+		result interface{}
+		err    error
+	}
+	done := make(chan callResult, 1)
+	go func() {
+		result, err := p.call(ctx, funcName, f, args, kwargs)
+		done <- callResult{result: result, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.result, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *yaegiPlugin) call(ctx context.Context, funcName string, f *yaegiFunction, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	result, err := fungo.CallFuncKw(ctx, f.fn, f.paramNames, args, kwargs)
+	if err != nil {
+		return nil, fungo.AsPluginError(funcName, err)
+	}
+	return result, nil
+}
+
+func (p *yaegiPlugin) CallStream(ctx context.Context, funcName string, args ...interface{}) (fungo.Stream, error) {
+	f, ok := p.functions[funcName]
+	if !ok {
+		return nil, &PluginError{
+			Kind:     ErrKindFuncNotFound,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function %s not found", funcName),
+		}
+	}
+	stream, err := fungo.CallFuncStream(ctx, f.fn, args...)
+	if err != nil {
+		return nil, fungo.AsPluginError(funcName, err)
+	}
+	return stream, nil
+}
+
+func (p *yaegiPlugin) Describe(funcName string) (*fungo.FuncSignature, error) {
+	f, ok := p.functions[funcName]
+	if !ok {
+		return nil, &PluginError{
+			Kind:     ErrKindFuncNotFound,
+			FuncName: funcName,
+			Message:  fmt.Sprintf("function %s not found", funcName),
+		}
+	}
+	return fungo.DescribeFunc(funcName, f.fn, f.paramNames, f.doc), nil
+}
+
+func (p *yaegiPlugin) Quit() error {
+	// no need to quit for interpreted go plugin
+	return nil
+}
+
+func (p *yaegiPlugin) StartHeartbeat() {
+	// no heartbeat needed for in-process plugin
+}
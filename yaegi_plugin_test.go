@@ -0,0 +1,193 @@
+package funplugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/httprunner/funplugin/fungo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYaegiPlugin(t *testing.T) {
+	plugin, err := Init("fungo/examples/debugtalk.go",
+		WithHostFunctions(map[string]interface{}{
+			"get_variable": func(name string) string { return "value of " + name },
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	if !assert.Equal(t, "yaegi-plugin", plugin.Type()) {
+		t.Fail()
+	}
+	if !assert.True(t, plugin.Has("Concatenate")) {
+		t.Fail()
+	}
+
+	result, err := plugin.Call("Concatenate", "1", 2, "3.14")
+	if !assert.NoError(t, err) {
+		t.Fail()
+	}
+	if !assert.Equal(t, "123.14", result) {
+		t.Fail()
+	}
+
+	// call function with context
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = plugin.CallContext(ctx, "Sleep", 10)
+	if !assert.ErrorIs(t, err, context.DeadlineExceeded) {
+		t.Fail()
+	}
+
+	// keyword arguments are bound by parameter names in source, or decoded into struct parameter
+	result, err = plugin.CallKw("SumTwoInt", nil, map[string]interface{}{"a": 1, "b": 2})
+	if !assert.NoError(t, err) {
+		t.Fail()
+	}
+	if !assert.Equal(t, 3, result) {
+		t.Fail()
+	}
+	result, err = plugin.CallKw("Greet", nil, map[string]interface{}{"name": "funplugin"})
+	if !assert.NoError(t, err) {
+		t.Fail()
+	}
+	if !assert.Equal(t, "Hello, funplugin!", result) {
+		t.Fail()
+	}
+
+	// call function as stream
+	stream, err := plugin.CallStream(context.Background(), "GenerateInts", 2)
+	if !assert.NoError(t, err) {
+		t.Fatal()
+	}
+	defer stream.Close()
+	for i := 0; i < 2; i++ {
+		v, err := stream.Recv()
+		if !assert.NoError(t, err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, i, v) {
+			t.Fail()
+		}
+	}
+
+	// call host function
+	result, err = plugin.Call("CallHostFunction", "get_variable", "token")
+	if !assert.NoError(t, err) {
+		t.Fail()
+	}
+	if !assert.Equal(t, "value of token", result) {
+		t.Fail()
+	}
+
+	// describe function signature with parameter names in source
+	sig, err := plugin.Describe("SumTwoInt")
+	if !assert.NoError(t, err) {
+		t.Fail()
+	}
+	if !assert.Equal(t, "a", sig.Params[0].Name) {
+		t.Fail()
+	}
+	if !assert.Equal(t, []string{"int"}, sig.Returns) {
+		t.Fail()
+	}
+	assertDescribeNotFound(t, plugin)
+
+	errorCases := []struct {
+		funcName string
+		args     []interface{}
+		kind     ErrorKind
+	}{
+		{"Divide", []interface{}{1, 0}, ErrKindPanic}, // divided by zero
+		{"SumTwoInt", nil, ErrKindArgMismatch},
+		{"init", nil, ErrKindFuncNotFound}, // unexported function is not plugin function
+	}
+	for _, c := range errorCases {
+		_, err := plugin.Call(c.funcName, c.args...)
+		var pluginErr *PluginError
+		if !assert.True(t, errors.As(err, &pluginErr), err) {
+			t.Fatal()
+		}
+		if !assert.Equal(t, c.kind, pluginErr.Kind, c.funcName) {
+			t.Fail()
+		}
+	}
+}
+
+func TestYaegiPluginCallTimeout(t *testing.T) {
+	plugin, err := Init("fungo/examples/debugtalk.go",
+		WithCallTimeout(100*time.Millisecond, "Sleep", "SleepUninterruptible"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+
+	// call returns on timeout, whether the function respects ctx or keeps running in background
+	for _, funcName := range []string{"Sleep", "SleepUninterruptible"} {
+		start := time.Now()
+		_, err = plugin.Call(funcName, 1)
+		var timeoutErr *CallTimeoutError
+		if !assert.True(t, errors.As(err, &timeoutErr), err) {
+			t.Fatal()
+		}
+		if !assert.Less(t, time.Since(start), 500*time.Millisecond, funcName) {
+			t.Fail()
+		}
+	}
+
+	result, err := plugin.Call("SumTwoInt", 1, 2)
+	if !assert.NoError(t, err) {
+		t.Fail()
+	}
+	if !assert.Equal(t, 3, result) {
+		t.Fail()
+	}
+}
+
+func TestYaegiPluginHostFunctions(t *testing.T) {
+	plugin, err := Init("fungo/examples/debugtalk.go",
+		WithHostFunctions(map[string]interface{}{
+			"get_variable": func(name string) string { return "value of " + name },
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Quit()
+	other, err := Init("fungo/examples/debugtalk.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Quit()
+
+	result, err := plugin.Call("CallHostFunction", "get_variable", "token")
+	if !assert.NoError(t, err) {
+		t.Fail()
+	}
+	if !assert.Equal(t, "value of token", result) {
+		t.Fail()
+	}
+	// host functions of other plugin are not visible
+	_, err = other.Call("CallHostFunction", "get_variable", "token")
+	if !assert.ErrorContains(t, err, fungo.ErrHostNotConnected.Error()) {
+		t.Fail()
+	}
+}
+
+func TestYaegiPluginInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debugtalk.go")
+	content := "package main\n\nfunc SumTwoInt(a, b int) int { return a + c }\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Init(path)
+	if !assert.Error(t, err) {
+		t.Fail()
+	}
+}